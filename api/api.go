@@ -0,0 +1,28 @@
+// APIの仕様書
+package api
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+// OpenAPI 3形式の仕様書
+//
+//go:embed openapi.json
+var OpenAPI []byte
+
+// 仕様書を返却する
+func ServeOpenAPI(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	if r.Method == http.MethodHead {
+		return
+	}
+	_, _ = w.Write(OpenAPI)
+}
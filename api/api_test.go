@@ -0,0 +1,49 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/dip-dev/go-tutorial/api"
+	"github.com/dip-dev/go-tutorial/internal/helper/openapi"
+)
+
+func TestServeOpenAPI(t *testing.T) {
+	success := map[string]struct {
+		method   string
+		wantBody bool
+	}{
+		"正常ケース：GET":  {method: http.MethodGet, wantBody: true},
+		"正常ケース：HEAD": {method: http.MethodHead},
+	}
+	for tn, tc := range success {
+		t.Run(tn, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(tc.method, "http://localhost/openapi.json", nil)
+			openapi.Check(t, "/openapi.json", api.ServeOpenAPI)(w, r)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+			if !tc.wantBody {
+				assert.Empty(t, w.Body.String())
+				return
+			}
+			var doc map[string]any
+			assert.NoError(t, json.NewDecoder(w.Body).Decode(&doc))
+			assert.Equal(t, "3.0.3", doc["openapi"])
+		})
+	}
+
+	t.Run("異常ケース：GET以外", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "http://localhost/openapi.json", nil)
+		openapi.Check(t, "/openapi.json", api.ServeOpenAPI)(w, r)
+
+		assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+		assert.Equal(t, "GET, HEAD", w.Header().Get("Allow"))
+	})
+}
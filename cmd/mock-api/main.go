@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/dip-dev/go-tutorial/internal/helper/test"
+)
+
+// mock-apiの代わりにローカルで動かすサーバー
+// Dockerイメージを取得できない環境でもdocker-composeから利用できる
+func main() {
+	addr := flag.String("addr", ":80", "listen address")
+	seed := flag.String("seed", "", "path to a JSON file with initial users and entries")
+	key := flag.String("key", test.DefaultMockAPIKey, "required value of the key header (empty disables the check)")
+	flag.Parse()
+
+	data := test.DefaultMockData()
+	if *seed != "" {
+		f, err := os.Open(*seed)
+		if err != nil {
+			log.Fatalf("failed to open seed file: %+v", err)
+		}
+		data, err = test.LoadMockData(f)
+		f.Close()
+		if err != nil {
+			log.Fatalf("failed to load seed file: %+v", err)
+		}
+	}
+
+	m := test.NewMockAPI(data)
+	m.Key = *key
+	srv := &http.Server{
+		Addr:              *addr,
+		Handler:           m,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shutdownCtx)
+	}()
+
+	log.Printf("mock-api is listening on %s", *addr)
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Fatalf("failed to launch mock-api: %+v", err)
+	}
+}
@@ -2,7 +2,12 @@ module github.com/dip-dev/go-tutorial
 
 go 1.20
 
-require github.com/stretchr/testify v1.8.2
+require (
+	github.com/andybalholm/brotli v1.0.6
+	github.com/stretchr/testify v1.8.2
+	golang.org/x/net v0.17.0
+	golang.org/x/text v0.14.0
+)
 
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
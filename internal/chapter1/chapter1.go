@@ -7,6 +7,13 @@ import (
 )
 
 func GetEcho(w http.ResponseWriter, r *http.Request) {
+	// 診断モードではリクエストの詳細を返す
+	query := r.URL.Query()
+	if isEnabled(query.Get(verboseParam)) {
+		echoVerbose(w, r)
+		return
+	}
+
 	//FIXME: Getメソッドのアクセスか確認
 	if r.Method != "GET" {
 		w.WriteHeader(http.StatusMethodNotAllowed)
@@ -19,6 +26,12 @@ func GetEcho(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// 複数値モードでは値を連結せずに配列で返す
+	if isEnabled(query.Get(multiParam)) {
+		writeJSON(w, echoParams(r))
+		return
+	}
+
 	//FIXME: パラメータを取得する
 	var ps = map[string]string{}
 	for k, v := range r.Form {
@@ -26,6 +39,7 @@ func GetEcho(w http.ResponseWriter, r *http.Request) {
 	}
 
 	//FIXME: パラメータをレスポンスに書き出す
+	w.Header().Set("Content-Type", "application/json")
 	encoder := json.NewEncoder(w)
 	if err := encoder.Encode(ps); err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
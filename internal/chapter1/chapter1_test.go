@@ -9,6 +9,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 
+	"github.com/dip-dev/go-tutorial/internal/helper/openapi"
 	"github.com/dip-dev/go-tutorial/internal/helper/test"
 )
 
@@ -57,7 +58,7 @@ func TestGetEcho(t *testing.T) {
 
 			w := httptest.NewRecorder()
 			r := httptest.NewRequest(http.MethodGet, "http://localhost/?"+param.Encode(), nil)
-			GetEcho(w, r)
+			openapi.Check(t, "/echo", GetEcho)(w, r)
 			got := w.Body.String()
 			assert.Equal(t, tc.wantStatus, w.Code)
 			for k, v := range tc.params {
@@ -79,7 +80,7 @@ func TestGetEcho(t *testing.T) {
 			} else {
 				r = httptest.NewRequest(tc.method, "http://localhost/", strings.NewReader(form.Encode()))
 			}
-			GetEcho(w, r)
+			openapi.Check(t, "/echo", GetEcho)(w, r)
 			assert.Equal(t, tc.wantStatus, w.Code)
 		})
 	}
@@ -90,7 +91,7 @@ func TestGetEcho(t *testing.T) {
 		errW := &test.ErrorResponseWriter{}
 
 		// 呼び出し
-		GetEcho(errW, r)
+		openapi.Check(t, "/echo", GetEcho)(errW, r)
 
 		assert.Equal(t, http.StatusInternalServerError, errW.Code())
 	})
@@ -0,0 +1,113 @@
+package chapter1
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Server-Sent Eventsのパラメータ
+const (
+	// 送信間隔（time.ParseDurationの形式）
+	intervalParam = "interval"
+	// 送信するイベント数（0は無制限）
+	countParam = "count"
+)
+
+// 送信間隔の既定値と範囲
+const (
+	defaultSSEInterval = time.Second
+	minSSEInterval     = 100 * time.Millisecond
+	maxSSEInterval     = time.Minute
+)
+
+// Server-Sent Eventsで送信するイベント
+type sseEvent struct {
+	ID     int                 `json:"id"`
+	Time   time.Time           `json:"time"`
+	Params map[string][]string `json:"params"`
+}
+
+// パラメータを一定間隔でServer-Sent Eventsとして送信し続ける
+// クライアントの切断、またはサーバーの停止で送信を終える
+func EchoSSE(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	interval := defaultSSEInterval
+	if v := r.Form.Get(intervalParam); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < minSSEInterval || d > maxSSEInterval {
+			http.Error(w, fmt.Sprintf("interval must be between %s and %s", minSSEInterval, maxSSEInterval), http.StatusBadRequest)
+			return
+		}
+		interval = d
+	}
+	count := 0
+	if v := r.Form.Get(countParam); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			http.Error(w, "count must be a non-negative integer", http.StatusBadRequest)
+			return
+		}
+		count = n
+	}
+	// 再接続時は続きのIDから送信する
+	id := 0
+	if v := r.Header.Get("Last-Event-ID"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			id = n
+		}
+	}
+
+	params := map[string][]string{}
+	for k, v := range r.Form {
+		if k == intervalParam || k == countParam {
+			continue
+		}
+		params[k] = v
+	}
+
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	// リバースプロキシにバッファリングさせない
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	if _, err := fmt.Fprintf(w, "retry: %d\n\n", interval.Milliseconds()); err != nil {
+		return
+	}
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	ctx := r.Context()
+	for sent := 0; count == 0 || sent < count; sent++ {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			id++
+			data, err := json.Marshal(sseEvent{ID: id, Time: now, Params: params})
+			if err != nil {
+				return
+			}
+			if _, err = fmt.Fprintf(w, "id: %d\nevent: echo\ndata: %s\n\n", id, data); err != nil {
+				return
+			}
+			if err = rc.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
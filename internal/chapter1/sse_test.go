@@ -0,0 +1,118 @@
+package chapter1
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/dip-dev/go-tutorial/internal/helper/openapi"
+)
+
+// イベントをid, event, dataの組で読み取る
+func readSSEEvent(t *testing.T, br *bufio.Reader) map[string]string {
+	event := map[string]string{}
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil {
+			t.Fatalf("read: %v", err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			if len(event) == 0 || event["retry"] != "" {
+				event = map[string]string{}
+				continue
+			}
+			return event
+		}
+		k, v, _ := strings.Cut(line, ": ")
+		event[k] = v
+	}
+}
+
+func TestEchoSSE(t *testing.T) {
+	t.Run("正常ケース：指定した件数のイベントを送信", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "http://localhost/echo/sse?interval=100ms&count=2&name=dip", nil)
+		r.Header.Set("Last-Event-ID", "10")
+		openapi.Check(t, "/echo/sse", EchoSSE)(w, r)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "text/event-stream", w.Header().Get("Content-Type"))
+		assert.True(t, w.Flushed)
+
+		br := bufio.NewReader(w.Body)
+		for _, wantID := range []string{"11", "12"} {
+			event := readSSEEvent(t, br)
+			assert.Equal(t, wantID, event["id"])
+			assert.Equal(t, "echo", event["event"])
+			var data sseEvent
+			assert.NoError(t, json.Unmarshal([]byte(event["data"]), &data))
+			assert.Equal(t, map[string][]string{"name": {"dip"}}, data.Params)
+		}
+		_, err := br.ReadString('\n')
+		assert.ErrorIs(t, err, io.EOF)
+	})
+	t.Run("正常ケース：サーバー停止時に送信を終える", func(t *testing.T) {
+		srv, url := startServer(t, openapi.Check(t, "/echo/sse", EchoSSE))
+		res, err := http.Get(url + "/echo/sse?interval=100ms")
+		if !assert.NoError(t, err) {
+			return
+		}
+		defer res.Body.Close()
+		br := bufio.NewReader(res.Body)
+		readSSEEvent(t, br)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		assert.NoError(t, srv.Shutdown(ctx))
+		_, err = io.ReadAll(br)
+		assert.NoError(t, err)
+	})
+
+	fail := map[string]struct {
+		method     string
+		query      string
+		wantStatus int
+	}{
+		"異常ケース：Getメソッドではない": {
+			method:     http.MethodPost,
+			wantStatus: http.StatusMethodNotAllowed,
+		},
+		"異常ケース：間隔が短すぎる": {
+			method:     http.MethodGet,
+			query:      "interval=1ms",
+			wantStatus: http.StatusBadRequest,
+		},
+		"異常ケース：間隔が不正": {
+			method:     http.MethodGet,
+			query:      "interval=abc",
+			wantStatus: http.StatusBadRequest,
+		},
+		"異常ケース：件数が不正": {
+			method:     http.MethodGet,
+			query:      "count=-1",
+			wantStatus: http.StatusBadRequest,
+		},
+		"異常ケース：パラメータが不正": {
+			method:     http.MethodGet,
+			query:      "%",
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+	for tn, tc := range fail {
+		t.Run(tn, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(tc.method, "http://localhost/echo/sse?"+tc.query, nil)
+			openapi.Check(t, "/echo/sse", EchoSSE)(w, r)
+			assert.Equal(t, tc.wantStatus, w.Code)
+		})
+	}
+}
@@ -0,0 +1,234 @@
+package chapter1
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+)
+
+// 動作モードを切り替えるパラメータ
+const (
+	// 値を配列のまま返す
+	multiParam = "multi"
+	// リクエストの詳細を返す
+	verboseParam = "verbose"
+)
+
+// 診断モードで受け付けるボディの上限
+const maxEchoBodySize = 1 << 20
+
+// 値を伏せるヘッダー
+var sensitiveHeaders = map[string]bool{
+	"Authorization":       true,
+	"Proxy-Authorization": true,
+	"Cookie":              true,
+	"Set-Cookie":          true,
+	"Key":                 true,
+	"X-Api-Key":           true,
+	"X-Auth-Token":        true,
+}
+
+const redacted = "[REDACTED]"
+
+// 診断モードのレスポンス
+type echoDiagnostic struct {
+	Method     string              `json:"method"`
+	Path       string              `json:"path"`
+	Proto      string              `json:"proto"`
+	Host       string              `json:"host"`
+	RemoteAddr string              `json:"remote_addr"`
+	Params     map[string][]string `json:"params"`
+	Headers    map[string][]string `json:"headers"`
+	TLS        *echoTLS            `json:"tls"`
+	Body       *echoBody           `json:"body,omitempty"`
+}
+
+// TLS接続の情報
+type echoTLS struct {
+	Version            string   `json:"version"`
+	CipherSuite        string   `json:"cipher_suite"`
+	ServerName         string   `json:"server_name"`
+	NegotiatedProtocol string   `json:"negotiated_protocol"`
+	PeerCertificates   []string `json:"peer_certificates"`
+}
+
+// リクエストボディの情報
+type echoBody struct {
+	ContentType string `json:"content_type"`
+	Size        int    `json:"size"`
+	SHA256      string `json:"sha256"`
+	// テキストの場合はそのまま、バイナリの場合はbase64で返す
+	Encoding string `json:"encoding"`
+	Content  string `json:"content"`
+}
+
+// 値を1,true,onなどで有効にするパラメータか
+func isEnabled(v string) bool {
+	switch strings.ToLower(v) {
+	case "1", "true", "on", "yes":
+		return true
+	}
+	return false
+}
+
+// モード切替用を除いたパラメータ
+func echoParams(r *http.Request) map[string][]string {
+	ps := map[string][]string{}
+	for k, v := range r.Form {
+		if k == multiParam || k == verboseParam {
+			continue
+		}
+		ps[k] = v
+	}
+	return ps
+}
+
+// リクエストの詳細を返す
+func echoVerbose(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet, http.MethodPost, http.MethodPut:
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	// ボディはParseFormで読み捨てられる前に保持しておく
+	var body []byte
+	if r.Body != nil && r.Body != http.NoBody {
+		var err error
+		body, err = io.ReadAll(http.MaxBytesReader(w, r.Body, maxEchoBodySize))
+		if err != nil {
+			var maxErr *http.MaxBytesError
+			if errors.As(err, &maxErr) {
+				w.WriteHeader(http.StatusRequestEntityTooLarge)
+				return
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	if err := r.ParseForm(); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	d := echoDiagnostic{
+		Method:     r.Method,
+		Path:       r.URL.Path,
+		Proto:      r.Proto,
+		Host:       r.Host,
+		RemoteAddr: r.RemoteAddr,
+		Params:     echoParams(r),
+		Headers:    redactHeaders(r.Header),
+		TLS:        echoTLSInfo(r.TLS),
+	}
+	if r.Method != http.MethodGet {
+		d.Body = echoBodyInfo(r.Header.Get("Content-Type"), body)
+	}
+	writeJSON(w, d)
+}
+
+// 機密情報を含むヘッダーの値を伏せる
+func redactHeaders(h http.Header) map[string][]string {
+	headers := map[string][]string{}
+	for k, vs := range h {
+		if sensitiveHeaders[http.CanonicalHeaderKey(k)] {
+			masked := make([]string, len(vs))
+			for i := range vs {
+				masked[i] = redacted
+			}
+			headers[k] = masked
+			continue
+		}
+		headers[k] = vs
+	}
+	return headers
+}
+
+func echoTLSInfo(cs *tls.ConnectionState) *echoTLS {
+	if cs == nil {
+		return nil
+	}
+	info := &echoTLS{
+		Version:            tlsVersionName(cs.Version),
+		CipherSuite:        tls.CipherSuiteName(cs.CipherSuite),
+		ServerName:         cs.ServerName,
+		NegotiatedProtocol: cs.NegotiatedProtocol,
+		PeerCertificates:   []string{},
+	}
+	for _, cert := range cs.PeerCertificates {
+		info.PeerCertificates = append(info.PeerCertificates, cert.Subject.String())
+	}
+	return info
+}
+
+// TLSのバージョンを表示用の名前に変換する
+// tls.VersionNameはGo 1.21以降のため使わない
+func tlsVersionName(version uint16) string {
+	switch version {
+	case tls.VersionTLS10:
+		return "TLS 1.0"
+	case tls.VersionTLS11:
+		return "TLS 1.1"
+	case tls.VersionTLS12:
+		return "TLS 1.2"
+	case tls.VersionTLS13:
+		return "TLS 1.3"
+	}
+	return fmt.Sprintf("0x%04X", version)
+}
+
+func echoBodyInfo(contentType string, body []byte) *echoBody {
+	sum := sha256.Sum256(body)
+	b := &echoBody{
+		ContentType: contentType,
+		Size:        len(body),
+		SHA256:      hex.EncodeToString(sum[:]),
+	}
+	if isText(contentType) && utf8.Valid(body) {
+		b.Encoding = "text"
+		b.Content = string(body)
+	} else {
+		b.Encoding = "base64"
+		b.Content = base64.StdEncoding.EncodeToString(body)
+	}
+	return b
+}
+
+// テキストとして返せるContent-Typeか
+func isText(contentType string) bool {
+	if contentType == "" {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	if strings.HasPrefix(mediaType, "text/") {
+		return true
+	}
+	switch mediaType {
+	case "application/json", "application/x-www-form-urlencoded", "application/xml", "application/x-ndjson":
+		return true
+	}
+	return strings.HasSuffix(mediaType, "+json") || strings.HasSuffix(mediaType, "+xml")
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,202 @@
+package chapter1
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/dip-dev/go-tutorial/internal/helper/openapi"
+	"github.com/dip-dev/go-tutorial/internal/helper/test"
+)
+
+func TestGetEchoMulti(t *testing.T) {
+	success := map[string]struct {
+		query string
+		want  map[string][]string
+	}{
+		"正常: 複数値のパラメータ": {
+			query: "a=1&a=2&name=dip+太郎&multi=1",
+			want: map[string][]string{
+				"a":    {"1", "2"},
+				"name": {"dip 太郎"},
+			},
+		},
+		"正常: パラメータなし": {
+			query: "multi=true",
+			want:  map[string][]string{},
+		},
+	}
+
+	for tn, tc := range success {
+		t.Run(tn, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "http://localhost/echo?"+tc.query, nil)
+			openapi.Check(t, "/echo", GetEcho)(w, r)
+
+			got := map[string][]string{}
+			assert.NoError(t, json.NewDecoder(w.Body).Decode(&got))
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+	t.Run("異常: Getメソッドではない", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "http://localhost/echo?multi=1", nil)
+		openapi.Check(t, "/echo", GetEcho)(w, r)
+		assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+	})
+}
+
+func TestGetEchoVerbose(t *testing.T) {
+	t.Run("正常: GETリクエストの詳細", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "http://localhost/echo?verbose=1&a=1&a=2", nil)
+		r.Header.Set("Authorization", "Bearer secret")
+		r.Header.Set("key", "dip")
+		r.Header.Add("X-Forwarded-For", "10.0.0.1")
+		r.Header.Add("X-Forwarded-For", "10.0.0.2")
+		openapi.Check(t, "/echo", GetEcho)(w, r)
+
+		var got echoDiagnostic
+		assert.NoError(t, json.NewDecoder(w.Body).Decode(&got))
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+		assert.Equal(t, http.MethodGet, got.Method)
+		assert.Equal(t, "/echo", got.Path)
+		assert.Equal(t, "HTTP/1.1", got.Proto)
+		assert.Equal(t, "192.0.2.1:1234", got.RemoteAddr)
+		assert.Equal(t, map[string][]string{"a": {"1", "2"}}, got.Params)
+		assert.Equal(t, []string{redacted}, got.Headers["Authorization"])
+		assert.Equal(t, []string{redacted}, got.Headers["Key"])
+		assert.Equal(t, []string{"10.0.0.1", "10.0.0.2"}, got.Headers["X-Forwarded-For"])
+		assert.Nil(t, got.TLS)
+		assert.Nil(t, got.Body)
+	})
+
+	success := map[string]struct {
+		method       string
+		contentType  string
+		body         []byte
+		wantEncoding string
+		wantContent  string
+		wantParams   map[string][]string
+	}{
+		"正常: JSONのPOST": {
+			method:       http.MethodPost,
+			contentType:  "application/json",
+			body:         []byte(`{"name":"dip 次郎","age":24}`),
+			wantEncoding: "text",
+			wantContent:  `{"name":"dip 次郎","age":24}`,
+			wantParams:   map[string][]string{},
+		},
+		"正常: フォームのPUT": {
+			method:       http.MethodPut,
+			contentType:  "application/x-www-form-urlencoded",
+			body:         []byte("name=dip+%E6%AC%A1%E9%83%8E&age=24"),
+			wantEncoding: "text",
+			wantContent:  "name=dip+%E6%AC%A1%E9%83%8E&age=24",
+			wantParams:   map[string][]string{"name": {"dip 次郎"}, "age": {"24"}},
+		},
+		"正常: バイナリのPOST": {
+			method:       http.MethodPost,
+			contentType:  "application/octet-stream",
+			body:         []byte{0x00, 0xff, 0x10},
+			wantEncoding: "base64",
+			wantContent:  "AP8Q",
+			wantParams:   map[string][]string{},
+		},
+	}
+	for tn, tc := range success {
+		t.Run(tn, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(tc.method, "http://localhost/echo?verbose=1", bytes.NewReader(tc.body))
+			r.Header.Set("Content-Type", tc.contentType)
+			openapi.Check(t, "/echo", GetEcho)(w, r)
+
+			var got echoDiagnostic
+			assert.NoError(t, json.NewDecoder(w.Body).Decode(&got))
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, tc.method, got.Method)
+			assert.Equal(t, tc.wantParams, got.Params)
+			if assert.NotNil(t, got.Body) {
+				sum := sha256.Sum256(tc.body)
+				assert.Equal(t, tc.contentType, got.Body.ContentType)
+				assert.Equal(t, len(tc.body), got.Body.Size)
+				assert.Equal(t, hex.EncodeToString(sum[:]), got.Body.SHA256)
+				assert.Equal(t, tc.wantEncoding, got.Body.Encoding)
+				assert.Equal(t, tc.wantContent, got.Body.Content)
+			}
+		})
+	}
+
+	fail := map[string]struct {
+		method     string
+		body       string
+		wantStatus int
+	}{
+		"異常: 対応していないメソッド": {
+			method:     http.MethodDelete,
+			wantStatus: http.StatusMethodNotAllowed,
+		},
+		"異常: ボディが大きすぎる": {
+			method:     http.MethodPost,
+			body:       strings.Repeat("a", maxEchoBodySize+1),
+			wantStatus: http.StatusRequestEntityTooLarge,
+		},
+	}
+	for tn, tc := range fail {
+		t.Run(tn, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(tc.method, "http://localhost/echo?verbose=1", strings.NewReader(tc.body))
+			openapi.Check(t, "/echo", GetEcho)(w, r)
+			assert.Equal(t, tc.wantStatus, w.Code)
+		})
+	}
+	t.Run("異常: JSONエンコード失敗", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "http://localhost/echo?verbose=1", nil)
+		errW := &test.ErrorResponseWriter{}
+		openapi.Check(t, "/echo", GetEcho)(errW, r)
+		assert.Equal(t, http.StatusInternalServerError, errW.Code())
+	})
+	t.Run("正常: TLS接続の情報", func(t *testing.T) {
+		ts := httptest.NewTLSServer(http.HandlerFunc(GetEcho))
+		defer ts.Close()
+
+		res, err := ts.Client().Get(ts.URL + "/echo?verbose=1")
+		if !assert.NoError(t, err) {
+			return
+		}
+		defer res.Body.Close()
+
+		var got echoDiagnostic
+		assert.NoError(t, json.NewDecoder(res.Body).Decode(&got))
+		if assert.NotNil(t, got.TLS) {
+			assert.Equal(t, "TLS 1.3", got.TLS.Version)
+			assert.NotEmpty(t, got.TLS.CipherSuite)
+		}
+	})
+}
+
+func TestTLSVersionName(t *testing.T) {
+	success := map[string]struct {
+		version uint16
+		want    string
+	}{
+		"正常ケース：TLS 1.2":  {version: tls.VersionTLS12, want: "TLS 1.2"},
+		"正常ケース：TLS 1.3":  {version: tls.VersionTLS13, want: "TLS 1.3"},
+		"正常ケース：不明なバージョン": {version: 0x0305, want: "0x0305"},
+	}
+	for tn, tc := range success {
+		t.Run(tn, func(t *testing.T) {
+			assert.Equal(t, tc.want, tlsVersionName(tc.version))
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package chapter1
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/dip-dev/go-tutorial/internal/helper/websocket"
+)
+
+// WebSocketの死活監視の間隔
+var (
+	// Pingを送信する間隔
+	wsPingInterval = 30 * time.Second
+	// Pongを待つ時間（Pingの間隔より長くする）
+	wsPongWait = 60 * time.Second
+)
+
+// 受信したメッセージをそのまま返すWebSocketのエコー
+// サーバーの停止時はGoing Away(1001)で接続を閉じる
+func EchoWebSocket(w http.ResponseWriter, r *http.Request) {
+	c, err := websocket.Upgrade(w, r)
+	if err != nil {
+		return
+	}
+	// 読み込み・書き込みの失敗や切断で終了した場合も接続を解放する
+	defer c.CloseNow()
+
+	// 何かしら受信するたびに読み込みのタイムアウトを延長する
+	extend := func() error {
+		return c.SetReadDeadline(time.Now().Add(wsPongWait))
+	}
+	if err = extend(); err != nil {
+		_ = c.Close(websocket.CloseInternalServerErr, "")
+		return
+	}
+	c.PongHandler = func([]byte) error {
+		return extend()
+	}
+
+	// ハイジャックした接続はサーバーの停止を待たないため、コンテキストで停止を検知する
+	ctx := r.Context()
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		ticker := time.NewTicker(wsPingInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := c.WriteControl(websocket.OpPing, nil); err != nil {
+					return
+				}
+			case <-ctx.Done():
+				_ = c.Close(websocket.CloseGoingAway, "server shutting down")
+				return
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	for {
+		op, data, err := c.ReadMessage()
+		if err != nil {
+			return
+		}
+		if err = extend(); err != nil {
+			return
+		}
+		if err = c.WriteMessage(op, data); err != nil {
+			return
+		}
+	}
+}
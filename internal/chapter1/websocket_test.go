@@ -0,0 +1,153 @@
+package chapter1
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/dip-dev/go-tutorial/internal/helper/openapi"
+	"github.com/dip-dev/go-tutorial/internal/helper/websocket"
+)
+
+// 停止時にリクエストのコンテキストをキャンセルするサーバーを起動する
+func startServer(t *testing.T, h http.HandlerFunc) (*http.Server, string) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	baseCtx, cancel := context.WithCancel(context.Background())
+	srv := &http.Server{
+		Handler: h,
+		BaseContext: func(net.Listener) context.Context {
+			return baseCtx
+		},
+	}
+	srv.RegisterOnShutdown(cancel)
+	go func() {
+		_ = srv.Serve(l)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		srv.Close()
+	})
+	return srv, "http://" + l.Addr().String()
+}
+
+func TestEchoWebSocket(t *testing.T) {
+	t.Run("正常ケース：メッセージのエコー", func(t *testing.T) {
+		_, url := startServer(t, openapi.Check(t, "/echo/ws", EchoWebSocket))
+		c, _, err := websocket.Dial(context.Background(), url+"/echo/ws", nil)
+		if !assert.NoError(t, err) {
+			return
+		}
+		defer c.Close(websocket.CloseNormalClosure, "")
+		_ = c.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+		assert.NoError(t, c.WriteMessage(websocket.OpText, []byte("dip 太郎")))
+		op, data, err := c.ReadMessage()
+		assert.NoError(t, err)
+		assert.Equal(t, websocket.OpText, op)
+		assert.Equal(t, "dip 太郎", string(data))
+	})
+	t.Run("正常ケース：サーバーからのPing", func(t *testing.T) {
+		oldInterval := wsPingInterval
+		wsPingInterval = 10 * time.Millisecond
+		defer func() { wsPingInterval = oldInterval }()
+
+		_, url := startServer(t, openapi.Check(t, "/echo/ws", EchoWebSocket))
+		c, _, err := websocket.Dial(context.Background(), url+"/echo/ws", nil)
+		if !assert.NoError(t, err) {
+			return
+		}
+		defer c.Close(websocket.CloseNormalClosure, "")
+		_ = c.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+		pinged := make(chan struct{}, 1)
+		c.PingHandler = func([]byte) error {
+			select {
+			case pinged <- struct{}{}:
+			default:
+			}
+			return c.WriteControl(websocket.OpPong, nil)
+		}
+		go func() {
+			_, _, _ = c.ReadMessage()
+		}()
+		select {
+		case <-pinged:
+		case <-time.After(5 * time.Second):
+			t.Error("ping is not received")
+		}
+	})
+	t.Run("正常ケース：サーバー停止時にGoing Awayで閉じる", func(t *testing.T) {
+		srv, url := startServer(t, openapi.Check(t, "/echo/ws", EchoWebSocket))
+		c, _, err := websocket.Dial(context.Background(), url+"/echo/ws", nil)
+		if !assert.NoError(t, err) {
+			return
+		}
+		_ = c.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		assert.NoError(t, srv.Shutdown(ctx))
+
+		_, _, err = c.ReadMessage()
+		var cerr *websocket.CloseError
+		if assert.ErrorAs(t, err, &cerr) {
+			assert.Equal(t, websocket.CloseGoingAway, cerr.Code)
+		}
+	})
+	t.Run("正常ケース：クローズフレームなしで切断されたら接続を解放する", func(t *testing.T) {
+		returned := make(chan struct{})
+		_, url := startServer(t, func(w http.ResponseWriter, r *http.Request) {
+			defer close(returned)
+			EchoWebSocket(w, r)
+		})
+
+		// クローズフレームを送らずに切断するため、ハンドシェイクは直接行う
+		conn, err := net.Dial("tcp", strings.TrimPrefix(url, "http://"))
+		if !assert.NoError(t, err) {
+			return
+		}
+		defer conn.Close()
+		_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+		_, err = fmt.Fprintf(conn, "GET /echo/ws HTTP/1.1\r\nHost: %s\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n"+
+			"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n\r\n", conn.RemoteAddr())
+		if !assert.NoError(t, err) {
+			return
+		}
+		br := bufio.NewReader(conn)
+		res, err := http.ReadResponse(br, nil)
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, http.StatusSwitchingProtocols, res.StatusCode)
+
+		// 送信側だけを閉じ、サーバーが接続を閉じればEOFを受信する
+		assert.NoError(t, conn.(*net.TCPConn).CloseWrite())
+		_, err = io.Copy(io.Discard, br)
+		assert.NoError(t, err)
+		select {
+		case <-returned:
+		case <-time.After(5 * time.Second):
+			t.Error("handler did not return")
+		}
+	})
+	t.Run("異常ケース：アップグレードではない", func(t *testing.T) {
+		_, url := startServer(t, openapi.Check(t, "/echo/ws", EchoWebSocket))
+		res, err := http.Get(url + "/echo/ws")
+		if !assert.NoError(t, err) {
+			return
+		}
+		defer res.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	})
+}
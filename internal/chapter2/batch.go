@@ -0,0 +1,331 @@
+package chapter2
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/dip-dev/go-tutorial/internal/helper/networking"
+)
+
+const (
+	// 1回で登録できるユーザー数の上限
+	maxBatchUsers = 1000
+	// リクエストボディの上限
+	maxBatchBodySize = 10 << 20
+	// mock-apiへ同時にリクエストする数
+	batchConcurrency = 4
+	// 登録を取り消す処理の制限時間
+	// クライアントが切断しても取り消しは最後まで行う
+	compensationTimeout = 30 * time.Second
+)
+
+// 一括登録で作成したユーザー情報
+type BatchUser struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+	Age  int    `json:"age"`
+}
+
+// 1件ごとの登録結果
+type BatchResult struct {
+	// リクエスト内の順番（0始まり）
+	Index  int        `json:"index"`
+	Status int        `json:"status"`
+	User   *BatchUser `json:"user,omitempty"`
+	Error  string     `json:"error,omitempty"`
+	// atomicモードで登録を取り消した場合はtrue
+	RolledBack bool `json:"rolled_back,omitempty"`
+}
+
+// 一括登録の結果
+type BatchResponse struct {
+	Atomic     bool          `json:"atomic"`
+	RolledBack bool          `json:"rolled_back"`
+	Succeeded  int           `json:"succeeded"`
+	Failed     int           `json:"failed"`
+	Results    []BatchResult `json:"results"`
+}
+
+// POST /users:batch
+// JSONの配列またはNDJSONで受け取ったユーザーを1件ずつmock-apiへ登録する
+// atomic=trueの場合は1件でも失敗すると、登録済みのユーザーを削除して取り消す
+func CreateBatch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	atomicMode := false
+	if v := r.URL.Query().Get("atomic"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			http.Error(w, "atomic must be a boolean", http.StatusBadRequest)
+			return
+		}
+		atomicMode = b
+	}
+
+	// リクエストボディの読み込み
+	mediaType := "application/json"
+	if ct := r.Header.Get("Content-Type"); ct != "" {
+		var err error
+		if mediaType, _, err = mime.ParseMediaType(ct); err != nil {
+			http.Error(w, "Invalid Content-Type", http.StatusBadRequest)
+			return
+		}
+	}
+	items, err := decodeBatchItems(http.MaxBytesReader(w, r.Body, maxBatchBodySize), mediaType)
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		switch {
+		case errors.Is(err, errUnsupportedBatchType):
+			http.Error(w, err.Error(), http.StatusUnsupportedMediaType)
+		case errors.As(err, &maxErr):
+			http.Error(w, "Request Entity Too Large", http.StatusRequestEntityTooLarge)
+		default:
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		}
+		return
+	}
+	defer r.Body.Close()
+	if len(items) == 0 {
+		http.Error(w, "users are required", http.StatusBadRequest)
+		return
+	}
+	if len(items) > maxBatchUsers {
+		http.Error(w, fmt.Sprintf("too many users: at most %d users can be created at once", maxBatchUsers), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	// 1件ずつ検証する
+	results := make([]BatchResult, len(items))
+	params := make([]map[string]string, len(items))
+	invalid := false
+	for i, item := range items {
+		results[i].Index = i
+		if err := json.Unmarshal(item, &params[i]); err != nil {
+			results[i].Status = http.StatusBadRequest
+			results[i].Error = "invalid user: " + err.Error()
+			invalid = true
+			continue
+		}
+		if err := validateUserParams(params[i]); err != nil {
+			results[i].Status = http.StatusBadRequest
+			results[i].Error = err.Error()
+			invalid = true
+		}
+	}
+
+	// Clientのインスタンス化
+	c, err := networking.NewClient(targetURL)
+	if err != nil {
+		http.Error(w, "Failed to create client", http.StatusInternalServerError)
+		return
+	}
+
+	// atomicモードでは1件でも不正な場合は登録しない
+	var aborted atomic.Bool
+	if atomicMode && invalid {
+		aborted.Store(true)
+	}
+
+	// 検証済みのユーザーを並行して登録する
+	var pending []int
+	for i := range results {
+		if results[i].Status == 0 {
+			pending = append(pending, i)
+		}
+	}
+	forEachBounded(pending, batchConcurrency, func(i int) {
+		// 取り消しが決まった後は新たに登録しない
+		if aborted.Load() {
+			results[i].Status = http.StatusFailedDependency
+			results[i].Error = "not processed because another user failed"
+			return
+		}
+		results[i] = createBatchUser(r.Context(), c, i, params[i])
+		if results[i].Status != http.StatusCreated && atomicMode {
+			aborted.Store(true)
+		}
+	})
+
+	res := BatchResponse{Atomic: atomicMode, Results: results}
+	if atomicMode && aborted.Load() {
+		// 削除に失敗したユーザーが残る場合は取り消し済みとしない
+		res.RolledBack = compensate(c, results)
+	}
+	for _, result := range results {
+		if result.Status == http.StatusCreated {
+			res.Succeeded++
+		} else {
+			res.Failed++
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusMultiStatus)
+	if err := json.NewEncoder(w).Encode(res); err != nil {
+		http.Error(w, "Failed to encode body", http.StatusInternalServerError)
+	}
+}
+
+var errUnsupportedBatchType = errors.New("Content-Type must be application/json or application/x-ndjson")
+
+// JSONの配列またはNDJSONを1件ずつに分ける
+// 1件ごとの検証は呼び出し元で行う
+func decodeBatchItems(r io.Reader, mediaType string) ([]json.RawMessage, error) {
+	switch mediaType {
+	case "application/json":
+		var items []json.RawMessage
+		dec := json.NewDecoder(r)
+		err := dec.Decode(&items)
+		if err == nil {
+			// 配列の後ろに続くデータは受け付けない
+			if _, err = dec.Token(); err == io.EOF {
+				return items, nil
+			} else if err == nil {
+				err = errors.New("unexpected data after JSON array")
+			}
+		}
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			return nil, err
+		}
+		return nil, errors.New("body must be a JSON array")
+	case "application/x-ndjson":
+		var items []json.RawMessage
+		sc := bufio.NewScanner(r)
+		sc.Buffer(make([]byte, 0, 64*1024), maxBatchBodySize)
+		for sc.Scan() {
+			line := bytes.TrimSpace(sc.Bytes())
+			if len(line) == 0 {
+				continue
+			}
+			items = append(items, json.RawMessage(append([]byte(nil), line...)))
+		}
+		if err := sc.Err(); err != nil {
+			return nil, err
+		}
+		return items, nil
+	}
+	return nil, errUnsupportedBatchType
+}
+
+// 最大n件ずつ並行してfnを呼び出し、全て終わるまで待つ
+func forEachBounded(indexes []int, n int, fn func(i int)) {
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for worker := 0; worker < n; worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				fn(i)
+			}
+		}()
+	}
+	for _, i := range indexes {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+// mock-apiへユーザーを1件登録する
+func createBatchUser(ctx context.Context, c *networking.Client, index int, params map[string]string) BatchResult {
+	result := BatchResult{Index: index}
+
+	// フォームデータの作成
+	formData := url.Values{}
+	formData.Set("name", params["name"])
+	formData.Set("age", params["age"])
+
+	// ヘッダーの設定（Content-TypeはForm）
+	header := map[string][]string{
+		"key": {"dip"},
+	}
+
+	// 外部APIへリクエスト
+	res, err := c.NewRequestAndDo(ctx, http.MethodPost, c.BaseURL.JoinPath("/users"), header, nil, networking.Form(formData))
+	if err != nil {
+		result.Status = http.StatusBadGateway
+		result.Error = err.Error()
+		return result
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK && res.StatusCode != http.StatusCreated {
+		msg, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		result.Status = res.StatusCode
+		if res.StatusCode >= http.StatusInternalServerError {
+			result.Status = http.StatusBadGateway
+		}
+		result.Error = fmt.Sprintf("mock-api returned %d: %s", res.StatusCode, bytes.TrimSpace(msg))
+		return result
+	}
+
+	var u BatchUser
+	if err := json.NewDecoder(res.Body).Decode(&u); err != nil {
+		result.Status = http.StatusBadGateway
+		result.Error = "Failed to decode body"
+		return result
+	}
+	result.Status = http.StatusCreated
+	result.User = &u
+	return result
+}
+
+// 登録済みのユーザーを削除して取り消す
+// 削除に失敗したユーザーは登録済みのまま、エラーを記録する
+// 登録済みのユーザーが1件以上あり、すべて削除できた場合はtrueを返す
+// 1件も登録していない場合は取り消したものがないためfalseを返す
+func compensate(c *networking.Client, results []BatchResult) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), compensationTimeout)
+	defer cancel()
+
+	var created []int
+	for i, result := range results {
+		if result.Status == http.StatusCreated && result.User != nil {
+			created = append(created, i)
+		}
+	}
+	var failed atomic.Bool
+	forEachBounded(created, batchConcurrency, func(i int) {
+		if err := deleteBatchUser(ctx, c, results[i].User.ID); err != nil {
+			results[i].Error = "rollback failed: " + err.Error()
+			failed.Store(true)
+			return
+		}
+		results[i].Status = http.StatusFailedDependency
+		results[i].Error = "rolled back because another user failed"
+		results[i].RolledBack = true
+	})
+	return len(created) > 0 && !failed.Load()
+}
+
+func deleteBatchUser(ctx context.Context, c *networking.Client, id int) error {
+	header := map[string][]string{"key": {"dip"}}
+	res, err := c.NewRequestAndDo(ctx, http.MethodDelete, c.BaseURL.JoinPath("/users", strconv.Itoa(id)), header, nil, nil)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	// 既に削除されている場合も取り消し済みとみなす
+	if res.StatusCode >= http.StatusBadRequest && res.StatusCode != http.StatusNotFound {
+		return fmt.Errorf("mock-api returned %d", res.StatusCode)
+	}
+	return nil
+}
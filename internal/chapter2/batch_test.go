@@ -0,0 +1,291 @@
+package chapter2
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/dip-dev/go-tutorial/internal/helper/openapi"
+	"github.com/dip-dev/go-tutorial/internal/helper/test"
+)
+
+// 指定した名前のユーザーの登録だけ失敗するmock-api
+func newFailingBatchMockAPI(t *testing.T, failName string) *test.MockAPI {
+	t.Helper()
+	m := test.NewMockAPI(test.DefaultMockData())
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost && r.URL.Path == "/users" {
+			_ = r.ParseForm()
+			if r.PostForm.Get("name") == failName {
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				return
+			}
+		}
+		m.ServeHTTP(w, r)
+	}))
+	t.Cleanup(ts.Close)
+	t.Setenv("MOCK_API_URL", ts.URL)
+	return m
+}
+
+func postBatch(t *testing.T, query, contentType, body string) (*httptest.ResponseRecorder, BatchResponse) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "http://localhost/users:batch"+query, strings.NewReader(body))
+	r.Header.Set("Content-Type", contentType)
+	openapi.Check(t, "/users:batch", CreateBatch)(w, r)
+
+	var res BatchResponse
+	if w.Code == http.StatusMultiStatus {
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+	}
+	return w, res
+}
+
+func TestCreateBatch(t *testing.T) {
+	success := map[string]struct {
+		contentType string
+		body        string
+		wantStatus  []int
+		wantUsers   int
+	}{
+		"正常ケース：JSONの配列": {
+			contentType: "application/json",
+			body:        `[{"name":"dip 四郎","age":"30"},{"name":"dip 五郎","age":"31"}]`,
+			wantStatus:  []int{http.StatusCreated, http.StatusCreated},
+			wantUsers:   2,
+		},
+		"正常ケース：NDJSON": {
+			contentType: "application/x-ndjson",
+			body:        "{\"name\":\"dip 四郎\",\"age\":\"30\"}\n\n{\"name\":\"dip 五郎\",\"age\":\"31\"}\n",
+			wantStatus:  []int{http.StatusCreated, http.StatusCreated},
+			wantUsers:   2,
+		},
+		"正常ケース：不正なユーザーのみ登録しない": {
+			contentType: "application/json",
+			body:        `[{"name":"dip 四郎","age":"30"},{"name":"","age":"31"},{"name":"dip 六郎","age":"abc"},{"name":"dip 七郎","age":32}]`,
+			wantStatus:  []int{http.StatusCreated, http.StatusBadRequest, http.StatusBadRequest, http.StatusBadRequest},
+			wantUsers:   1,
+		},
+	}
+	for tn, tc := range success {
+		t.Run(tn, func(t *testing.T) {
+			m := newDetailMockAPI(t)
+
+			w, res := postBatch(t, "", tc.contentType, tc.body)
+
+			assert.Equal(t, http.StatusMultiStatus, w.Code)
+			got := make([]int, 0, len(res.Results))
+			for i, result := range res.Results {
+				assert.Equal(t, i, result.Index)
+				got = append(got, result.Status)
+			}
+			assert.Equal(t, tc.wantStatus, got)
+			assert.Equal(t, tc.wantUsers, res.Succeeded)
+			assert.Equal(t, len(tc.wantStatus)-tc.wantUsers, res.Failed)
+			assert.Len(t, m.Users(), len(test.DefaultMockData().Users)+tc.wantUsers)
+		})
+	}
+
+	fail := map[string]struct {
+		query       string
+		contentType string
+		body        string
+		wantStatus  int
+	}{
+		"異常ケース：配列ではない": {
+			contentType: "application/json",
+			body:        `{"name":"dip 四郎","age":"30"}`,
+			wantStatus:  http.StatusBadRequest,
+		},
+		"異常ケース：配列の後ろにデータが続く": {
+			contentType: "application/json",
+			body:        `[{"name":"dip 四郎","age":"30"}] {"name":"dip 五郎","age":"31"}`,
+			wantStatus:  http.StatusBadRequest,
+		},
+		"異常ケース：配列の後ろに不正なデータが続く": {
+			contentType: "application/json",
+			body:        `[{"name":"dip 四郎","age":"30"}]x`,
+			wantStatus:  http.StatusBadRequest,
+		},
+		"異常ケース：空の配列": {
+			contentType: "application/json",
+			body:        `[]`,
+			wantStatus:  http.StatusBadRequest,
+		},
+		"異常ケース：件数が上限を超える": {
+			contentType: "application/json",
+			body:        "[" + strings.Repeat(`{"name":"dip","age":"1"},`, maxBatchUsers) + `{"name":"dip","age":"1"}]`,
+			wantStatus:  http.StatusRequestEntityTooLarge,
+		},
+		"異常ケース：対応していないContent-Type": {
+			contentType: "text/csv",
+			body:        "name,age\n",
+			wantStatus:  http.StatusUnsupportedMediaType,
+		},
+		"異常ケース：atomicが真偽値ではない": {
+			query:       "?atomic=yes",
+			contentType: "application/json",
+			body:        `[{"name":"dip 四郎","age":"30"}]`,
+			wantStatus:  http.StatusBadRequest,
+		},
+	}
+	for tn, tc := range fail {
+		t.Run(tn, func(t *testing.T) {
+			m := newDetailMockAPI(t)
+
+			w, _ := postBatch(t, tc.query, tc.contentType, tc.body)
+
+			assert.Equal(t, tc.wantStatus, w.Code)
+			assert.Equal(t, test.DefaultMockData().Users, m.Users())
+		})
+	}
+
+	t.Run("正常ケース：外部APIの失敗は1件ごとに返す", func(t *testing.T) {
+		m := newFailingBatchMockAPI(t, "dip 五郎")
+
+		_, res := postBatch(t, "", "application/json", `[{"name":"dip 四郎","age":"30"},{"name":"dip 五郎","age":"31"}]`)
+
+		assert.False(t, res.RolledBack)
+		assert.Equal(t, http.StatusCreated, res.Results[0].Status)
+		assert.Equal(t, http.StatusBadGateway, res.Results[1].Status)
+		assert.Len(t, m.Users(), len(test.DefaultMockData().Users)+1)
+	})
+	t.Run("異常ケース：メソッドが一致しない", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "http://localhost/users:batch", nil)
+		CreateBatch(w, r)
+		assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+	})
+}
+
+func TestCreateBatchAtomic(t *testing.T) {
+	t.Run("正常ケース：全て成功", func(t *testing.T) {
+		m := newDetailMockAPI(t)
+
+		_, res := postBatch(t, "?atomic=true", "application/json", `[{"name":"dip 四郎","age":"30"},{"name":"dip 五郎","age":"31"}]`)
+
+		assert.True(t, res.Atomic)
+		assert.False(t, res.RolledBack)
+		assert.Equal(t, 2, res.Succeeded)
+		assert.Len(t, m.Users(), len(test.DefaultMockData().Users)+2)
+	})
+	t.Run("異常ケース：不正なユーザーがある場合は登録しない", func(t *testing.T) {
+		m := newDetailMockAPI(t)
+
+		_, res := postBatch(t, "?atomic=true", "application/json", `[{"name":"dip 四郎","age":"30"},{"name":"","age":"31"}]`)
+
+		// 1件も登録していないため取り消し済みとしない
+		assert.False(t, res.RolledBack)
+		assert.Equal(t, http.StatusFailedDependency, res.Results[0].Status)
+		assert.Equal(t, http.StatusBadRequest, res.Results[1].Status)
+		assert.Equal(t, 0, res.Succeeded)
+		assert.Equal(t, test.DefaultMockData().Users, m.Users())
+	})
+	t.Run("異常ケース：1件も登録できなかった場合は取り消し済みとしない", func(t *testing.T) {
+		m := newFailingBatchMockAPI(t, "dip 失敗")
+
+		_, res := postBatch(t, "?atomic=true", "application/json", `[{"name":"dip 失敗","age":"20"}]`)
+
+		assert.False(t, res.RolledBack)
+		assert.Equal(t, http.StatusBadGateway, res.Results[0].Status)
+		assert.False(t, res.Results[0].RolledBack)
+		assert.Equal(t, test.DefaultMockData().Users, m.Users())
+	})
+	t.Run("異常ケース：登録に失敗した場合は登録済みのユーザーを削除する", func(t *testing.T) {
+		m := newFailingBatchMockAPI(t, "dip 失敗")
+
+		var items []string
+		for i := 0; i < 20; i++ {
+			items = append(items, fmt.Sprintf(`{"name":"dip %d","age":"20"}`, i))
+		}
+		items = append(items, `{"name":"dip 失敗","age":"20"}`)
+		_, res := postBatch(t, "?atomic=true", "application/json", "["+strings.Join(items, ",")+"]")
+
+		assert.True(t, res.RolledBack)
+		assert.Equal(t, 0, res.Succeeded)
+		assert.Equal(t, len(items), res.Failed)
+		var rolledBack int
+		for _, result := range res.Results[:len(items)-1] {
+			assert.Equal(t, http.StatusFailedDependency, result.Status)
+			if result.RolledBack {
+				rolledBack++
+			}
+		}
+		assert.Equal(t, http.StatusBadGateway, res.Results[len(items)-1].Status)
+		// 登録したユーザーは全て削除されている
+		assert.Positive(t, rolledBack)
+		assert.Equal(t, test.DefaultMockData().Users, m.Users())
+	})
+	t.Run("異常ケース：削除に失敗した場合は取り消し済みとしない", func(t *testing.T) {
+		m := test.NewMockAPI(test.DefaultMockData())
+		// 他のユーザーの登録後に失敗し、削除も失敗するmock-api
+		created := make(chan struct{})
+		var once sync.Once
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch {
+			case r.Method == http.MethodDelete:
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				return
+			case r.Method == http.MethodPost && r.URL.Path == "/users":
+				_ = r.ParseForm()
+				if r.PostForm.Get("name") == "dip 失敗" {
+					select {
+					case <-created:
+					case <-time.After(5 * time.Second):
+					}
+					http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+					return
+				}
+				defer once.Do(func() { close(created) })
+			}
+			m.ServeHTTP(w, r)
+		}))
+		defer ts.Close()
+		t.Setenv("MOCK_API_URL", ts.URL)
+
+		_, res := postBatch(t, "?atomic=true", "application/json", `[{"name":"dip 四郎","age":"30"},{"name":"dip 失敗","age":"20"}]`)
+
+		assert.False(t, res.RolledBack)
+		// 削除できなかったユーザーは登録済みのまま返す
+		assert.Equal(t, http.StatusCreated, res.Results[0].Status)
+		assert.False(t, res.Results[0].RolledBack)
+		assert.Contains(t, res.Results[0].Error, "rollback failed")
+		assert.Equal(t, http.StatusBadGateway, res.Results[1].Status)
+		assert.Len(t, m.Users(), len(test.DefaultMockData().Users)+1)
+	})
+}
+
+func TestForEachBounded(t *testing.T) {
+	var running, maxRunning int32
+	done := make([]bool, 50)
+	indexes := make([]int, len(done))
+	for i := range indexes {
+		indexes[i] = i
+	}
+
+	forEachBounded(indexes, 3, func(i int) {
+		n := atomic.AddInt32(&running, 1)
+		for {
+			m := atomic.LoadInt32(&maxRunning)
+			if n <= m || atomic.CompareAndSwapInt32(&maxRunning, m, n) {
+				break
+			}
+		}
+		done[i] = true
+		atomic.AddInt32(&running, -1)
+	})
+
+	assert.LessOrEqual(t, maxRunning, int32(3))
+	for _, d := range done {
+		assert.True(t, d)
+	}
+}
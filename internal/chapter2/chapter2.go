@@ -3,19 +3,32 @@ package chapter2
 import (
 	"context"
 	"encoding/json"
-	"io"
+	"errors"
 	"net/http"
 	"net/url"
 	"strconv"
 
+	"github.com/dip-dev/go-tutorial/internal/helper/fieldset"
 	"github.com/dip-dev/go-tutorial/internal/helper/networking"
+	"github.com/dip-dev/go-tutorial/internal/helper/proxy"
 )
 
 const targetURL = "http://mock-api"
 
+// 外部APIのレスポンスを中継する
+var responseProxy = proxy.NewResponseWriter()
+
 type User struct {
-	Name string
-	Age  int
+	Name string `json:"name"`
+	Age  int    `json:"age"`
+}
+
+// 一覧のユーザー情報（fieldsで絞り込める項目）
+// 外部APIが返すidも指定できるよう、Userとは別に定義する
+type listedUser struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+	Age  int    `json:"age"`
 }
 
 func Create(w http.ResponseWriter, r *http.Request) {
@@ -36,16 +49,8 @@ func Create(w http.ResponseWriter, r *http.Request) {
 	defer r.Body.Close()
 
 	// 必須パラメータのチェック
-	if name, ok := params["name"]; !ok || name == "" {
-		http.Error(w, "name is required", http.StatusBadRequest)
-		return
-	}
-	if age, ok := params["age"]; !ok || age == "" {
-		http.Error(w, "age is required", http.StatusBadRequest)
-		return
-	}
-	if _, err := strconv.Atoi(params["age"]); err != nil {
-		http.Error(w, "age is not a number", http.StatusBadRequest)
+	if err := validateUserParams(params); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
@@ -61,10 +66,9 @@ func Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// ヘッダーの設定
+	// ヘッダーの設定（Content-TypeはForm）
 	header := map[string][]string{
-		"key":          {"dip"},
-		"Content-Type": {"application/x-www-form-urlencoded"},
+		"key": {"dip"},
 	}
 
 	// 外部APIへリクエスト
@@ -74,7 +78,7 @@ func Create(w http.ResponseWriter, r *http.Request) {
 		c.BaseURL.JoinPath("/users"),
 		header,
 		nil,
-		formData.Encode(),
+		networking.Form(formData),
 	)
 	if err2 != nil {
 		http.Error(w, err2.Error(), http.StatusInternalServerError)
@@ -82,21 +86,28 @@ func Create(w http.ResponseWriter, r *http.Request) {
 	}
 	defer res.Body.Close()
 
-	// ヘッダーをコピー
-	for k, vs := range res.Header {
-		for _, v := range vs {
-			w.Header().Set(k, v)
-		}
-	}
-	// ステータスコードをコピー
-	w.WriteHeader(res.StatusCode)
-	// ボディをコピー
-	if _, err3 := io.Copy(w, res.Body); err3 != nil {
+	// ヘッダー・ステータスコード・ボディをコピー
+	if err3 := responseProxy.WriteResponse(w, res); err3 != nil {
 		http.Error(w, "Failed to copy body", http.StatusInternalServerError)
 		return
 	}
 }
 
+// ユーザー登録のパラメータを検証する
+// 一括登録でも同じ条件で検証する
+func validateUserParams(params map[string]string) error {
+	if name, ok := params["name"]; !ok || name == "" {
+		return errors.New("name is required")
+	}
+	if age, ok := params["age"]; !ok || age == "" {
+		return errors.New("age is required")
+	}
+	if _, err := strconv.Atoi(params["age"]); err != nil {
+		return errors.New("age is not a number")
+	}
+	return nil
+}
+
 func Get(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
@@ -113,8 +124,17 @@ func Get(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Invalid parameters", http.StatusBadRequest)
 		return
 	}
+	// レスポンスに含める項目の指定は外部APIへは渡さない
+	fields, err := fieldset.Parse(r.Form.Get("fields"), listedUser{})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	params := map[string][]string{}
 	for k, v := range r.Form {
+		if k == "fields" {
+			continue
+		}
 		params[k] = append(params[k], v...)
 	}
 
@@ -132,16 +152,31 @@ func Get(w http.ResponseWriter, r *http.Request) {
 	}
 	defer res.Body.Close()
 
-	// ヘッダーをコピー
-	for k, vs := range res.Header {
-		for _, v := range vs {
-			w.Header().Set(k, v)
-		}
+	// 項目の指定がある場合は絞り込んで返却する
+	if !fields.Empty() && res.StatusCode == http.StatusOK {
+		writeProjectedUsers(w, res, fields)
+		return
 	}
-	// ステータスコードをコピー
-	w.WriteHeader(res.StatusCode)
-	// ボディをコピー
-	if _, err3 := io.Copy(w, res.Body); err3 != nil {
+
+	// ヘッダー・ステータスコード・ボディをコピー
+	if err3 := responseProxy.WriteResponse(w, res); err3 != nil {
 		http.Error(w, "Failed to copy body", http.StatusInternalServerError)
 	}
 }
+
+// ユーザー情報を指定された項目だけに絞り込んで返却する
+func writeProjectedUsers(w http.ResponseWriter, res *http.Response, fields fieldset.Fields) {
+	var users []listedUser
+	if err := json.NewDecoder(res.Body).Decode(&users); err != nil {
+		http.Error(w, "Failed to decode body", http.StatusBadGateway)
+		return
+	}
+
+	// ボディを書き換えるため、Content-Lengthはコピーしない
+	responseProxy.CopyHeader(w.Header(), res)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(res.StatusCode)
+	if err := json.NewEncoder(w).Encode(fields.Project(users)); err != nil {
+		http.Error(w, "Failed to encode body", http.StatusInternalServerError)
+	}
+}
@@ -12,10 +12,18 @@ import (
 
 	"github.com/stretchr/testify/assert"
 
+	"github.com/dip-dev/go-tutorial/internal/helper/idempotency"
+	"github.com/dip-dev/go-tutorial/internal/helper/networking"
+	"github.com/dip-dev/go-tutorial/internal/helper/openapi"
 	"github.com/dip-dev/go-tutorial/internal/helper/test"
 )
 
 func TestMain(m *testing.M) {
+	// mock-apiの代わりにローカルのサーバーを使う
+	ts := httptest.NewServer(test.NewMockAPI(test.DefaultMockData()))
+	defer ts.Close()
+	os.Setenv("MOCK_API_URL", ts.URL)
+
 	m.Run()
 }
 
@@ -63,7 +71,7 @@ func TestGet(t *testing.T) {
 
 			w := httptest.NewRecorder()
 			r := httptest.NewRequest(http.MethodGet, "http://localhost/?"+param.Encode(), nil)
-			Get(w, r)
+			openapi.Check(t, "/users", Get)(w, r)
 			got := []User{}
 			t.Logf(w.Body.String())
 			if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
@@ -77,7 +85,7 @@ func TestGet(t *testing.T) {
 		t.Run(tn, func(t *testing.T) {
 			w := httptest.NewRecorder()
 			r := httptest.NewRequest(tc.method, "http://localhost/", nil)
-			Get(w, r)
+			openapi.Check(t, "/users", Get)(w, r)
 			assert.Equal(t, tc.wantStatus, w.Code)
 		})
 	}
@@ -85,7 +93,7 @@ func TestGet(t *testing.T) {
 		r := httptest.NewRequest(http.MethodGet, "http://localhost/?%", nil)
 		w := httptest.NewRecorder()
 
-		Get(w, r)
+		openapi.Check(t, "/users", Get)(w, r)
 
 		assert.Equal(t, http.StatusBadRequest, w.Code)
 	})
@@ -98,7 +106,7 @@ func TestGet(t *testing.T) {
 		errW := &test.ErrorResponseWriter{}
 
 		// 呼び出し
-		Get(errW, r)
+		openapi.Check(t, "/users", Get)(errW, r)
 
 		assert.Equal(t, http.StatusInternalServerError, errW.Code())
 	})
@@ -115,21 +123,81 @@ func TestGet(t *testing.T) {
 		r := httptest.NewRequest(http.MethodGet, "http://localhost/?"+param.Encode(), nil)
 		w := httptest.NewRecorder()
 
-		Get(w, r)
+		openapi.Check(t, "/users", Get)(w, r)
 
 		assert.Equal(t, http.StatusInternalServerError, w.Code)
 	})
-	t.Run("異常ケース:外部APIリクエストに失敗", func(t *testing.T) {
+	t.Run("正常ケース:項目を指定", func(t *testing.T) {
+		handlers := []test.Handler{
+			{
+				Path: "/users",
+				Handler: func(w http.ResponseWriter, r *http.Request) {
+					// 項目の指定は外部APIへ渡さない
+					assert.NotContains(t, r.URL.Query(), "fields")
+					w.Header().Set("Content-Type", "application/json")
+					_, _ = w.Write([]byte(`[{"name":"dip 太郎","age":25},{"name":"dip 花子","age":25}]`))
+				},
+			},
+		}
+		ts := httptest.NewServer(test.Route(handlers...))
+		defer ts.Close()
 
-		// エラーを起こすためにリダイレクトする
+		// 環境変数を一時的に変更
+		oldURL := os.Getenv("MOCK_API_URL")
+		os.Setenv("MOCK_API_URL", ts.URL)
+		defer os.Setenv("MOCK_API_URL", oldURL)
+
+		r := httptest.NewRequest(http.MethodGet, "http://localhost/?age=25&fields=name", nil)
+		w := httptest.NewRecorder()
+
+		openapi.Check(t, "/users", Get)(w, r)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `[{"name":"dip 太郎"},{"name":"dip 花子"}]`, w.Body.String())
+	})
+	t.Run("正常ケース:idを含む項目を指定", func(t *testing.T) {
 		handlers := []test.Handler{
 			{
 				Path: "/users",
 				Handler: func(w http.ResponseWriter, r *http.Request) {
-					http.Redirect(w, r, "/users", http.StatusFound)
+					w.Header().Set("Content-Type", "application/json")
+					_, _ = w.Write([]byte(`[{"id":1,"name":"dip 太郎","age":25},{"id":2,"name":"dip 花子","age":25}]`))
 				},
 			},
 		}
+		ts := httptest.NewServer(test.Route(handlers...))
+		defer ts.Close()
+
+		// 環境変数を一時的に変更
+		oldURL := os.Getenv("MOCK_API_URL")
+		os.Setenv("MOCK_API_URL", ts.URL)
+		defer os.Setenv("MOCK_API_URL", oldURL)
+
+		r := httptest.NewRequest(http.MethodGet, "http://localhost/?age=25&fields=id,name", nil)
+		w := httptest.NewRecorder()
+
+		openapi.Check(t, "/users", Get)(w, r)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `[{"id":1,"name":"dip 太郎"},{"id":2,"name":"dip 花子"}]`, w.Body.String())
+	})
+	t.Run("異常: 存在しない項目を指定", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "http://localhost/?fields=name,salary", nil)
+		w := httptest.NewRecorder()
+
+		openapi.Check(t, "/users", Get)(w, r)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+	t.Run("異常ケース:外部APIリクエストに失敗", func(t *testing.T) {
+
+		// エラーを起こすためにリダイレクトする
+		handlers := []test.Handler{
+			{
+				Path:    "/users",
+				Handler: test.RedirectHandler("/users"),
+			},
+		}
 
 		// エラーを返すモックサーバーを作成
 		ts := httptest.NewServer(test.Route(handlers...))
@@ -147,7 +215,7 @@ func TestGet(t *testing.T) {
 		r := httptest.NewRequest(http.MethodGet, "http://localhost/?"+param.Encode(), nil)
 		w := httptest.NewRecorder()
 
-		Get(w, r)
+		openapi.Check(t, "/users", Get)(w, r)
 
 		assert.Equal(t, http.StatusInternalServerError, w.Code)
 	})
@@ -222,7 +290,7 @@ func TestCreate(t *testing.T) {
 			// Content-Typeヘッダーを追加
 			r.Header.Set("Content-Type", "application/json")
 
-			Create(w, r)
+			openapi.Check(t, "/users", Create)(w, r)
 
 			var got User
 			if err = json.NewDecoder(w.Body).Decode(&got); err != nil {
@@ -248,7 +316,7 @@ func TestCreate(t *testing.T) {
 				r = httptest.NewRequest(http.MethodPost, "http://localhost/", bytes.NewReader(params))
 			}
 
-			Create(w, r)
+			openapi.Check(t, "/users", Create)(w, r)
 
 			assert.Equal(t, tc.wantStatus, w.Code)
 		})
@@ -259,7 +327,7 @@ func TestCreate(t *testing.T) {
 		w := httptest.NewRecorder()
 
 		// 呼び出し
-		Create(w, r)
+		openapi.Check(t, "/users", Create)(w, r)
 
 		assert.Equal(t, http.StatusBadRequest, w.Code)
 	})
@@ -269,11 +337,12 @@ func TestCreate(t *testing.T) {
 			"age":  "24",
 		})
 		r := httptest.NewRequest(http.MethodPost, "http://localhost/", bytes.NewReader(params))
+		r.Header.Set("Content-Type", "application/json")
 		// エンコード処理でエラーを返すカスタムResponseWriterを利用
 		errW := &test.ErrorResponseWriter{}
 
 		// 呼び出し
-		Create(errW, r)
+		openapi.Check(t, "/users", Create)(errW, r)
 
 		assert.Equal(t, http.StatusInternalServerError, errW.Code())
 	})
@@ -292,7 +361,7 @@ func TestCreate(t *testing.T) {
 		r := httptest.NewRequest(http.MethodPost, "http://localhost/", bytes.NewReader(params))
 		w := httptest.NewRecorder()
 
-		Create(w, r)
+		openapi.Check(t, "/users", Create)(w, r)
 
 		assert.Equal(t, http.StatusInternalServerError, w.Code)
 	})
@@ -301,10 +370,8 @@ func TestCreate(t *testing.T) {
 		// エラーを起こすためにリダイレクトする
 		handlers := []test.Handler{
 			{
-				Path: "/users",
-				Handler: func(w http.ResponseWriter, r *http.Request) {
-					http.Redirect(w, r, "/users", http.StatusFound)
-				},
+				Path:    "/users",
+				Handler: test.RedirectHandler("/users"),
 			},
 		}
 
@@ -325,8 +392,98 @@ func TestCreate(t *testing.T) {
 		w := httptest.NewRecorder()
 		r := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(params))
 
-		Create(w, r)
+		openapi.Check(t, "/users", Create)(w, r)
 
 		assert.Equal(t, http.StatusInternalServerError, w.Code)
 	})
 }
+
+// Idempotency-Keyはミドルウェアで処理する
+func TestCreateIdempotency(t *testing.T) {
+	m := newDetailMockAPI(t)
+	h := idempotency.New(idempotency.NewMemoryStore()).HandlerFunc(Create)
+	post := func(name string) *httptest.ResponseRecorder {
+		params, _ := json.Marshal(map[string]string{"name": name, "age": "24"})
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "http://localhost/users", bytes.NewReader(params))
+		r.Header.Set("Content-Type", "application/json")
+		r.Header.Set(idempotency.KeyHeader, "create-user")
+		openapi.Check(t, "/users", h)(w, r)
+		return w
+	}
+
+	first := post("dip 四郎")
+	second := post("dip 四郎")
+	assert.Equal(t, http.StatusOK, first.Code)
+	assert.Equal(t, first.Body.String(), second.Body.String())
+	assert.Equal(t, "true", second.Header().Get(idempotency.ReplayedHeader))
+	// 再送ではユーザーを登録しない
+	assert.Len(t, m.Users(), len(test.DefaultMockData().Users)+1)
+
+	// 同じキーで内容が異なる場合は拒否する
+	assert.Equal(t, http.StatusUnprocessableEntity, post("dip 五郎").Code)
+	assert.Len(t, m.Users(), len(test.DefaultMockData().Users)+1)
+}
+
+// 記録済みのmock-apiのレスポンスを再生してテストする
+// testdata/get_users.jsonは手書きのフィクスチャ（noteを参照）
+// VCR_MODE=record で実行するとtestdata配下のファイルを更新する
+func TestGetReplay(t *testing.T) {
+	rec, err := networking.NewRecorder("testdata/get_users.json", networking.RecorderModeFromEnv())
+	if !assert.NoError(t, err) {
+		return
+	}
+	networking.SetDefaultOptions(networking.WithHTTPClient(rec.Client()))
+	defer networking.SetDefaultOptions()
+
+	success := map[string]struct {
+		query    string
+		wantBody string
+	}{
+		"正常ケース：年齢で絞り込み": {
+			query:    "age=25",
+			wantBody: `[{"id":123456,"name":"dip 太郎","age":25},{"id":345678,"name":"dip 花子","age":25}]`,
+		},
+		"正常ケース：名前で絞り込み": {
+			query:    "name=dip+%E6%AC%A1%E9%83%8E",
+			wantBody: `[{"id":234567,"name":"dip 次郎","age":24}]`,
+		},
+	}
+
+	for tn, tc := range success {
+		t.Run(tn, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "http://localhost/users?"+tc.query, nil)
+			openapi.Check(t, "/users", Get)(w, r)
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.JSONEq(t, tc.wantBody, w.Body.String())
+		})
+	}
+
+	assert.NoError(t, rec.Save())
+}
+
+// 外部APIのヘッダーを中継する際の扱い
+func TestGetProxyHeaders(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Set-Cookie", "a=1")
+		w.Header().Add("Set-Cookie", "b=2")
+		w.Header().Set("Connection", "X-Hop")
+		w.Header().Set("X-Hop", "1")
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`[]`))
+	}))
+	defer ts.Close()
+	t.Setenv("MOCK_API_URL", ts.URL)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "http://localhost/users", nil)
+	openapi.Check(t, "/users", Get)(w, r)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []string{"a=1", "b=2"}, w.Header().Values("Set-Cookie"))
+	assert.Empty(t, w.Header().Get("Connection"))
+	assert.Empty(t, w.Header().Get("X-Hop"))
+	assert.Equal(t, "2", w.Header().Get("Content-Length"))
+	assert.Equal(t, "1.1 go-tutorial", w.Header().Get("Via"))
+}
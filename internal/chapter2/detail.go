@@ -0,0 +1,267 @@
+package chapter2
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/dip-dev/go-tutorial/internal/helper/etag"
+	"github.com/dip-dev/go-tutorial/internal/helper/networking"
+)
+
+// JSON Merge Patch（RFC 7396）のメディアタイプ
+const mergePatchContentType = "application/merge-patch+json"
+
+// ユーザー情報の詳細
+type userDetail struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+	Age  int    `json:"age"`
+}
+
+// ユーザー情報の置き換え内容
+// idは変更できないが、指定された場合はmock-apiで競合として扱うためそのまま渡す
+// ageは登録と同じく数値の文字列で受け付ける
+type userReplacement struct {
+	ID   *int   `json:"id,omitempty"`
+	Name string `json:"name"`
+	Age  string `json:"age"`
+}
+
+// GET /users/{id}
+func Detail(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	id, ok := userID(r)
+	if !ok {
+		http.Error(w, "user not found", http.StatusNotFound)
+		return
+	}
+	proxyUser(w, r, id, nil)
+}
+
+// PUT /users/{id}
+func Update(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	id, ok := userID(r)
+	if !ok {
+		http.Error(w, "user not found", http.StatusNotFound)
+		return
+	}
+
+	// リクエストボディの設定
+	var params userReplacement
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	// 必須パラメータのチェック
+	if err := validateUserParams(map[string]string{"name": params.Name, "age": params.Age}); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	proxyUser(w, r, id, networking.JSON(params))
+}
+
+// PATCH /users/{id}
+// ボディはJSON Merge Patchとしてmock-apiへそのまま渡す
+func Patch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPatch {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	id, ok := userID(r)
+	if !ok {
+		http.Error(w, "user not found", http.StatusNotFound)
+		return
+	}
+
+	// application/jsonも互換のため受け付ける
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != mergePatchContentType && mediaType != "application/json" {
+		w.Header().Set("Accept-Patch", mergePatchContentType)
+		http.Error(w, "Content-Type must be "+mergePatchContentType, http.StatusUnsupportedMediaType)
+		return
+	}
+
+	// リクエストボディの設定
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	// 項目の削除（null）も含めて変換せずに渡すため、オブジェクトであることだけ確認する
+	var patch map[string]json.RawMessage
+	if err := json.Unmarshal(body, &patch); err != nil {
+		http.Error(w, "body must be a JSON object", http.StatusBadRequest)
+		return
+	}
+	// ageは登録・置き換えと同じく数値の文字列で受け付ける（nullは削除）
+	if age, ok := patch["age"]; ok && string(age) != "null" {
+		var v string
+		if err := json.Unmarshal(age, &v); err != nil {
+			http.Error(w, "age must be a string", http.StatusBadRequest)
+			return
+		}
+		if _, err := strconv.Atoi(v); err != nil {
+			http.Error(w, "age is not a number", http.StatusBadRequest)
+			return
+		}
+	}
+
+	proxyUser(w, r, id, networking.Raw(bytes.NewReader(body), mergePatchContentType))
+}
+
+// DELETE /users/{id}
+func Delete(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	id, ok := userID(r)
+	if !ok {
+		http.Error(w, "user not found", http.StatusNotFound)
+		return
+	}
+	proxyUser(w, r, id, nil)
+}
+
+// パスからユーザーIDを取り出す
+func userID(r *http.Request) (int, bool) {
+	rest := strings.TrimPrefix(r.URL.Path, "/users/")
+	id, err := strconv.Atoi(rest)
+	if err != nil || id <= 0 {
+		return 0, false
+	}
+	return id, true
+}
+
+// mock-apiの/users/{id}へリクエストし、結果を返却する
+// 現在のユーザー情報を取得してETagを計算し、If-Match・If-None-Matchの判定はここで行う
+func proxyUser(w http.ResponseWriter, r *http.Request, id int, body any) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// ヘッダーの設定（Content-Typeはbodyの形式で決まる）
+	header := map[string][]string{"key": {"dip"}}
+
+	// Clientのインスタンス化
+	c, err := networking.NewClient(targetURL)
+	if err != nil {
+		http.Error(w, "Failed to create client", http.StatusInternalServerError)
+		return
+	}
+	target := c.BaseURL.JoinPath("/users", strconv.Itoa(id))
+
+	// 現在のユーザー情報を取得する（存在しない場合は404をそのまま返す）
+	current, upstreamETag, ok := fetchUser(ctx, w, c, target, header)
+	if !ok {
+		return
+	}
+	tag := userETag(current)
+
+	if r.Method == http.MethodGet {
+		if etag.IfNoneMatch(r.Header.Get("If-None-Match"), tag) {
+			w.Header().Set("ETag", tag)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		writeUserDetail(w, current)
+		return
+	}
+
+	if ifMatch := r.Header.Get("If-Match"); ifMatch != "" && !etag.IfMatch(ifMatch, tag) {
+		http.Error(w, "Precondition Failed", http.StatusPreconditionFailed)
+		return
+	}
+	// 取得してから更新するまでに変更された場合は、mock-apiのETagで検知して412を返す
+	if upstreamETag != "" {
+		header["If-Match"] = []string{upstreamETag}
+	}
+
+	// 外部APIへリクエスト
+	res, err2 := c.NewRequestAndDo(ctx, r.Method, target, header, nil, body)
+	if err2 != nil {
+		http.Error(w, err2.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer res.Body.Close()
+
+	// 削除の204や409・412などはヘッダー・ステータスコード・ボディをコピー
+	if res.StatusCode != http.StatusOK {
+		copyUserResponse(w, res)
+		return
+	}
+	var updated userDetail
+	if err3 := json.NewDecoder(res.Body).Decode(&updated); err3 != nil {
+		http.Error(w, "Failed to decode body", http.StatusBadGateway)
+		return
+	}
+	writeUserDetail(w, updated)
+}
+
+// mock-apiのレスポンスをそのまま返す
+// ステータスコードを送った後のため、失敗した場合は接続を切断してクライアントに途中で切れたことを伝える
+func copyUserResponse(w http.ResponseWriter, res *http.Response) {
+	if err := responseProxy.WriteResponse(w, res); err != nil {
+		log.Printf("failed to copy user response: %+v", err)
+		panic(http.ErrAbortHandler)
+	}
+}
+
+// mock-apiからユーザー情報を取得する
+// 取得できなかった場合はレスポンスを書き出してfalseを返す
+func fetchUser(ctx context.Context, w http.ResponseWriter, c *networking.Client, target *url.URL, header map[string][]string) (userDetail, string, bool) {
+	res, err := c.NewRequestAndDo(ctx, http.MethodGet, target, header, nil, nil)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return userDetail{}, "", false
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		copyUserResponse(w, res)
+		return userDetail{}, "", false
+	}
+	var u userDetail
+	if err := json.NewDecoder(res.Body).Decode(&u); err != nil {
+		http.Error(w, "Failed to decode body", http.StatusBadGateway)
+		return userDetail{}, "", false
+	}
+	return u, res.Header.Get("ETag"), true
+}
+
+func writeUserDetail(w http.ResponseWriter, u userDetail) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", userETag(u))
+	if err := json.NewEncoder(w).Encode(u); err != nil {
+		log.Printf("failed to write user detail: %+v", err)
+		panic(http.ErrAbortHandler)
+	}
+}
+
+// ユーザー情報のETag（内容から計算する強いETag）
+func userETag(u userDetail) string {
+	b, _ := json.Marshal(u)
+	sum := sha256.Sum256(b)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
@@ -0,0 +1,330 @@
+package chapter2
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/dip-dev/go-tutorial/internal/helper/openapi"
+	"github.com/dip-dev/go-tutorial/internal/helper/test"
+)
+
+// 更新系のテストで他のテストのデータを変更しないよう、ケースごとにmock-apiを用意する
+func newDetailMockAPI(t *testing.T) *test.MockAPI {
+	t.Helper()
+	m := test.NewMockAPI(test.DefaultMockData())
+	ts := httptest.NewServer(m)
+	t.Cleanup(ts.Close)
+	t.Setenv("MOCK_API_URL", ts.URL)
+	return m
+}
+
+func TestUserDetail(t *testing.T) {
+	taro := userDetail{ID: 123456, Name: "dip 太郎", Age: 25}
+
+	success := map[string]struct {
+		handler     http.HandlerFunc
+		method      string
+		contentType string
+		ifMatch     string
+		body        string
+		wantStatus  int
+		wantBody    string
+		wantUsers   []test.MockUser
+	}{
+		"正常ケース：取得": {
+			handler:    Detail,
+			method:     http.MethodGet,
+			wantStatus: http.StatusOK,
+			wantBody:   `{"id":123456,"name":"dip 太郎","age":25}`,
+		},
+		"正常ケース：置き換え": {
+			handler:     Update,
+			method:      http.MethodPut,
+			contentType: "application/json",
+			ifMatch:     userETag(taro),
+			body:        `{"name":"dip 太郎","age":"26"}`,
+			wantStatus:  http.StatusOK,
+			wantBody:    `{"id":123456,"name":"dip 太郎","age":26}`,
+		},
+		"正常ケース：Merge Patchで部分的に更新": {
+			handler:     Patch,
+			method:      http.MethodPatch,
+			contentType: "application/merge-patch+json",
+			ifMatch:     userETag(taro),
+			body:        `{"name":"dip 一郎"}`,
+			wantStatus:  http.StatusOK,
+			wantBody:    `{"id":123456,"name":"dip 一郎","age":25}`,
+		},
+		"正常ケース：削除": {
+			handler:    Delete,
+			method:     http.MethodDelete,
+			ifMatch:    userETag(taro),
+			wantStatus: http.StatusNoContent,
+			wantUsers: []test.MockUser{
+				{ID: 234567, Name: "dip 次郎", Age: 24},
+				{ID: 345678, Name: "dip 花子", Age: 25},
+			},
+		},
+	}
+	for tn, tc := range success {
+		t.Run(tn, func(t *testing.T) {
+			m := newDetailMockAPI(t)
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(tc.method, "http://localhost/users/123456", strings.NewReader(tc.body))
+			if tc.contentType != "" {
+				r.Header.Set("Content-Type", tc.contentType)
+			}
+			if tc.ifMatch != "" {
+				r.Header.Set("If-Match", tc.ifMatch)
+			}
+			openapi.Check(t, "/users/{id}", tc.handler)(w, r)
+
+			assert.Equal(t, tc.wantStatus, w.Code)
+			if tc.wantBody != "" {
+				assert.JSONEq(t, tc.wantBody, w.Body.String())
+				// 更新後の内容のETagを返す
+				u := m.Users()[0]
+				assert.Equal(t, userETag(userDetail{ID: u.ID, Name: u.Name, Age: u.Age}), w.Header().Get("ETag"))
+			}
+			if tc.wantUsers != nil {
+				assert.Equal(t, tc.wantUsers, m.Users())
+			}
+		})
+	}
+
+	fail := map[string]struct {
+		handler     http.HandlerFunc
+		method      string
+		path        string
+		contentType string
+		ifMatch     string
+		body        string
+		wantStatus  int
+	}{
+		"異常ケース：存在しないユーザー": {
+			handler:    Detail,
+			method:     http.MethodGet,
+			path:       "/users/1",
+			wantStatus: http.StatusNotFound,
+		},
+		"異常ケース：IDが数値ではない": {
+			handler:    Delete,
+			method:     http.MethodDelete,
+			path:       "/users/abc",
+			wantStatus: http.StatusNotFound,
+		},
+		"異常ケース：ETagが一致しない": {
+			handler:     Update,
+			method:      http.MethodPut,
+			path:        "/users/123456",
+			contentType: "application/json",
+			ifMatch:     `"stale"`,
+			body:        `{"name":"dip 太郎","age":"26"}`,
+			wantStatus:  http.StatusPreconditionFailed,
+		},
+		"異常ケース：削除時にETagが一致しない": {
+			handler:    Delete,
+			method:     http.MethodDelete,
+			path:       "/users/123456",
+			ifMatch:    `"stale"`,
+			wantStatus: http.StatusPreconditionFailed,
+		},
+		"異常ケース：IDを変更": {
+			handler:     Patch,
+			method:      http.MethodPatch,
+			path:        "/users/123456",
+			contentType: "application/merge-patch+json",
+			body:        `{"id":1}`,
+			wantStatus:  http.StatusConflict,
+		},
+		"異常ケース：置き換え時にIDを変更": {
+			handler:     Update,
+			method:      http.MethodPut,
+			path:        "/users/123456",
+			contentType: "application/json",
+			body:        `{"id":1,"name":"dip 太郎","age":"26"}`,
+			wantStatus:  http.StatusConflict,
+		},
+		"異常ケース：置き換え時に名前なし": {
+			handler:     Update,
+			method:      http.MethodPut,
+			path:        "/users/123456",
+			contentType: "application/json",
+			body:        `{"age":"26"}`,
+			wantStatus:  http.StatusBadRequest,
+		},
+		"異常ケース：置き換え時に年齢なし": {
+			handler:     Update,
+			method:      http.MethodPut,
+			path:        "/users/123456",
+			contentType: "application/json",
+			body:        `{"name":"dip 太郎"}`,
+			wantStatus:  http.StatusBadRequest,
+		},
+		"異常ケース：置き換え時に年齢が文字列ではない": {
+			handler:     Update,
+			method:      http.MethodPut,
+			path:        "/users/123456",
+			contentType: "application/json",
+			body:        `{"name":"dip 太郎","age":26}`,
+			wantStatus:  http.StatusBadRequest,
+		},
+		"異常ケース：置き換え時に年齢が数値ではない": {
+			handler:     Update,
+			method:      http.MethodPut,
+			path:        "/users/123456",
+			contentType: "application/json",
+			body:        `{"name":"dip 太郎","age":"abc"}`,
+			wantStatus:  http.StatusBadRequest,
+		},
+		"異常ケース：Merge Patchの年齢が文字列ではない": {
+			handler:     Patch,
+			method:      http.MethodPatch,
+			path:        "/users/123456",
+			contentType: "application/merge-patch+json",
+			body:        `{"age":26}`,
+			wantStatus:  http.StatusBadRequest,
+		},
+		"異常ケース：Merge Patchの年齢が数値ではない": {
+			handler:     Patch,
+			method:      http.MethodPatch,
+			path:        "/users/123456",
+			contentType: "application/merge-patch+json",
+			body:        `{"age":"abc"}`,
+			wantStatus:  http.StatusBadRequest,
+		},
+		"異常ケース：不正なJSON": {
+			handler:     Update,
+			method:      http.MethodPut,
+			path:        "/users/123456",
+			contentType: "application/json",
+			body:        `{`,
+			wantStatus:  http.StatusBadRequest,
+		},
+		"異常ケース：Merge Patchがオブジェクトではない": {
+			handler:     Patch,
+			method:      http.MethodPatch,
+			path:        "/users/123456",
+			contentType: "application/merge-patch+json",
+			body:        `[]`,
+			wantStatus:  http.StatusBadRequest,
+		},
+		"異常ケース：必須の項目を削除": {
+			handler:     Patch,
+			method:      http.MethodPatch,
+			path:        "/users/123456",
+			contentType: "application/merge-patch+json",
+			body:        `{"age":null}`,
+			wantStatus:  http.StatusBadRequest,
+		},
+		"異常ケース：Merge Patchではない": {
+			handler:     Patch,
+			method:      http.MethodPatch,
+			path:        "/users/123456",
+			contentType: "text/plain",
+			body:        `{"age":30}`,
+			wantStatus:  http.StatusUnsupportedMediaType,
+		},
+		"異常ケース：メソッドが一致しない": {
+			handler:    Detail,
+			method:     http.MethodPost,
+			path:       "/users/123456",
+			wantStatus: http.StatusMethodNotAllowed,
+		},
+	}
+	for tn, tc := range fail {
+		t.Run(tn, func(t *testing.T) {
+			m := newDetailMockAPI(t)
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(tc.method, "http://localhost"+tc.path, strings.NewReader(tc.body))
+			if tc.contentType != "" {
+				r.Header.Set("Content-Type", tc.contentType)
+			}
+			if tc.ifMatch != "" {
+				r.Header.Set("If-Match", tc.ifMatch)
+			}
+			openapi.Check(t, "/users/{id}", tc.handler)(w, r)
+
+			assert.Equal(t, tc.wantStatus, w.Code)
+			// 失敗した場合はデータを変更しない
+			assert.Equal(t, test.DefaultMockData().Users, m.Users())
+		})
+	}
+
+	t.Run("異常ケース：ヘッダーを送った後に書き込めない場合は接続を切断する", func(t *testing.T) {
+		newDetailMockAPI(t)
+
+		for _, path := range []string{"/users/123456", "/users/1"} {
+			r := httptest.NewRequest(http.MethodGet, "http://localhost"+path, nil)
+			errW := &test.ErrorResponseWriter{}
+			assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+				Detail(errW, r)
+			})
+		}
+	})
+	t.Run("正常ケース：ETagが一致する場合は304", func(t *testing.T) {
+		newDetailMockAPI(t)
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "http://localhost/users/123456", nil)
+		r.Header.Set("If-None-Match", userETag(taro))
+		openapi.Check(t, "/users/{id}", Detail)(w, r)
+
+		assert.Equal(t, http.StatusNotModified, w.Code)
+		assert.Empty(t, w.Body.String())
+	})
+	t.Run("正常ケース：取得したETagで更新し、古いETagでは更新できない", func(t *testing.T) {
+		newDetailMockAPI(t)
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "http://localhost/users/123456", nil)
+		Detail(w, r)
+		etag := w.Header().Get("ETag")
+		if !assert.NotEmpty(t, etag) {
+			return
+		}
+
+		for _, want := range []int{http.StatusOK, http.StatusPreconditionFailed} {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPatch, "http://localhost/users/123456", strings.NewReader(`{"age":"26"}`))
+			r.Header.Set("Content-Type", "application/merge-patch+json")
+			r.Header.Set("If-Match", etag)
+			Patch(w, r)
+			assert.Equal(t, want, w.Code)
+		}
+	})
+	t.Run("異常ケース：条件付きリクエストに対応しない外部APIでもETagを判定する", func(t *testing.T) {
+		// ETagを返さず、If-Matchも判定しない外部API
+		var writes int
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet {
+				writes++
+			}
+			assert.Empty(t, r.Header.Get("If-Match"))
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"id":123456,"name":"dip 太郎","age":25}`))
+		}))
+		defer ts.Close()
+		t.Setenv("MOCK_API_URL", ts.URL)
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodDelete, "http://localhost/users/123456", nil)
+		r.Header.Set("If-Match", `"stale"`)
+		openapi.Check(t, "/users/{id}", Delete)(w, r)
+
+		assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+		assert.Zero(t, writes)
+
+		w = httptest.NewRecorder()
+		r = httptest.NewRequest(http.MethodGet, "http://localhost/users/123456", nil)
+		r.Header.Set("If-None-Match", userETag(taro))
+		openapi.Check(t, "/users/{id}", Detail)(w, r)
+		assert.Equal(t, http.StatusNotModified, w.Code)
+	})
+}
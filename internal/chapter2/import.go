@@ -0,0 +1,386 @@
+package chapter2
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/transform"
+
+	"github.com/dip-dev/go-tutorial/internal/helper/negotiation"
+	"github.com/dip-dev/go-tutorial/internal/helper/networking"
+)
+
+const (
+	// アップロードできるファイルサイズの上限
+	maxImportFileSize = 10 << 20
+	// 1回のインポートで登録できる行数の上限
+	maxImportRows = 10000
+	// mock-apiへまとめて登録する行数
+	importBatchSize = 100
+	// 文字コードの判定に使う先頭のバイト数
+	charsetDetectSize = 64 << 10
+	// アップロードするファイルのフォーム項目名
+	importFormField = "file"
+)
+
+// 文字コード
+const (
+	charsetUTF8     = "utf-8"
+	charsetShiftJIS = "shift_jis"
+)
+
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// エラーレポートのメディアタイプ
+var importReportMediaTypes = []string{"application/json", "text/csv"}
+
+// インポートできなかった行
+type ImportError struct {
+	// CSVの行番号（ヘッダーを含めて1始まり）
+	Line   int    `json:"line"`
+	Name   string `json:"name"`
+	Age    string `json:"age"`
+	Reason string `json:"reason"`
+}
+
+// インポートの結果
+type ImportResult struct {
+	// 判定した文字コード
+	Charset  string        `json:"charset"`
+	Imported int           `json:"imported"`
+	Failed   int           `json:"failed"`
+	Errors   []ImportError `json:"errors"`
+}
+
+// 登録待ちの行
+type importRow struct {
+	line   int
+	params map[string]string
+}
+
+// POST /users:import
+// multipart/form-dataでアップロードされたCSV（name,age）を読み込み、importBatchSize行ずつmock-apiへ登録する
+// 途中まで登録してから上限で失敗しないよう、一時ファイルに書き出しながら行数を数えてから登録を始める
+// Accept: text/csvの場合は、インポートできなかった行をCSVのエラーレポートとして返す
+func Import(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// レスポンス形式の決定
+	mediaType, ok := negotiation.Negotiate(r.Header.Get("Accept"), importReportMediaTypes...)
+	if !ok {
+		http.Error(w, "Not Acceptable: supported types are "+strings.Join(importReportMediaTypes, ", "), http.StatusNotAcceptable)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportFileSize)
+	file, err := importFile(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	// 行数を数えながら一時ファイルに書き出し、登録時は一時ファイルから読み直す
+	tmp, err := os.CreateTemp("", "users-import-*.csv")
+	if err != nil {
+		http.Error(w, "Failed to buffer file", http.StatusInternalServerError)
+		return
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	rows, err := countImportRows(io.TeeReader(file, tmp))
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		switch {
+		case errors.As(err, &maxErr):
+			http.Error(w, "Request Entity Too Large", http.StatusRequestEntityTooLarge)
+		case errors.Is(err, errImportRead):
+			http.Error(w, "Failed to read file", http.StatusBadRequest)
+		default:
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		}
+		return
+	}
+	if rows > maxImportRows {
+		http.Error(w, fmt.Sprintf("too many rows: at most %d rows can be imported at once", maxImportRows), http.StatusRequestEntityTooLarge)
+		return
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		http.Error(w, "Failed to read file", http.StatusInternalServerError)
+		return
+	}
+
+	body, charset, err := decodeCharset(tmp)
+	if err != nil {
+		http.Error(w, "Failed to read file", http.StatusInternalServerError)
+		return
+	}
+	cr := newImportReader(body)
+	header, columns, err := readImportHeader(cr)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Clientのインスタンス化
+	c, err := networking.NewClient(targetURL)
+	if err != nil {
+		http.Error(w, "Failed to create client", http.StatusInternalServerError)
+		return
+	}
+
+	// 検証済みの行がimportBatchSize行たまるごとに登録する
+	result := ImportResult{Charset: charset, Errors: []ImportError{}}
+	batch := make([]importRow, 0, importBatchSize)
+	err = readImportRows(cr, header, columns, func(row importRow) {
+		batch = append(batch, row)
+		if len(batch) == importBatchSize {
+			importBatch(r, c, batch, &result)
+			batch = batch[:0]
+		}
+	}, result.addError)
+	if err != nil {
+		// 1回目の読み込みで確認済みのため、一時ファイルの読み込みに失敗した場合のみ
+		http.Error(w, "Failed to read file", http.StatusInternalServerError)
+		return
+	}
+	importBatch(r, c, batch, &result)
+	// 登録時のエラーは登録単位ごとに追加されるため、行番号順に並べ直す
+	sort.SliceStable(result.Errors, func(i, j int) bool {
+		return result.Errors[i].Line < result.Errors[j].Line
+	})
+
+	if mediaType == "text/csv" {
+		writeImportReport(w, result, charset)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		http.Error(w, "Failed to encode body", http.StatusInternalServerError)
+	}
+}
+
+// ファイルの読み込みに失敗した
+var errImportRead = errors.New("failed to read file")
+
+// ファイルを最後まで読み込み、ヘッダーと空行を除いた行数を数える
+// ヘッダーの誤りと読み込みの失敗はエラーを返す
+func countImportRows(r io.Reader) (int, error) {
+	body, _, err := decodeCharset(r)
+	if err != nil {
+		return 0, importReadError(err)
+	}
+	cr := newImportReader(body)
+	header, columns, err := readImportHeader(cr)
+	if err != nil {
+		return 0, err
+	}
+	rows := 0
+	err = readImportRows(cr, header, columns, func(importRow) { rows++ }, func(ImportError) { rows++ })
+	return rows, err
+}
+
+func newImportReader(r io.Reader) *csv.Reader {
+	cr := csv.NewReader(r)
+	// 列数の誤りは行ごとのエラーとして扱う
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	return cr
+}
+
+// ヘッダー以降の行を1行ずつ検証し、登録できる行はemit、できない行はrejectに渡す
+// CSVの構文の誤りは行ごとのエラーとして扱い、読み込みの失敗のみエラーを返す
+func readImportRows(cr *csv.Reader, header []string, columns map[string]int, emit func(importRow), reject func(ImportError)) error {
+	for {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			reject(ImportError{Line: parseErr.StartLine, Reason: parseErr.Err.Error()})
+			continue
+		}
+		if err != nil {
+			return importReadError(err)
+		}
+		line, _ := cr.FieldPos(0)
+		// 空行は読み飛ばす
+		if len(record) == 1 && strings.TrimSpace(record[0]) == "" {
+			continue
+		}
+
+		params := map[string]string{}
+		for name, i := range columns {
+			if i < len(record) {
+				params[name] = strings.TrimSpace(record[i])
+			}
+		}
+		if len(record) != len(header) {
+			reject(ImportError{Line: line, Name: params["name"], Age: params["age"], Reason: fmt.Sprintf("expected %d fields but got %d", len(header), len(record))})
+			continue
+		}
+		// 1件ずつ登録する場合と同じ条件で検証する
+		if err := validateUserParams(params); err != nil {
+			reject(ImportError{Line: line, Name: params["name"], Age: params["age"], Reason: err.Error()})
+			continue
+		}
+		emit(importRow{line: line, params: params})
+	}
+}
+
+// サイズの上限を超えた場合はそのまま、それ以外はerrImportReadにまとめる
+func importReadError(err error) error {
+	var maxErr *http.MaxBytesError
+	if errors.As(err, &maxErr) {
+		return err
+	}
+	return fmt.Errorf("%w: %v", errImportRead, err)
+}
+
+func (res *ImportResult) addError(e ImportError) {
+	res.Failed++
+	res.Errors = append(res.Errors, e)
+}
+
+// multipart/form-dataからCSVファイルの項目を探す
+func importFile(r *http.Request) (io.ReadCloser, error) {
+	mr, err := r.MultipartReader()
+	if err != nil {
+		return nil, errors.New("Content-Type must be multipart/form-data")
+	}
+	for {
+		part, err := mr.NextPart()
+		if errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("%s is required", importFormField)
+		}
+		if err != nil {
+			return nil, errors.New("invalid multipart body")
+		}
+		if part.FormName() == importFormField {
+			return part, nil
+		}
+		part.Close()
+	}
+}
+
+// 先頭のバイト列からUTF-8かShift_JISかを判定し、UTF-8で読み込むReaderを返す
+// BOM付きのUTF-8とUTF-8として正しいバイト列はUTF-8、それ以外はShift_JISとみなす
+func decodeCharset(r io.Reader) (io.Reader, string, error) {
+	br := bufio.NewReaderSize(r, charsetDetectSize)
+	head, err := br.Peek(charsetDetectSize)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, bufio.ErrBufferFull) {
+		return nil, "", err
+	}
+	if bytes.HasPrefix(head, utf8BOM) {
+		_, _ = br.Discard(len(utf8BOM))
+		return br, charsetUTF8, nil
+	}
+	if validUTF8Prefix(head, errors.Is(err, io.EOF)) {
+		return br, charsetUTF8, nil
+	}
+	return transform.NewReader(br, japanese.ShiftJIS.NewDecoder()), charsetShiftJIS, nil
+}
+
+// UTF-8として正しいか
+// 途中までしか読んでいない場合は、末尾で途切れた文字を除いて判定する
+func validUTF8Prefix(b []byte, complete bool) bool {
+	if utf8.Valid(b) {
+		return true
+	}
+	if complete {
+		return false
+	}
+	for i := 1; i < utf8.UTFMax && i < len(b); i++ {
+		if !utf8.FullRune(b[len(b)-i:]) && utf8.Valid(b[:len(b)-i]) {
+			return true
+		}
+	}
+	return false
+}
+
+// ヘッダー行と列名ごとの位置を返す
+func readImportHeader(cr *csv.Reader) ([]string, map[string]int, error) {
+	header, err := cr.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, nil, errors.New("file is empty")
+	}
+	if err != nil {
+		return nil, nil, errors.New("invalid CSV header")
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if _, ok := columns[name]; ok {
+			return nil, nil, fmt.Errorf("duplicate column: %s", name)
+		}
+		columns[name] = i
+	}
+	for _, name := range []string{"name", "age"} {
+		if _, ok := columns[name]; !ok {
+			return nil, nil, fmt.Errorf("column %q is required", name)
+		}
+	}
+	return header, columns, nil
+}
+
+// まとめた行を並行してmock-apiへ登録する
+func importBatch(r *http.Request, c *networking.Client, batch []importRow, result *ImportResult) {
+	if len(batch) == 0 {
+		return
+	}
+	results := make([]BatchResult, len(batch))
+	indexes := make([]int, len(batch))
+	for i := range batch {
+		indexes[i] = i
+	}
+	forEachBounded(indexes, batchConcurrency, func(i int) {
+		results[i] = createBatchUser(r.Context(), c, i, batch[i].params)
+	})
+	for i, res := range results {
+		if res.Status == http.StatusCreated {
+			result.Imported++
+			continue
+		}
+		row := batch[i]
+		result.addError(ImportError{Line: row.line, Name: row.params["name"], Age: row.params["age"], Reason: res.Error})
+	}
+}
+
+// インポートできなかった行をCSVで返す
+// Excelで開けるよう、アップロードされたファイルと同じ文字コードで書き出す
+func writeImportReport(w http.ResponseWriter, result ImportResult, charset string) {
+	w.Header().Set("Content-Type", mime.FormatMediaType("text/csv", map[string]string{"charset": charset}))
+	w.Header().Set("Content-Disposition", `attachment; filename="import-errors.csv"`)
+
+	var out io.Writer = w
+	if charset == charsetShiftJIS {
+		tw := transform.NewWriter(w, encoding.ReplaceUnsupported(japanese.ShiftJIS.NewEncoder()))
+		defer tw.Close()
+		out = tw
+	}
+	cw := csv.NewWriter(out)
+	_ = cw.Write([]string{"line", "name", "age", "reason"})
+	for _, e := range result.Errors {
+		_ = cw.Write([]string{strconv.Itoa(e.Line), e.Name, e.Age, e.Reason})
+	}
+	cw.Flush()
+}
@@ -0,0 +1,304 @@
+package chapter2
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/transform"
+
+	"github.com/dip-dev/go-tutorial/internal/helper/openapi"
+	"github.com/dip-dev/go-tutorial/internal/helper/test"
+)
+
+// CSVファイルをmultipart/form-dataのボディにする
+func importBody(t *testing.T, field string, file []byte) (string, *bytes.Buffer) {
+	t.Helper()
+	body := &bytes.Buffer{}
+	mw := multipart.NewWriter(body)
+	assert.NoError(t, mw.WriteField("comment", "ユーザー一覧"))
+	fw, err := mw.CreateFormFile(field, "users.csv")
+	assert.NoError(t, err)
+	_, err = fw.Write(file)
+	assert.NoError(t, err)
+	assert.NoError(t, mw.Close())
+	return mw.FormDataContentType(), body
+}
+
+func postImport(t *testing.T, accept string, file []byte) *httptest.ResponseRecorder {
+	t.Helper()
+	contentType, body := importBody(t, importFormField, file)
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "http://localhost/users:import", body)
+	r.Header.Set("Content-Type", contentType)
+	if accept != "" {
+		r.Header.Set("Accept", accept)
+	}
+	openapi.Check(t, "/users:import", Import)(w, r)
+	return w
+}
+
+func shiftJIS(t *testing.T, s string) []byte {
+	t.Helper()
+	b, _, err := transform.Bytes(japanese.ShiftJIS.NewEncoder(), []byte(s))
+	assert.NoError(t, err)
+	return b
+}
+
+func TestImport(t *testing.T) {
+	success := map[string]struct {
+		file        []byte
+		wantCharset string
+		wantUsers   []string
+		wantErrors  []ImportError
+	}{
+		"正常ケース：UTF-8": {
+			file:        []byte("name,age\ndip 四郎,30\ndip 五郎,31\n"),
+			wantCharset: charsetUTF8,
+			wantUsers:   []string{"dip 四郎", "dip 五郎"},
+			wantErrors:  []ImportError{},
+		},
+		"正常ケース：BOM付きのUTF-8": {
+			file:        append(append([]byte(nil), utf8BOM...), "name,age\r\ndip 四郎,30\r\n"...),
+			wantCharset: charsetUTF8,
+			wantUsers:   []string{"dip 四郎"},
+			wantErrors:  []ImportError{},
+		},
+		"正常ケース：Shift_JIS": {
+			file:        shiftJIS(t, "name,age\ndip 四郎,30\nディップ 五郎,31\n"),
+			wantCharset: charsetShiftJIS,
+			wantUsers:   []string{"dip 四郎", "ディップ 五郎"},
+			wantErrors:  []ImportError{},
+		},
+		"正常ケース：列の順番と大文字小文字が異なる": {
+			file:        []byte("Age, Name\n30, dip 四郎\n"),
+			wantCharset: charsetUTF8,
+			wantUsers:   []string{"dip 四郎"},
+			wantErrors:  []ImportError{},
+		},
+		"正常ケース：不正な行のみ登録しない": {
+			file:        []byte("name,age\ndip 四郎,30\n,31\n\ndip 六郎,abc\ndip 七郎,32,extra\n\"dip \"八郎\",33\ndip 九郎,34\n"),
+			wantCharset: charsetUTF8,
+			wantUsers:   []string{"dip 四郎", "dip 九郎"},
+			wantErrors: []ImportError{
+				{Line: 3, Name: "", Age: "31", Reason: "name is required"},
+				{Line: 5, Name: "dip 六郎", Age: "abc", Reason: "age is not a number"},
+				{Line: 6, Name: "dip 七郎", Age: "32", Reason: "expected 2 fields but got 3"},
+				{Line: 7, Reason: `extraneous or missing " in quoted-field`},
+			},
+		},
+	}
+	for tn, tc := range success {
+		t.Run(tn, func(t *testing.T) {
+			m := newDetailMockAPI(t)
+
+			w := postImport(t, "", tc.file)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			var res ImportResult
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+			assert.Equal(t, tc.wantCharset, res.Charset)
+			assert.Equal(t, len(tc.wantUsers), res.Imported)
+			assert.Equal(t, len(tc.wantErrors), res.Failed)
+			assert.Equal(t, tc.wantErrors, res.Errors)
+
+			var names []string
+			for _, u := range m.Users()[len(test.DefaultMockData().Users):] {
+				names = append(names, u.Name)
+			}
+			assert.ElementsMatch(t, tc.wantUsers, names)
+		})
+	}
+
+	fail := map[string]struct {
+		accept     string
+		file       []byte
+		wantStatus int
+	}{
+		"異常ケース：空のファイル": {
+			file:       []byte{},
+			wantStatus: http.StatusBadRequest,
+		},
+		"異常ケース：必須の列がない": {
+			file:       []byte("name,email\ndip 四郎,dip@example.com\n"),
+			wantStatus: http.StatusBadRequest,
+		},
+		"異常ケース：列名が重複している": {
+			file:       []byte("name,age,name\ndip 四郎,30,dip\n"),
+			wantStatus: http.StatusBadRequest,
+		},
+		"異常ケース：対応していないAccept": {
+			accept:     "application/xml",
+			file:       []byte("name,age\ndip 四郎,30\n"),
+			wantStatus: http.StatusNotAcceptable,
+		},
+		"異常ケース：行数が上限を超える": {
+			file:       []byte("name,age\n" + strings.Repeat("dip,20\n", maxImportRows+1)),
+			wantStatus: http.StatusRequestEntityTooLarge,
+		},
+		"異常ケース：登録単位を超えてからファイルサイズが上限を超える": {
+			file:       []byte("name,age\n" + strings.Repeat("dip,20\n", importBatchSize+1) + strings.Repeat("a", maxImportFileSize) + ",20\n"),
+			wantStatus: http.StatusRequestEntityTooLarge,
+		},
+	}
+	for tn, tc := range fail {
+		t.Run(tn, func(t *testing.T) {
+			m := newDetailMockAPI(t)
+
+			w := postImport(t, tc.accept, tc.file)
+
+			assert.Equal(t, tc.wantStatus, w.Code)
+			// 上限を超えた場合も含め、失敗した場合は1件も登録しない
+			assert.Equal(t, test.DefaultMockData().Users, m.Users())
+		})
+	}
+
+	t.Run("正常ケース：100行ずつ登録する", func(t *testing.T) {
+		m := newDetailMockAPI(t)
+		var sb strings.Builder
+		sb.WriteString("name,age\n")
+		for i := 0; i < importBatchSize*2+1; i++ {
+			fmt.Fprintf(&sb, "dip %d,20\n", i)
+		}
+
+		w := postImport(t, "", []byte(sb.String()))
+
+		var res ImportResult
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+		assert.Equal(t, importBatchSize*2+1, res.Imported)
+		assert.Len(t, m.Users(), len(test.DefaultMockData().Users)+importBatchSize*2+1)
+	})
+	t.Run("正常ケース：外部APIの失敗は行ごとに返す", func(t *testing.T) {
+		newFailingBatchMockAPI(t, "dip 五郎")
+
+		w := postImport(t, "", []byte("name,age\ndip 四郎,30\ndip 五郎,31\n,32\n"))
+
+		var res ImportResult
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+		assert.Equal(t, 1, res.Imported)
+		if assert.Len(t, res.Errors, 2) {
+			// 行番号順に並ぶ
+			assert.Equal(t, 3, res.Errors[0].Line)
+			assert.Contains(t, res.Errors[0].Reason, "mock-api returned 500")
+			assert.Equal(t, 4, res.Errors[1].Line)
+		}
+	})
+	t.Run("異常ケース：multipart/form-dataではない", func(t *testing.T) {
+		newDetailMockAPI(t)
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "http://localhost/users:import", strings.NewReader("name,age\n"))
+		r.Header.Set("Content-Type", "text/csv")
+		Import(w, r)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+	t.Run("異常ケース：ファイルの項目がない", func(t *testing.T) {
+		newDetailMockAPI(t)
+		contentType, body := importBody(t, "upload", []byte("name,age\n"))
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "http://localhost/users:import", body)
+		r.Header.Set("Content-Type", contentType)
+		openapi.Check(t, "/users:import", Import)(w, r)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+	t.Run("異常ケース：メソッドが一致しない", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "http://localhost/users:import", nil)
+		Import(w, r)
+		assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+	})
+}
+
+func TestReadImportRows(t *testing.T) {
+	t.Run("正常ケース：読み込んだ行から順に渡す", func(t *testing.T) {
+		// 途中で読み込みに失敗しても、それまでの行は渡し終えている
+		r := io.MultiReader(strings.NewReader("name,age\ndip 四郎,30\n,31\n\ndip 六郎,32\n"), iotest.ErrReader(errors.New("broken")))
+		cr := newImportReader(r)
+		header, columns, err := readImportHeader(cr)
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		var lines, rejected []int
+		err = readImportRows(cr, header, columns, func(row importRow) {
+			lines = append(lines, row.line)
+		}, func(e ImportError) {
+			rejected = append(rejected, e.Line)
+		})
+
+		assert.ErrorIs(t, err, errImportRead)
+		assert.Equal(t, []int{2, 5}, lines)
+		assert.Equal(t, []int{3}, rejected)
+	})
+}
+
+func TestImportReport(t *testing.T) {
+	success := map[string]struct {
+		file        []byte
+		wantCharset string
+		decode      func(t *testing.T, b []byte) []byte
+	}{
+		"正常ケース：UTF-8": {
+			file:        []byte("name,age\nダメな 四郎,abc\ndip 五郎,31\n"),
+			wantCharset: charsetUTF8,
+			decode:      func(t *testing.T, b []byte) []byte { return b },
+		},
+		"正常ケース：Shift_JISで返す": {
+			file:        shiftJIS(t, "name,age\nダメな 四郎,abc\ndip 五郎,31\n"),
+			wantCharset: charsetShiftJIS,
+			decode: func(t *testing.T, b []byte) []byte {
+				d, err := io.ReadAll(transform.NewReader(bytes.NewReader(b), japanese.ShiftJIS.NewDecoder()))
+				assert.NoError(t, err)
+				return d
+			},
+		},
+	}
+	for tn, tc := range success {
+		t.Run(tn, func(t *testing.T) {
+			newDetailMockAPI(t)
+
+			w := postImport(t, "text/csv", tc.file)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, "text/csv; charset="+tc.wantCharset, w.Header().Get("Content-Type"))
+			assert.Equal(t, `attachment; filename="import-errors.csv"`, w.Header().Get("Content-Disposition"))
+			records, err := csv.NewReader(bytes.NewReader(tc.decode(t, w.Body.Bytes()))).ReadAll()
+			assert.NoError(t, err)
+			assert.Equal(t, [][]string{
+				{"line", "name", "age", "reason"},
+				{"2", "ダメな 四郎", "abc", "age is not a number"},
+			}, records)
+		})
+	}
+}
+
+func TestValidUTF8Prefix(t *testing.T) {
+	// 「あ」はUTF-8で3バイト
+	a := []byte("あ")
+	success := map[string]struct {
+		b        []byte
+		complete bool
+		want     bool
+	}{
+		"正常ケース：UTF-8":            {b: []byte("name,あ"), complete: true, want: true},
+		"正常ケース：末尾で文字が途切れている":     {b: append([]byte("name,"), a[:2]...), complete: false, want: true},
+		"正常ケース：空":                {b: []byte{}, complete: true, want: true},
+		"異常ケース：最後まで読んで文字が途切れている": {b: append([]byte("name,"), a[:2]...), complete: true, want: false},
+		"異常ケース：Shift_JIS":        {b: shiftJIS(t, "名前,年齢"), complete: false, want: false},
+	}
+	for tn, tc := range success {
+		t.Run(tn, func(t *testing.T) {
+			assert.Equal(t, tc.want, validUTF8Prefix(tc.b, tc.complete))
+		})
+	}
+}
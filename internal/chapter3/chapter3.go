@@ -2,19 +2,22 @@ package chapter3
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"net/http"
-	"strconv"
+	"strings"
 
+	"github.com/dip-dev/go-tutorial/internal/helper/negotiation"
 	"github.com/dip-dev/go-tutorial/internal/helper/networking"
 )
 
 type User struct {
-	ID   int
-	Name string
-	Age  int
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+	Age  int    `json:"age"`
 }
 
+// 案件情報
+// JSONの形式は外部API・レスポンスごとにwire.goで定義する
 type Entry struct {
 	Name   string
 	UserID int
@@ -23,77 +26,145 @@ type Entry struct {
 
 const targetURL = "http://mock-api"
 
+// mock-apiへのリクエストのオプション
+// 圧縮されたレスポンスを受け付け、展開後の大きさを制限する
+var upstreamOptions = []networking.Option{
+	networking.WithDecompression(networking.DefaultMaxDecompressedSize),
+}
+
+// 案件情報一覧を既定の形式で返却する
 func Get(w http.ResponseWriter, r *http.Request) {
+	get(w, r, DefaultEntryFormat())
+}
+
+// 案件情報一覧を指定した形式で返却するハンドラ
+// APIのバージョンごとに形式を固定する場合に使う
+func GetWithFormat(format EntryFormat) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		get(w, r, format)
+	}
+}
+
+func get(w http.ResponseWriter, r *http.Request, format EntryFormat) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
 		return
 	}
-	ctx, cancel := context.WithCancel(context.Background())
+	// クライアントが切断した場合は外部APIへのリクエストも中断する
+	ctx, cancel := context.WithCancel(r.Context())
 	defer cancel()
 
-	// クエリパラメータの設定
-	var err error
-	query := r.URL.Query()
-	var names []string
-	var ok bool
-	names, ok = query["name"]
+	// レスポンス形式の決定
+	mediaType, ok := negotiation.Negotiate(r.Header.Get("Accept"), entryMediaTypes...)
 	if !ok {
-		http.Error(w, "Invalid parameters", http.StatusBadRequest)
+		http.Error(w, "Not Acceptable: supported types are "+strings.Join(entryMediaTypes, ", "), http.StatusNotAcceptable)
 		return
 	}
-	params := map[string][]string{
-		"name": names,
+
+	// クエリパラメータの検証
+	q, err := ParseEntryQuery(r.URL.Query(), format)
+	if err != nil {
+		var verr *ValidationError
+		if errors.As(err, &verr) {
+			writeValidationError(w, http.StatusBadRequest, verr)
+			return
+		}
+		http.Error(w, "Invalid parameters", http.StatusBadRequest)
+		return
 	}
 
-	// データ受信用のチャンネル
-	ch1 := make(chan []int)
 	// エラー受信用のチャンネル
 	errch := make(chan error)
 
-	// ユーザー情報を取得する
-	go GetUserID(ctx, ch1, errch, params)
+	// ユーザーIDの特定
+	ids := q.UserIDs
+	if len(q.Names) > 0 {
+		params := map[string][]string{
+			"name": q.Names,
+		}
 
-	var ids []int
-	select {
-	case ids = <-ch1:
-		// ユーザーが見つからない場合はエラーを返す
-		if len(ids) == 0 {
-			http.Error(w, "User is not found", http.StatusNotFound)
+		// データ受信用のチャンネル
+		ch1 := make(chan []int)
+
+		// ユーザー情報を取得する
+		go GetUserID(ctx, ch1, errch, params)
+
+		var found []int
+		select {
+		case found = <-ch1:
+			// ユーザーが見つからない場合はエラーを返す
+			if len(found) == 0 {
+				http.Error(w, "User is not found", http.StatusNotFound)
+				return
+			}
+		case err = <-errch:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
-	case err = <-errch:
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+		if len(q.UserIDs) > 0 {
+			found = intersectIDs(found, q.UserIDs)
+		}
+		ids = found
 	}
 
-	// クエリパラメータ用のmapをリセット
-	delete(params, "name")
-	for _, id := range ids {
-		params["userID"] = append(params["userID"], strconv.Itoa(id))
-	}
+	stream := newEntryStream(w, mediaType, q)
 
-	// データ受信用のチャンネル
-	ch2 := make(chan []Entry)
+	// 条件に一致するユーザーがいない場合は外部APIを呼ばない
+	if len(ids) == 0 {
+		if err := stream.close(); err != nil {
+			stream.abort(errEncoding)
+		}
+		return
+	}
 
-	// データを取得する
-	go GetEntries(ctx, ch2, errch, params)
+	// ユーザーIDと給与による絞り込みは外部APIで行う
+	params := q.UpstreamParams(ids)
 
-	var entries []Entry
-	select {
-	case entries = <-ch2:
-	case err = <-errch:
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+	// データ受信用のチャンネル
+	ch2 := make(chan Entry)
+
+	// データを1件ずつ取得する
+	go StreamEntries(ctx, ch2, errch, params)
+
+	// ソートする場合は全件を受信してから書き出す
+	var sorted []Entry
+	for done := false; !done; {
+		select {
+		case e, ok := <-ch2:
+			if !ok {
+				done = true
+				break
+			}
+			// 外部APIで絞り込めない条件を適用する
+			if !q.Match(e) {
+				break
+			}
+			if len(q.Sort) > 0 {
+				sorted = append(sorted, e)
+				break
+			}
+			if err = stream.write(e); err != nil {
+				stream.abort(errEncoding)
+				return
+			}
+		case err = <-errch:
+			stream.abort(err)
+			return
+		}
 	}
 
 	// 値を返却する
-	data := map[string][]Entry{"entries": entries}
-	if err := json.NewEncoder(w).Encode(data); err != nil {
-		http.Error(w, "Encoding json is failed", http.StatusInternalServerError)
+	SortEntries(sorted, q.Sort)
+	for _, e := range sorted {
+		if err = stream.write(e); err != nil {
+			stream.abort(errEncoding)
+			return
+		}
+	}
+	if err = stream.close(); err != nil {
+		stream.abort(errEncoding)
 		return
 	}
-	w.Header().Set("Content-Type", "application/json")
-
 }
 
 func GetUserID(ctx context.Context, ch chan []int, errch chan error, params map[string][]string) {
@@ -103,7 +174,7 @@ func GetUserID(ctx context.Context, ch chan []int, errch chan error, params map[
 	var err error
 	// Clientのインスタンス化
 	var c *networking.Client
-	c, err = networking.NewClient(targetURL)
+	c, err = networking.NewClient(targetURL, upstreamOptions...)
 	if err != nil {
 		errch <- err
 		return
@@ -118,7 +189,7 @@ func GetUserID(ctx context.Context, ch chan []int, errch chan error, params map[
 	defer res.Body.Close()
 
 	got := []User{}
-	if err = json.NewDecoder(res.Body).Decode(&got); err != nil {
+	if err = networking.DecodeResponse(res, &got); err != nil {
 		errch <- err
 		return
 	}
@@ -130,34 +201,3 @@ func GetUserID(ctx context.Context, ch chan []int, errch chan error, params map[
 
 	ch <- ids
 }
-
-func GetEntries(ctx context.Context, ch chan []Entry, errch chan error, params map[string][]string) {
-	// ヘッダーの設定
-	header := map[string][]string{"key": {"dip"}}
-
-	var err error
-	// Clientのインスタンス化
-	var c *networking.Client
-	c, err = networking.NewClient(targetURL)
-	if err != nil {
-		errch <- err
-		return
-	}
-
-	// 外部APIへリクエスト
-	var res *http.Response
-	res, err = c.NewRequestAndDo(ctx, http.MethodGet, c.BaseURL.JoinPath("/entries"), header, params, nil)
-	if err != nil {
-		errch <- err
-		return
-	}
-	defer res.Body.Close()
-
-	var entries []Entry
-	if err = json.NewDecoder(res.Body).Decode(&entries); err != nil {
-		errch <- err
-		return
-	}
-
-	ch <- entries
-}
\ No newline at end of file
@@ -1,17 +1,22 @@
 package chapter3
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
 	"encoding/json"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"os"
-	"strconv"
+	"strings"
 	"testing"
 
+	"github.com/andybalholm/brotli"
 	"github.com/stretchr/testify/assert"
 
+	"github.com/dip-dev/go-tutorial/internal/helper/openapi"
 	"github.com/dip-dev/go-tutorial/internal/helper/test"
 )
 
@@ -21,15 +26,16 @@ func TestMain(m *testing.M) {
 
 var (
 	// 外部APIのモックサーバー用
+	mockAPI = test.NewMockAPI(mockData())
 	// ユーザー情報取得API（正常）
 	successMockGetUserHandler = test.Handler{
 		Path:    "/users",
-		Handler: MockGetUser,
+		Handler: mockAPI.ServeHTTP,
 	}
 	// 案件情報取得API（正常）
 	successMockGetEntriesHandler = test.Handler{
 		Path:    "/entries",
-		Handler: MockGetEntry,
+		Handler: mockAPI.ServeHTTP,
 	}
 	// 正常時のハンドラー
 	successHandlers = []test.Handler{
@@ -39,12 +45,12 @@ var (
 	// ユーザー情報取得API（異常）
 	failMockGetUserHandler = test.Handler{
 		Path:    "/users",
-		Handler: MakeRedirectHandler("/users"),
+		Handler: test.RedirectHandler("/users"),
 	}
 	// ユーザー情報取得API（異常）
 	failMockGetEntryHandler = test.Handler{
 		Path:    "/entries",
-		Handler: MakeRedirectHandler("/entries"),
+		Handler: test.RedirectHandler("/entries"),
 	}
 	// ユーザー情報取得APIのみ異常発生
 	getUsersFailHandlers = []test.Handler{
@@ -60,19 +66,22 @@ var (
 		Path:    "/users",
 		Handler: MockErrorResponse,
 	}
-	invalidResponseGetEntries = test.Handler{
-		Path:    "/entries",
-		Handler: MockErrorResponse,
-	}
 )
 
+// 給与での絞り込みを確認するため、初期データに案件を1件追加する
+func mockData() test.MockData {
+	data := test.DefaultMockData()
+	data.Entries = append(data.Entries, test.MockEntry{Name: "案件情報3", UserID: 345678, Salary: 500000})
+	return data
+}
+
 func TestGet(t *testing.T) {
 	// レスポンスに含まれるキーの名前
 	keyString := "entries"
 
 	success := map[string]struct {
 		params     map[string][]string
-		response   []Entry
+		response   []EntryV2
 		handlers   []test.Handler
 		wantStatus int
 	}{
@@ -80,7 +89,7 @@ func TestGet(t *testing.T) {
 			params: map[string][]string{
 				"name": {"dip 太郎"},
 			},
-			response: []Entry{
+			response: []EntryV2{
 				{
 					Name:   "案件情報1",
 					UserID: 123456,
@@ -94,16 +103,60 @@ func TestGet(t *testing.T) {
 			params: map[string][]string{
 				"name": {"dip 次郎"},
 			},
-			response: []Entry{
+			response: []EntryV2{
+				{
+					Name:   "案件情報2",
+					UserID: 234567,
+					Salary: 123456,
+				},
+			},
+			handlers:   successHandlers,
+			wantStatus: http.StatusOK,
+		},
+		"正常ケース：ユーザーIDで絞り込み": {
+			params: map[string][]string{
+				"user_id": {"234567", "345678"},
+			},
+			response: []EntryV2{
 				{
 					Name:   "案件情報2",
 					UserID: 234567,
 					Salary: 123456,
 				},
+				{
+					Name:   "案件情報3",
+					UserID: 345678,
+					Salary: 500000,
+				},
 			},
 			handlers:   successHandlers,
 			wantStatus: http.StatusOK,
 		},
+		"正常ケース：給与で絞り込み": {
+			params: map[string][]string{
+				"user_id":    {"123456", "345678"},
+				"min_salary": {"200000"},
+				"max_salary": {"600000"},
+			},
+			response: []EntryV2{
+				{
+					Name:   "案件情報3",
+					UserID: 345678,
+					Salary: 500000,
+				},
+			},
+			handlers:   successHandlers,
+			wantStatus: http.StatusOK,
+		},
+		"正常ケース：名前とユーザーIDが一致しない": {
+			params: map[string][]string{
+				"name":    {"dip 太郎"},
+				"user_id": {"234567"},
+			},
+			response:   []EntryV2{},
+			handlers:   successHandlers,
+			wantStatus: http.StatusOK,
+		},
 	}
 	fail := map[string]struct {
 		method     string
@@ -111,6 +164,8 @@ func TestGet(t *testing.T) {
 		resWriter  http.ResponseWriter
 		handlers   []test.Handler
 		wantStatus int
+		// 書き出し開始後のエラーは接続を切断する
+		wantAbort bool
 	}{
 		"異常ケース：Getメソッドではない": {
 			method: http.MethodPost,
@@ -128,6 +183,14 @@ func TestGet(t *testing.T) {
 			handlers:   successHandlers,
 			wantStatus: http.StatusNotFound,
 		},
+		"異常ケース：名前が空": {
+			method: http.MethodGet,
+			params: map[string][]string{
+				"name": {""},
+			},
+			handlers:   successHandlers,
+			wantStatus: http.StatusNotFound,
+		},
 		"異常ケース：パラメータにnameが無い": {
 			method: http.MethodGet,
 			params: map[string][]string{
@@ -136,14 +199,50 @@ func TestGet(t *testing.T) {
 			handlers:   successHandlers,
 			wantStatus: http.StatusBadRequest,
 		},
-		"異常ケース：JSONエンコード失敗": {
+		"異常ケース：給与が数値ではない": {
+			method: http.MethodGet,
+			params: map[string][]string{
+				"name":       {"dip 太郎"},
+				"min_salary": {"abc"},
+			},
+			handlers:   successHandlers,
+			wantStatus: http.StatusBadRequest,
+		},
+		"異常ケース：給与の範囲が不正": {
+			method: http.MethodGet,
+			params: map[string][]string{
+				"name":       {"dip 太郎"},
+				"min_salary": {"300000"},
+				"max_salary": {"100000"},
+			},
+			handlers:   successHandlers,
+			wantStatus: http.StatusBadRequest,
+		},
+		"異常ケース：ユーザーIDが不正": {
+			method: http.MethodGet,
+			params: map[string][]string{
+				"user_id": {"-1"},
+			},
+			handlers:   successHandlers,
+			wantStatus: http.StatusBadRequest,
+		},
+		"異常ケース：ソートキーが不正": {
 			method: http.MethodGet,
 			params: map[string][]string{
 				"name": {"dip 太郎"},
+				"sort": {"age"},
 			},
-			resWriter:  &test.ErrorResponseWriter{},
 			handlers:   successHandlers,
-			wantStatus: http.StatusInternalServerError,
+			wantStatus: http.StatusBadRequest,
+		},
+		"異常ケース：JSONエンコード失敗": {
+			method: http.MethodGet,
+			params: map[string][]string{
+				"name": {"dip 太郎"},
+			},
+			resWriter: &test.ErrorResponseWriter{},
+			handlers:  successHandlers,
+			wantAbort: true,
 		},
 		"異常ケース：ユーザー情報取得時にエラー発生": {
 			method: http.MethodGet,
@@ -186,8 +285,8 @@ func TestGet(t *testing.T) {
 
 			w := httptest.NewRecorder()
 			r := httptest.NewRequest(http.MethodGet, "http://localhost/?"+param.Encode(), nil)
-			Get(w, r)
-			got := map[string][]Entry{}
+			openapi.Check(t, "/v2/entries", GetWithFormat(EntryFormatV2))(w, r)
+			got := map[string][]EntryV2{}
 			t.Logf(w.Body.String())
 			if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
 				t.Errorf("error: %#v, res: %#v", err, got)
@@ -218,152 +317,310 @@ func TestGet(t *testing.T) {
 
 			r := httptest.NewRequest(tc.method, "http://localhost/?"+param.Encode(), nil)
 
+			if tc.wantAbort {
+				assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+					Get(tc.resWriter, r)
+				})
+				return
+			}
 			if tc.resWriter == nil {
 				w := httptest.NewRecorder()
-				Get(w, r)
+				openapi.Check(t, "/v2/entries", GetWithFormat(EntryFormatV2))(w, r)
 				assert.Equal(t, tc.wantStatus, w.Code)
 			} else {
 				errW := &test.ErrorResponseWriter{}
-				Get(errW, r)
+				openapi.Check(t, "/v2/entries", GetWithFormat(EntryFormatV2))(errW, r)
 				assert.Equal(t, tc.wantStatus, errW.Code())
 			}
 		})
 	}
 }
 
-func TestGetUserID(t *testing.T) {
+func TestGetWithQuery(t *testing.T) {
+	// 外部APIのモック
+	ts := httptest.NewServer(test.Route(successHandlers...))
+	defer ts.Close()
+
+	// 環境変数を一時的に変更
+	oldURL := os.Getenv("MOCK_API_URL")
+	os.Setenv("MOCK_API_URL", ts.URL)
+	defer os.Setenv("MOCK_API_URL", oldURL)
+
+	t.Run("正常ケース：複数キーでソート", func(t *testing.T) {
+		param := url.Values{
+			"user_id": {"123456", "234567", "345678"},
+			"sort":    {"-salary,-name"},
+		}
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "http://localhost/?"+param.Encode(), nil)
+		openapi.Check(t, "/v2/entries", GetWithFormat(EntryFormatV2))(w, r)
+
+		got := map[string][]EntryV2{}
+		if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+			t.Errorf("error: %#v, res: %#v", err, got)
+		}
+		assert.Equal(t, http.StatusOK, w.Code)
+		names := []string{}
+		for _, e := range got["entries"] {
+			names = append(names, e.Name)
+		}
+		assert.Equal(t, []string{"案件情報3", "案件情報2", "案件情報1"}, names)
+	})
+	t.Run("正常ケース：項目を指定", func(t *testing.T) {
+		param := url.Values{
+			"user_id": {"123456"},
+			"fields":  {"name,salary"},
+		}
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "http://localhost/?"+param.Encode(), nil)
+		openapi.Check(t, "/v2/entries", GetWithFormat(EntryFormatV2))(w, r)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"entries":[{"name":"案件情報1","salary":123456}]}`, w.Body.String())
+	})
+	t.Run("異常ケース：パラメータ単位のエラーを返す", func(t *testing.T) {
+		param := url.Values{
+			"min_salary": {"abc"},
+			"sort":       {"age"},
+			"fields":     {"age"},
+		}
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "http://localhost/?"+param.Encode(), nil)
+		openapi.Check(t, "/v2/entries", GetWithFormat(EntryFormatV2))(w, r)
+
+		got := ValidationError{}
+		if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+			t.Errorf("error: %#v, res: %#v", err, got)
+		}
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		fields := []string{}
+		for _, fe := range got.Errors {
+			fields = append(fields, fe.Field)
+		}
+		assert.ElementsMatch(t, []string{"name", "min_salary", "sort", "fields"}, fields)
+	})
+}
+
+func TestGetContentNegotiation(t *testing.T) {
+	// 外部APIのモック
+	ts := httptest.NewServer(test.Route(successHandlers...))
+	defer ts.Close()
+
+	// 環境変数を一時的に変更
+	oldURL := os.Getenv("MOCK_API_URL")
+	os.Setenv("MOCK_API_URL", ts.URL)
+	defer os.Setenv("MOCK_API_URL", oldURL)
+
 	success := map[string]struct {
-		params   map[string][]string
-		handlers []test.Handler
-		response []int
+		accept          string
+		query           string
+		wantContentType string
+		wantBody        string
 	}{
-		"正常ケース：データあり": {
-			params: map[string][]string{
-				"name": {"dip 太郎"},
-			},
-			handlers: []test.Handler{successMockGetUserHandler},
-			response: []int{123456},
+		"正常ケース：Acceptヘッダーなし": {
+			query:           "user_id=123456",
+			wantContentType: "application/json",
+			wantBody:        `{"entries":[{"name":"案件情報1","user_id":123456,"salary":123456}]}` + "\n",
 		},
-		"正常ケース：データなし": {
-			params: map[string][]string{
-				"name": {"dip 三郎"},
-			},
-			handlers: []test.Handler{successMockGetUserHandler},
-			response: []int{},
-		},
-	}
-	fail := map[string]struct {
-		params    map[string][]string
-		targetURL string
-		handlers  []test.Handler
-	}{
-		"異常ケース：baseURLが不正": {
-			params: map[string][]string{
-				"name": {"dip 太郎"},
-			},
-			targetURL: ":\\test",
-			handlers:  []test.Handler{successMockGetUserHandler},
+		"正常ケース：CSV": {
+			accept:          "text/csv",
+			query:           "user_id=123456&user_id=234567&sort=user_id",
+			wantContentType: "text/csv; charset=utf-8",
+			wantBody:        "\ufeffname,user_id,salary\n案件情報1,123456,123456\n案件情報2,234567,123456\n",
 		},
-		"異常ケース：外部APIリクエストに失敗": {
-			params: map[string][]string{
-				"name": {"dip 太郎"},
-			},
-			handlers: getUsersFailHandlers,
+		"正常ケース：CSV（項目を指定）": {
+			accept:          "text/csv",
+			query:           "user_id=123456&fields=salary,name",
+			wantContentType: "text/csv; charset=utf-8",
+			wantBody:        "\ufeffsalary,name\n123456,案件情報1\n",
 		},
-		"異常ケース：Jsonのデコードに失敗": {
-			params: map[string][]string{
-				"name": {"dip 太郎"},
-			},
-			handlers: []test.Handler{invalidResponseGetUser},
+		"正常ケース：NDJSON": {
+			accept:          "application/x-ndjson",
+			query:           "user_id=123456&user_id=234567&sort=user_id",
+			wantContentType: "application/x-ndjson",
+			wantBody:        `{"name":"案件情報1","user_id":123456,"salary":123456}` + "\n" + `{"name":"案件情報2","user_id":234567,"salary":123456}` + "\n",
 		},
 	}
+
 	for tn, tc := range success {
 		t.Run(tn, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "http://localhost/?"+tc.query, nil)
+			r.Header.Set("Accept", tc.accept)
+			openapi.Check(t, "/v2/entries", GetWithFormat(EntryFormatV2))(w, r)
 
-			// 外部APIのモック
-			ts := httptest.NewServer(test.Route(tc.handlers...))
-			defer ts.Close()
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, tc.wantContentType, w.Header().Get("Content-Type"))
+			assert.Equal(t, tc.wantBody, w.Body.String())
+		})
+	}
+	t.Run("異常ケース：対応していない形式", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "http://localhost/?user_id=123456", nil)
+		r.Header.Set("Accept", "application/xml")
+		openapi.Check(t, "/v2/entries", GetWithFormat(EntryFormatV2))(w, r)
+
+		assert.Equal(t, http.StatusNotAcceptable, w.Code)
+	})
+}
 
-			// 環境変数を一時的に変更
-			oldURL := os.Getenv("MOCK_API_URL")
-			os.Setenv("MOCK_API_URL", ts.URL)
-			defer os.Setenv("MOCK_API_URL", oldURL)
+// 外部APIが対応する条件は外部APIへ渡す
+func TestGetUpstreamFilters(t *testing.T) {
+	var got url.Values
+	ts := httptest.NewServer(test.Route(successMockGetUserHandler, test.Handler{
+		Path: "/entries",
+		Handler: func(w http.ResponseWriter, r *http.Request) {
+			got = r.URL.Query()
+			mockAPI.ServeHTTP(w, r)
+		},
+	}))
+	defer ts.Close()
+	t.Setenv("MOCK_API_URL", ts.URL)
 
-			ctx, cancel := context.WithCancel(context.Background())
-			defer cancel()
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "http://localhost/?user_id=345678&min_salary=200000&max_salary=600000", nil)
+	openapi.Check(t, "/v2/entries", GetWithFormat(EntryFormatV2))(w, r)
 
-			ch := make(chan []int)
-			errch := make(chan error)
-			go GetUserID(ctx, ch, errch, tc.params)
-			select {
-			case ids := <-ch:
-				assert.ElementsMatch(t, ids, tc.response)
-			case err := <-errch:
-				t.Errorf("Error is occured : %v", err)
-			}
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, url.Values{"userID": {"345678"}, "minSalary": {"200000"}, "maxSalary": {"600000"}}, got)
+	assert.Equal(t, `{"entries":[{"name":"案件情報3","user_id":345678,"salary":500000}]}`+"\n", w.Body.String())
+}
 
-		})
+// バージョンを指定しない場合は移行前の形式（v1）で返す
+func TestGetDefaultFormat(t *testing.T) {
+	// 外部APIのモック
+	ts := httptest.NewServer(test.Route(successHandlers...))
+	defer ts.Close()
+
+	// 環境変数を一時的に変更
+	t.Setenv("MOCK_API_URL", ts.URL)
+
+	success := map[string]struct {
+		accept   string
+		query    string
+		wantBody string
+	}{
+		"正常ケース：JSON": {
+			query:    "user_id=123456",
+			wantBody: `{"entries":[{"Name":"案件情報1","UserID":123456,"Salary":123456}]}` + "\n",
+		},
+		"正常ケース：項目を指定": {
+			query:    "user_id=123456&fields=user_id,Name",
+			wantBody: `{"entries":[{"UserID":123456,"Name":"案件情報1"}]}` + "\n",
+		},
+		"正常ケース：CSV": {
+			accept:   "text/csv",
+			query:    "user_id=123456",
+			wantBody: "\ufeffName,UserID,Salary\n案件情報1,123456,123456\n",
+		},
+		"正常ケース：v1のキーでソート": {
+			query:    "user_id=123456&user_id=234567&sort=-UserID&fields=UserID",
+			wantBody: `{"entries":[{"UserID":234567},{"UserID":123456}]}` + "\n",
+		},
 	}
-	for tn, tc := range fail {
+	for tn, tc := range success {
 		t.Run(tn, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "http://localhost/?"+tc.query, nil)
+			r.Header.Set("Accept", tc.accept)
+			openapi.Check(t, "/entries", Get)(w, r)
 
-			// 外部APIのモック
-			ts := httptest.NewServer(test.Route(tc.handlers...))
-			defer ts.Close()
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, tc.wantBody, w.Body.String())
+		})
+	}
 
-			// 環境変数を一時的に変更
-			oldURL := os.Getenv("MOCK_API_URL")
-			var baseURL string
-			if tc.targetURL == "" {
-				baseURL = ts.URL
-			} else {
-				baseURL = tc.targetURL
-			}
-			os.Setenv("MOCK_API_URL", baseURL)
-			defer os.Setenv("MOCK_API_URL", oldURL)
+	t.Run("正常ケース：互換フラグを無効にするとv2の形式で返す", func(t *testing.T) {
+		t.Setenv(legacyEntryFormatEnv, "false")
 
-			ctx, cancel := context.WithCancel(context.Background())
-			defer cancel()
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "http://localhost/?user_id=123456&sort=-user_id", nil)
+		openapi.Check(t, "/v2/entries", Get)(w, r)
 
-			ch := make(chan []int)
-			errch := make(chan error)
-			go GetUserID(ctx, ch, errch, tc.params)
-			select {
-			case ids := <-ch:
-				t.Errorf("Return ids : %v", ids)
-			case err := <-errch:
-				assert.Error(t, err)
-			}
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, `{"entries":[{"name":"案件情報1","user_id":123456,"salary":123456}]}`+"\n", w.Body.String())
+	})
+}
+
+func TestGetWithFormat(t *testing.T) {
+	// 外部APIのモック
+	ts := httptest.NewServer(test.Route(successHandlers...))
+	defer ts.Close()
+
+	// 環境変数を一時的に変更
+	t.Setenv("MOCK_API_URL", ts.URL)
+
+	success := map[string]struct {
+		route    string
+		format   EntryFormat
+		query    string
+		wantBody string
+	}{
+		"正常ケース：v1": {
+			route:    "/v1/entries",
+			format:   EntryFormatV1,
+			query:    "user_id=123456&fields=salary",
+			wantBody: `{"entries":[{"Salary":123456}]}` + "\n",
+		},
+		"正常ケース：v2": {
+			route:    "/v2/entries",
+			format:   EntryFormatV2,
+			query:    "user_id=123456",
+			wantBody: `{"entries":[{"name":"案件情報1","user_id":123456,"salary":123456}]}` + "\n",
+		},
+	}
+	for tn, tc := range success {
+		t.Run(tn, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "http://localhost"+tc.route+"?"+tc.query, nil)
+			openapi.Check(t, tc.route, GetWithFormat(tc.format))(w, r)
 
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, tc.wantBody, w.Body.String())
 		})
 	}
 }
 
-func TestGetEntries(t *testing.T) {
+func TestGetUserID(t *testing.T) {
 	success := map[string]struct {
 		params   map[string][]string
 		handlers []test.Handler
-		response []Entry
+		response []int
 	}{
 		"正常ケース：データあり": {
 			params: map[string][]string{
-				"userID": {"123456"},
-			},
-			handlers: []test.Handler{successMockGetEntriesHandler},
-			response: []Entry{
-				{
-					UserID: 123456,
-					Name:   "案件情報1",
-					Salary: 123456,
-				},
+				"name": {"dip 太郎"},
 			},
+			handlers: []test.Handler{successMockGetUserHandler},
+			response: []int{123456},
 		},
 		"正常ケース：データなし": {
 			params: map[string][]string{
-				"userID": {"999999"},
+				"name": {"dip 三郎"},
+			},
+			handlers: []test.Handler{successMockGetUserHandler},
+			response: []int{},
+		},
+		"正常ケース：gzipで圧縮されたレスポンス": {
+			params: map[string][]string{
+				"name": {"dip 太郎"},
+			},
+			handlers: []test.Handler{{Path: "/users", Handler: compressedHandler("gzip", mockAPI.ServeHTTP)}},
+			response: []int{123456},
+		},
+		"正常ケース：brotliで圧縮されたレスポンス": {
+			params: map[string][]string{
+				"name": {"dip 太郎"},
+			},
+			handlers: []test.Handler{{Path: "/users", Handler: compressedHandler("br", mockAPI.ServeHTTP)}},
+			response: []int{123456},
+		},
+		"正常ケース：Content-TypeのないJSON": {
+			params: map[string][]string{
+				"name": {"dip 太郎"},
 			},
-			handlers: []test.Handler{successMockGetEntriesHandler},
-			response: []Entry{},
+			handlers: []test.Handler{{Path: "/users", Handler: contentTypeHandler("", mockAPI.ServeHTTP)}},
+			response: []int{123456},
 		},
 	}
 	fail := map[string]struct {
@@ -373,22 +630,28 @@ func TestGetEntries(t *testing.T) {
 	}{
 		"異常ケース：baseURLが不正": {
 			params: map[string][]string{
-				"userID": {"123456"},
+				"name": {"dip 太郎"},
 			},
 			targetURL: ":\\test",
-			handlers:  []test.Handler{successMockGetEntriesHandler},
+			handlers:  []test.Handler{successMockGetUserHandler},
 		},
 		"異常ケース：外部APIリクエストに失敗": {
 			params: map[string][]string{
-				"userID": {"123456"},
+				"name": {"dip 太郎"},
 			},
-			handlers: getEntriesFailHandlers,
+			handlers: getUsersFailHandlers,
 		},
 		"異常ケース：Jsonのデコードに失敗": {
 			params: map[string][]string{
-				"userID": {"123456"},
+				"name": {"dip 太郎"},
 			},
-			handlers: []test.Handler{invalidResponseGetEntries},
+			handlers: []test.Handler{invalidResponseGetUser},
+		},
+		"異常ケース：JSON以外の形式": {
+			params: map[string][]string{
+				"name": {"dip 太郎"},
+			},
+			handlers: []test.Handler{{Path: "/users", Handler: contentTypeHandler("text/plain; charset=utf-8", mockAPI.ServeHTTP)}},
 		},
 	}
 	for tn, tc := range success {
@@ -406,12 +669,12 @@ func TestGetEntries(t *testing.T) {
 			ctx, cancel := context.WithCancel(context.Background())
 			defer cancel()
 
-			ch := make(chan []Entry)
+			ch := make(chan []int)
 			errch := make(chan error)
-			go GetEntries(ctx, ch, errch, tc.params)
+			go GetUserID(ctx, ch, errch, tc.params)
 			select {
-			case entries := <-ch:
-				assert.ElementsMatch(t, entries, tc.response)
+			case ids := <-ch:
+				assert.ElementsMatch(t, ids, tc.response)
 			case err := <-errch:
 				t.Errorf("Error is occured : %v", err)
 			}
@@ -439,9 +702,9 @@ func TestGetEntries(t *testing.T) {
 			ctx, cancel := context.WithCancel(context.Background())
 			defer cancel()
 
-			ch := make(chan []Entry)
+			ch := make(chan []int)
 			errch := make(chan error)
-			go GetEntries(ctx, ch, errch, tc.params)
+			go GetUserID(ctx, ch, errch, tc.params)
 			select {
 			case ids := <-ch:
 				t.Errorf("Return ids : %v", ids)
@@ -453,117 +716,56 @@ func TestGetEntries(t *testing.T) {
 	}
 }
 
-func MockGetUser(w http.ResponseWriter, r *http.Request) {
-
-	// クエリ文字列に名前があるかチェック
-	query := r.URL.Query()
-	name := query.Get("name")
-
-	if name == "" {
-		http.Error(w, "Invalid parameters", http.StatusBadRequest)
-	}
-
-	// データを返す
-	var data []User
+func MockErrorResponse(w http.ResponseWriter, _ *http.Request) {
+	http.Error(w, "Encoding json is failed", http.StatusInternalServerError)
+}
 
-	switch name {
-	case "dip 太郎":
-		data = []User{
-			{
-				ID:   123456,
-				Name: "dip 太郎",
-				Age:  25,
-			},
+// Content-Typeを書き換えてレスポンスを返す
+// 空の場合はContent-Typeを付けずに返す
+func contentTypeHandler(contentType string, h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		rec := httptest.NewRecorder()
+		h(rec, r)
+		for k, vs := range rec.Header() {
+			w.Header()[k] = vs
 		}
-	case "dip 次郎":
-		data = []User{
-			{
-				ID:   234567,
-				Name: "dip 次郎",
-				Age:  25,
-			},
+		if contentType == "" {
+			// nilを設定するとContent-Typeの自動判定も行わない
+			w.Header()["Content-Type"] = nil
+		} else {
+			w.Header().Set("Content-Type", contentType)
 		}
-	default:
-		data = []User{}
-	}
-
-	// 値を返却する
-	encoder := json.NewEncoder(w)
-	if err := encoder.Encode(data); err != nil {
-		http.Error(w, "Encoding json is failed", http.StatusInternalServerError)
-		return
+		w.WriteHeader(rec.Code)
+		_, _ = w.Write(rec.Body.Bytes())
 	}
-
 }
 
-func MockGetEntry(w http.ResponseWriter, r *http.Request) {
-
-	// クエリ文字列にIDがあるかチェック
-	query := r.URL.Query()
-	var idStrings []string
-	var ok bool
-	idStrings, ok = query["userID"]
-	if !ok {
-		http.Error(w, "Invalid parameters", http.StatusBadRequest)
-		return
-	}
-	length := len(idStrings)
-	if length == 0 {
-		http.Error(w, "Invalid parameters", http.StatusBadRequest)
-		return
-	}
-
-	ids := make([]int, length)
-	var id int
-	var err error
-	for i, idString := range idStrings {
-		id, err = strconv.Atoi(idString)
-		if err != nil {
-			http.Error(w, "Convert is failed", http.StatusInternalServerError)
+// Accept-Encodingに指定があれば、レスポンスを圧縮して返すハンドラ
+func compressedHandler(encoding string, h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		rec := httptest.NewRecorder()
+		h(rec, r)
+		for k, vs := range rec.Header() {
+			w.Header()[k] = vs
+		}
+		if !strings.Contains(r.Header.Get("Accept-Encoding"), encoding) {
+			w.WriteHeader(rec.Code)
+			_, _ = w.Write(rec.Body.Bytes())
 			return
 		}
-		ids[i] = id
-	}
 
-	// データを返す
-	entries := []Entry{
-		{
-			UserID: 123456,
-			Name:   "案件情報1",
-			Salary: 123456,
-		},
-		{
-			UserID: 234567,
-			Name:   "案件情報2",
-			Salary: 123456,
-		},
-	}
-	var data []Entry
-
-	// ユーザーのIDが一致するものを探す
-	for _, userID := range ids {
-		for _, entry := range entries {
-			if userID == entry.UserID {
-				data = append(data, entry)
-			}
+		var buf bytes.Buffer
+		var zw io.WriteCloser
+		switch encoding {
+		case "gzip":
+			zw = gzip.NewWriter(&buf)
+		case "br":
+			zw = brotli.NewWriter(&buf)
 		}
-	}
-
-	// 値を返却する
-	encoder := json.NewEncoder(w)
-	if err := encoder.Encode(data); err != nil {
-		http.Error(w, "Encoding json is failed", http.StatusInternalServerError)
-		return
-	}
-	w.Header().Set("Content-Type", "application/json")
-}
-
-func MakeRedirectHandler(redirectURL string) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		http.Redirect(w, r, redirectURL, http.StatusFound)
+		_, _ = zw.Write(rec.Body.Bytes())
+		_ = zw.Close()
+		w.Header().Set("Content-Encoding", encoding)
+		w.WriteHeader(rec.Code)
+		_, _ = w.Write(buf.Bytes())
 	}
 }
-
-func MockErrorResponse(w http.ResponseWriter, _ *http.Request) {
-	http.Error(w, "Encoding json is failed", http.StatusInternalServerError)
-}
\ No newline at end of file
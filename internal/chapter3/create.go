@@ -0,0 +1,195 @@
+package chapter3
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/dip-dev/go-tutorial/internal/helper/networking"
+)
+
+// 登録時の入力値の制限
+const (
+	maxEntryNameLength = 100
+	minEntrySalary     = 1
+	maxEntrySalary     = 100000000
+	maxEntryBodySize   = 1 << 20
+)
+
+// 案件情報を既定の形式で登録する
+func Create(w http.ResponseWriter, r *http.Request) {
+	create(w, r, DefaultEntryFormat())
+}
+
+// 案件情報を指定した形式で登録するハンドラ
+// リクエストボディとレスポンスは同じ形式のキーを使う
+func CreateWithFormat(format EntryFormat) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		create(w, r, format)
+	}
+}
+
+func create(w http.ResponseWriter, r *http.Request, format EntryFormat) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	// クライアントが切断した場合は外部APIへのリクエストも中断する
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	// リクエストボディの読み込み
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxEntryBodySize))
+	if err != nil {
+		http.Error(w, "Invalid body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	// 入力値の検証
+	e, err := format.decode(body)
+	if err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			verr := &ValidationError{}
+			message := "must be an integer"
+			if typeErr.Type.Kind() == reflect.String {
+				message = "must be a string"
+			}
+			verr.add(typeErr.Field, message)
+			writeValidationError(w, http.StatusBadRequest, verr)
+			return
+		}
+		http.Error(w, "Invalid body", http.StatusBadRequest)
+		return
+	}
+	if err = ValidateEntry(e, format); err != nil {
+		var verr *ValidationError
+		if errors.As(err, &verr) {
+			writeValidationError(w, http.StatusBadRequest, verr)
+			return
+		}
+		http.Error(w, "Invalid body", http.StatusBadRequest)
+		return
+	}
+
+	// エラー受信用のチャンネル
+	errch := make(chan error)
+
+	// ユーザーが存在するか確認する
+	ch1 := make(chan []int)
+	go GetUserID(ctx, ch1, errch, map[string][]string{"id": {strconv.Itoa(e.UserID)}})
+	select {
+	case ids := <-ch1:
+		// idで絞り込まれない場合もあるため、指定したユーザーが含まれるか確認する
+		if len(intersectIDs(ids, []int{e.UserID})) == 0 {
+			verr := &ValidationError{}
+			verr.add("user_id", "user does not exist")
+			writeValidationError(w, http.StatusUnprocessableEntity, verr)
+			return
+		}
+	case err = <-errch:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// 外部APIへ登録する
+	ch2 := make(chan Entry)
+	go PostEntry(ctx, ch2, errch, e)
+	var created Entry
+	select {
+	case created = <-ch2:
+	case err = <-errch:
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	b, err := json.Marshal(format.wire(created))
+	if err != nil {
+		http.Error(w, "Encoding response is failed", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", entryLocation(r, created))
+	w.WriteHeader(http.StatusCreated)
+	_, _ = w.Write(b)
+}
+
+// 登録する案件情報を検証する
+// 項目名はリクエストと同じ形式のキーで返す
+func ValidateEntry(e Entry, format EntryFormat) error {
+	verr := &ValidationError{}
+	if e.Name == "" {
+		verr.add(format.fieldName("name"), "must not be empty")
+	} else if utf8.RuneCountInString(e.Name) > maxEntryNameLength {
+		verr.add(format.fieldName("name"), fmt.Sprintf("must be at most %d characters", maxEntryNameLength))
+	}
+	if e.UserID <= 0 {
+		verr.add(format.fieldName("user_id"), "must be a positive integer")
+	}
+	if e.Salary < minEntrySalary || e.Salary > maxEntrySalary {
+		verr.add(format.fieldName("salary"), fmt.Sprintf("must be between %d and %d", minEntrySalary, maxEntrySalary))
+	}
+	if len(verr.Errors) > 0 {
+		return verr
+	}
+	return nil
+}
+
+func PostEntry(ctx context.Context, ch chan Entry, errch chan error, e Entry) {
+	// ヘッダーの設定
+	header := map[string][]string{
+		"key": {"dip"},
+	}
+
+	// Clientのインスタンス化
+	c, err := networking.NewClient(targetURL, upstreamOptions...)
+	if err != nil {
+		sendError(ctx, errch, err)
+		return
+	}
+
+	// 外部APIへリクエスト
+	res, err := c.NewRequestAndDo(ctx, http.MethodPost, c.BaseURL.JoinPath("/entries"), header, nil, networking.JSON(newUpstreamEntry(e)))
+	if err != nil {
+		sendError(ctx, errch, err)
+		return
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK && res.StatusCode != http.StatusCreated {
+		msg, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		sendError(ctx, errch, fmt.Errorf("mock-api returned %d: %s", res.StatusCode, bytes.TrimSpace(msg)))
+		return
+	}
+
+	var got upstreamEntry
+	if err = networking.DecodeResponse(res, &got); err != nil {
+		sendError(ctx, errch, err)
+		return
+	}
+
+	select {
+	case ch <- got.toEntry():
+	case <-ctx.Done():
+	}
+}
+
+// 登録した案件情報の参照先
+// 案件情報には個別のURLがないため、登録したユーザーの一覧を示す
+// バージョンの接頭辞を残すため、ルーターで書き換える前のパスを使う
+func entryLocation(r *http.Request, e Entry) string {
+	path := r.URL.Path
+	if u, err := url.ParseRequestURI(r.RequestURI); err == nil && u.Path != "" {
+		path = u.Path
+	}
+	return path + "?" + url.Values{"user_id": {strconv.Itoa(e.UserID)}}.Encode()
+}
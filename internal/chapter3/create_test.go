@@ -0,0 +1,232 @@
+package chapter3
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/dip-dev/go-tutorial/internal/helper/idempotency"
+	"github.com/dip-dev/go-tutorial/internal/helper/openapi"
+	"github.com/dip-dev/go-tutorial/internal/helper/test"
+)
+
+// 登録のテストで他のテストのデータを変更しないよう、ケースごとにmock-apiを用意する
+func newCreateMockAPI(t *testing.T) *test.MockAPI {
+	t.Helper()
+	m := test.NewMockAPI(mockData())
+	ts := httptest.NewServer(m)
+	t.Cleanup(ts.Close)
+	t.Setenv("MOCK_API_URL", ts.URL)
+	return m
+}
+
+func TestCreate(t *testing.T) {
+	success := map[string]struct {
+		route        string
+		format       EntryFormat
+		body         string
+		wantBody     string
+		wantLocation string
+	}{
+		"正常ケース：v2": {
+			route:        "/v2/entries",
+			format:       EntryFormatV2,
+			body:         `{"name":"案件情報4","user_id":234567,"salary":300000}`,
+			wantBody:     `{"name":"案件情報4","user_id":234567,"salary":300000}`,
+			wantLocation: "/v2/entries?user_id=234567",
+		},
+		"正常ケース：v1": {
+			route:        "/v1/entries",
+			format:       EntryFormatV1,
+			body:         `{"Name":"案件情報4","UserID":234567,"Salary":300000}`,
+			wantBody:     `{"Name":"案件情報4","UserID":234567,"Salary":300000}`,
+			wantLocation: "/v1/entries?user_id=234567",
+		},
+		"正常ケース：バージョン指定なし": {
+			route:        "/entries",
+			body:         `{"Name":"案件情報4","UserID":234567,"Salary":300000}`,
+			wantBody:     `{"Name":"案件情報4","UserID":234567,"Salary":300000}`,
+			wantLocation: "/entries?user_id=234567",
+		},
+	}
+	for tn, tc := range success {
+		t.Run(tn, func(t *testing.T) {
+			m := newCreateMockAPI(t)
+
+			h := Create
+			if tc.format != 0 {
+				h = CreateWithFormat(tc.format)
+			}
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPost, "http://localhost"+tc.route, strings.NewReader(tc.body))
+			r.Header.Set("Content-Type", "application/json")
+			openapi.Check(t, tc.route, h)(w, r)
+
+			assert.Equal(t, http.StatusCreated, w.Code)
+			assert.JSONEq(t, tc.wantBody, w.Body.String())
+			assert.Equal(t, tc.wantLocation, w.Header().Get("Location"))
+			assert.Contains(t, m.Entries(), test.MockEntry{Name: "案件情報4", UserID: 234567, Salary: 300000})
+		})
+	}
+
+	fail := map[string]struct {
+		body       string
+		wantStatus int
+		wantBody   string
+	}{
+		"異常ケース：不正なJSON": {
+			body:       `{`,
+			wantStatus: http.StatusBadRequest,
+		},
+		"異常ケース：給与が数値ではない": {
+			body:       `{"name":"案件情報4","user_id":234567,"salary":"abc"}`,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"errors":[{"field":"salary","message":"must be an integer"}]}`,
+		},
+		"異常ケース：案件名が文字列ではない": {
+			body:       `{"name":1,"user_id":234567,"salary":300000}`,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"errors":[{"field":"name","message":"must be a string"}]}`,
+		},
+		"異常ケース：必須の項目なし": {
+			body:       `{}`,
+			wantStatus: http.StatusBadRequest,
+			wantBody: `{"errors":[` +
+				`{"field":"name","message":"must not be empty"},` +
+				`{"field":"user_id","message":"must be a positive integer"},` +
+				`{"field":"salary","message":"must be between 1 and 100000000"}]}`,
+		},
+		"異常ケース：案件名が長すぎる": {
+			body:       `{"name":"` + strings.Repeat("案", maxEntryNameLength+1) + `","user_id":234567,"salary":300000}`,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"errors":[{"field":"name","message":"must be at most 100 characters"}]}`,
+		},
+		"異常ケース：給与が上限を超える": {
+			body:       `{"name":"案件情報4","user_id":234567,"salary":100000001}`,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"errors":[{"field":"salary","message":"must be between 1 and 100000000"}]}`,
+		},
+		"異常ケース：存在しないユーザー": {
+			body:       `{"name":"案件情報4","user_id":1,"salary":300000}`,
+			wantStatus: http.StatusUnprocessableEntity,
+			wantBody:   `{"errors":[{"field":"user_id","message":"user does not exist"}]}`,
+		},
+	}
+	for tn, tc := range fail {
+		t.Run(tn, func(t *testing.T) {
+			m := newCreateMockAPI(t)
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPost, "http://localhost/entries", strings.NewReader(tc.body))
+			r.Header.Set("Content-Type", "application/json")
+			openapi.Check(t, "/v2/entries", CreateWithFormat(EntryFormatV2))(w, r)
+
+			assert.Equal(t, tc.wantStatus, w.Code)
+			if tc.wantBody != "" {
+				assert.JSONEq(t, tc.wantBody, w.Body.String())
+			}
+			// 失敗した場合は登録しない
+			assert.Equal(t, mockData().Entries, m.Entries())
+		})
+	}
+
+	t.Run("異常ケース：v1の検証エラーはv1のキーで返す", func(t *testing.T) {
+		m := newCreateMockAPI(t)
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "http://localhost/v1/entries", strings.NewReader(`{"Name":"","UserID":"abc","Salary":0}`))
+		r.Header.Set("Content-Type", "application/json")
+		openapi.Check(t, "/v1/entries", CreateWithFormat(EntryFormatV1))(w, r)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.JSONEq(t, `{"errors":[{"field":"UserID","message":"must be an integer"}]}`, w.Body.String())
+
+		w = httptest.NewRecorder()
+		r = httptest.NewRequest(http.MethodPost, "http://localhost/v1/entries", strings.NewReader(`{"Name":"","UserID":0,"Salary":0}`))
+		r.Header.Set("Content-Type", "application/json")
+		openapi.Check(t, "/v1/entries", CreateWithFormat(EntryFormatV1))(w, r)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.JSONEq(t, `{"errors":[`+
+			`{"field":"Name","message":"must not be empty"},`+
+			`{"field":"UserID","message":"must be a positive integer"},`+
+			`{"field":"Salary","message":"must be between 1 and 100000000"}]}`, w.Body.String())
+		assert.Equal(t, mockData().Entries, m.Entries())
+	})
+	t.Run("異常ケース：メソッドが一致しない", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPut, "http://localhost/entries", nil)
+		Create(w, r)
+		assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+	})
+	t.Run("異常ケース：外部APIが別のユーザーを返す", func(t *testing.T) {
+		// idの条件を無視してユーザーを返す外部API
+		var posted bool
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/entries" {
+				posted = true
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`[{"id":123456,"name":"dip 太郎","age":25},{"id":345678,"name":"dip 花子","age":25}]`))
+		}))
+		defer ts.Close()
+		t.Setenv("MOCK_API_URL", ts.URL)
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "http://localhost/entries", strings.NewReader(`{"name":"案件情報4","user_id":234567,"salary":300000}`))
+		r.Header.Set("Content-Type", "application/json")
+		openapi.Check(t, "/v2/entries", CreateWithFormat(EntryFormatV2))(w, r)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+		assert.JSONEq(t, `{"errors":[{"field":"user_id","message":"user does not exist"}]}`, w.Body.String())
+		assert.False(t, posted)
+	})
+	t.Run("異常ケース：外部APIの登録失敗", func(t *testing.T) {
+		ts := httptest.NewServer(test.Route(successMockGetUserHandler, failMockGetEntryHandler))
+		defer ts.Close()
+		t.Setenv("MOCK_API_URL", ts.URL)
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "http://localhost/entries", strings.NewReader(`{"name":"案件情報4","user_id":234567,"salary":300000}`))
+		r.Header.Set("Content-Type", "application/json")
+		openapi.Check(t, "/v2/entries", CreateWithFormat(EntryFormatV2))(w, r)
+		assert.Equal(t, http.StatusBadGateway, w.Code)
+	})
+}
+
+// Idempotency-Keyはミドルウェアで処理する
+func TestCreateIdempotency(t *testing.T) {
+	body := `{"name":"案件情報4","user_id":234567,"salary":300000}`
+	h := idempotency.New(idempotency.NewMemoryStore()).HandlerFunc(CreateWithFormat(EntryFormatV2))
+	post := func(t *testing.T, key, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "http://localhost/entries", strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/json")
+		r.Header.Set(idempotency.KeyHeader, key)
+		openapi.Check(t, "/v2/entries", h)(w, r)
+		return w
+	}
+
+	t.Run("正常ケース：同じキーの再送は登録しない", func(t *testing.T) {
+		m := newCreateMockAPI(t)
+
+		first := post(t, "create-once", body)
+		second := post(t, "create-once", body)
+
+		assert.Equal(t, http.StatusCreated, first.Code)
+		assert.Equal(t, http.StatusCreated, second.Code)
+		assert.Equal(t, first.Body.String(), second.Body.String())
+		assert.Equal(t, first.Header().Get("Location"), second.Header().Get("Location"))
+		assert.Equal(t, "true", second.Header().Get(idempotency.ReplayedHeader))
+		assert.Len(t, m.Entries(), len(mockData().Entries)+1)
+	})
+	t.Run("異常ケース：同じキーで内容が異なる", func(t *testing.T) {
+		m := newCreateMockAPI(t)
+
+		assert.Equal(t, http.StatusCreated, post(t, "create-conflict", body).Code)
+		w := post(t, "create-conflict", `{"name":"案件情報5","user_id":234567,"salary":300000}`)
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+		assert.Len(t, m.Entries(), len(mockData().Entries)+1)
+	})
+}
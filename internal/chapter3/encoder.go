@@ -0,0 +1,153 @@
+package chapter3
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/dip-dev/go-tutorial/internal/helper/fieldset"
+)
+
+// 案件情報一覧のレスポンス形式
+const (
+	mediaTypeJSON   = "application/json"
+	mediaTypeCSV    = "text/csv"
+	mediaTypeNDJSON = "application/x-ndjson"
+)
+
+// 対応しているレスポンス形式（先頭がデフォルト）
+var entryMediaTypes = []string{mediaTypeJSON, mediaTypeCSV, mediaTypeNDJSON}
+
+// Excelで開いた際に文字化けしないよう、CSVの先頭に付与するBOM
+const utf8BOM = "\ufeff"
+
+// 案件情報を1件ずつ書き出すエンコーダー
+type entryEncoder interface {
+	// Content-Typeヘッダーの値
+	contentType() string
+	// 一覧の書き出し開始
+	begin() error
+	// 案件情報1件の書き出し
+	encode(e Entry) error
+	// 一覧の書き出し終了
+	end() error
+}
+
+func newEntryEncoder(mediaType string, w io.Writer, fields fieldset.Fields, format EntryFormat) entryEncoder {
+	switch mediaType {
+	case mediaTypeCSV:
+		if fields.Empty() {
+			fields = fieldset.All(format.model())
+		}
+		return &csvEntryEncoder{w: w, csv: csv.NewWriter(w), fields: fields, format: format}
+	case mediaTypeNDJSON:
+		return &ndjsonEntryEncoder{enc: json.NewEncoder(w), fields: fields, format: format}
+	default:
+		return &jsonEntryEncoder{w: w, fields: fields, format: format}
+	}
+}
+
+// {"entries":[...]}の形式で書き出す
+type jsonEntryEncoder struct {
+	w      io.Writer
+	fields fieldset.Fields
+	format EntryFormat
+	count  int
+}
+
+func (e *jsonEntryEncoder) contentType() string {
+	return mediaTypeJSON
+}
+
+func (e *jsonEntryEncoder) begin() error {
+	_, err := io.WriteString(e.w, `{"entries":[`)
+	return err
+}
+
+func (e *jsonEntryEncoder) encode(entry Entry) error {
+	b, err := json.Marshal(e.fields.Project(e.format.wire(entry)))
+	if err != nil {
+		return err
+	}
+	if e.count > 0 {
+		if _, err = io.WriteString(e.w, ","); err != nil {
+			return err
+		}
+	}
+	e.count++
+	_, err = e.w.Write(b)
+	return err
+}
+
+func (e *jsonEntryEncoder) end() error {
+	_, err := io.WriteString(e.w, "]}\n")
+	return err
+}
+
+// 1行に1件ずつJSONで書き出す
+type ndjsonEntryEncoder struct {
+	enc    *json.Encoder
+	fields fieldset.Fields
+	format EntryFormat
+}
+
+func (e *ndjsonEntryEncoder) contentType() string {
+	return mediaTypeNDJSON
+}
+
+func (e *ndjsonEntryEncoder) begin() error {
+	return nil
+}
+
+func (e *ndjsonEntryEncoder) encode(entry Entry) error {
+	return e.enc.Encode(e.fields.Project(e.format.wire(entry)))
+}
+
+func (e *ndjsonEntryEncoder) end() error {
+	return nil
+}
+
+// ヘッダー行付きのCSVで書き出す
+// 値のエスケープはencoding/csvに任せる
+type csvEntryEncoder struct {
+	w      io.Writer
+	csv    *csv.Writer
+	fields fieldset.Fields
+	format EntryFormat
+}
+
+func (e *csvEntryEncoder) contentType() string {
+	return mediaTypeCSV + "; charset=utf-8"
+}
+
+func (e *csvEntryEncoder) begin() error {
+	if _, err := io.WriteString(e.w, utf8BOM); err != nil {
+		return err
+	}
+	if err := e.csv.Write(e.fields.Keys()); err != nil {
+		return err
+	}
+	return e.flush()
+}
+
+func (e *csvEntryEncoder) encode(entry Entry) error {
+	obj, _ := e.fields.Project(e.format.wire(entry)).(fieldset.Object)
+	record := make([]string, 0, len(obj))
+	for _, m := range obj {
+		record = append(record, fmt.Sprint(m.Value))
+	}
+	if err := e.csv.Write(record); err != nil {
+		return err
+	}
+	return e.flush()
+}
+
+func (e *csvEntryEncoder) end() error {
+	return e.flush()
+}
+
+func (e *csvEntryEncoder) flush() error {
+	e.csv.Flush()
+	return e.csv.Error()
+}
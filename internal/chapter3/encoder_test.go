@@ -0,0 +1,45 @@
+package chapter3
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/dip-dev/go-tutorial/internal/helper/fieldset"
+	"github.com/dip-dev/go-tutorial/internal/helper/test"
+)
+
+func TestCSVEntryEncoder(t *testing.T) {
+	t.Run("正常ケース：区切り文字・引用符を含む名前をエスケープ", func(t *testing.T) {
+		entries := []Entry{
+			{Name: "案件「A」, 東京", UserID: 1, Salary: 100},
+			{Name: `"大阪"案件`, UserID: 2, Salary: 200},
+			{Name: "改行\n案件", UserID: 3, Salary: 300},
+		}
+		var buf bytes.Buffer
+		enc := newEntryEncoder(mediaTypeCSV, &buf, fieldset.Fields{}, EntryFormatV2)
+		assert.NoError(t, enc.begin())
+		for _, e := range entries {
+			assert.NoError(t, enc.encode(e))
+		}
+		assert.NoError(t, enc.end())
+
+		// BOMを除いて読み戻すと元の値と一致する
+		body := strings.TrimPrefix(buf.String(), utf8BOM)
+		records, err := csv.NewReader(strings.NewReader(body)).ReadAll()
+		assert.NoError(t, err)
+		assert.Equal(t, [][]string{
+			{"name", "user_id", "salary"},
+			{"案件「A」, 東京", "1", "100"},
+			{`"大阪"案件`, "2", "200"},
+			{"改行\n案件", "3", "300"},
+		}, records)
+	})
+	t.Run("異常ケース：書き込みに失敗", func(t *testing.T) {
+		enc := newEntryEncoder(mediaTypeCSV, &test.ErrorResponseWriter{}, fieldset.Fields{}, EntryFormatV2)
+		assert.Error(t, enc.begin())
+	})
+}
@@ -0,0 +1,243 @@
+package chapter3
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/dip-dev/go-tutorial/internal/helper/fieldset"
+)
+
+// ソートに使用できる項目（v2の形式の項目名）
+// v1ではレスポンスと同じPascalCaseのキーで指定する
+const (
+	sortFieldName   = "name"
+	sortFieldUserID = "user_id"
+	sortFieldSalary = "salary"
+)
+
+// 案件情報一覧の検索条件
+type EntryQuery struct {
+	Names     []string
+	UserIDs   []int
+	MinSalary *int
+	MaxSalary *int
+	Sort      []SortKey
+	Fields    fieldset.Fields
+	// レスポンスの形式
+	Format EntryFormat
+}
+
+// ソート条件
+type SortKey struct {
+	Field string
+	Desc  bool
+}
+
+// パラメータ単位のエラー
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// パラメータの検証エラー
+type ValidationError struct {
+	Errors []FieldError `json:"errors"`
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, 0, len(e.Errors))
+	for _, fe := range e.Errors {
+		msgs = append(msgs, fe.Field+": "+fe.Message)
+	}
+	return strings.Join(msgs, ", ")
+}
+
+func (e *ValidationError) add(field, message string) {
+	e.Errors = append(e.Errors, FieldError{Field: field, Message: message})
+}
+
+// クエリパラメータを検索条件に変換する
+// 項目の指定はレスポンスの形式のキーで検証する
+func ParseEntryQuery(query url.Values, format EntryFormat) (*EntryQuery, error) {
+	q := &EntryQuery{Format: format}
+	verr := &ValidationError{}
+
+	// 空の名前も従来どおり外部APIへそのまま渡す
+	q.Names = append(q.Names, query["name"]...)
+
+	for _, v := range query["user_id"] {
+		id, err := strconv.Atoi(v)
+		if err != nil || id <= 0 {
+			verr.add("user_id", "must be a positive integer")
+			continue
+		}
+		q.UserIDs = append(q.UserIDs, id)
+	}
+
+	if _, ok := query["name"]; !ok && len(query["user_id"]) == 0 {
+		verr.add("name", "name or user_id is required")
+	}
+
+	q.MinSalary = parseSalary(query, "min_salary", verr)
+	q.MaxSalary = parseSalary(query, "max_salary", verr)
+	if q.MinSalary != nil && q.MaxSalary != nil && *q.MinSalary > *q.MaxSalary {
+		verr.add("max_salary", "must be greater than or equal to min_salary")
+	}
+
+	if raw := query.Get("sort"); raw != "" {
+		q.Sort = parseSort(raw, format, verr)
+	}
+
+	fields, err := fieldset.Parse(query.Get("fields"), format.model())
+	if err != nil {
+		verr.add("fields", err.Error())
+	}
+	q.Fields = fields
+
+	if len(verr.Errors) > 0 {
+		return nil, verr
+	}
+	return q, nil
+}
+
+func parseSalary(query url.Values, field string, verr *ValidationError) *int {
+	vs, ok := query[field]
+	if !ok {
+		return nil
+	}
+	if len(vs) != 1 {
+		verr.add(field, "must be specified only once")
+		return nil
+	}
+	salary, err := strconv.Atoi(vs[0])
+	if err != nil || salary < 0 {
+		verr.add(field, "must be a non-negative integer")
+		return nil
+	}
+	return &salary
+}
+
+// ソート条件はレスポンスの形式のキーで受け付け、項目名に変換する
+func parseSort(raw string, format EntryFormat, verr *ValidationError) []SortKey {
+	var keys []SortKey
+	seen := map[string]bool{}
+	for _, s := range strings.Split(raw, ",") {
+		name := strings.TrimSpace(s)
+		key := SortKey{}
+		if strings.HasPrefix(name, "-") {
+			name = name[1:]
+			key.Desc = true
+		}
+		field, ok := format.parseFieldName(name)
+		if !ok {
+			verr.add("sort", "unknown sort key: "+s)
+			continue
+		}
+		key.Field = field
+		if seen[key.Field] {
+			verr.add("sort", "duplicate sort key: "+name)
+			continue
+		}
+		seen[key.Field] = true
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// 外部APIの/entriesへ渡す検索条件
+// mock-apiが対応するユーザーIDと給与の条件は外部APIで絞り込む
+func (q *EntryQuery) UpstreamParams(ids []int) map[string][]string {
+	params := map[string][]string{}
+	for _, id := range ids {
+		params["userID"] = append(params["userID"], strconv.Itoa(id))
+	}
+	if q.MinSalary != nil {
+		params["minSalary"] = []string{strconv.Itoa(*q.MinSalary)}
+	}
+	if q.MaxSalary != nil {
+		params["maxSalary"] = []string{strconv.Itoa(*q.MaxSalary)}
+	}
+	return params
+}
+
+// 案件情報が検索条件に一致するか
+// ユーザーIDは外部APIで絞り込み済みのため、ここでは給与のみを判定する
+// 給与の条件に対応していない外部APIもあるため、受信後にも判定する
+func (q *EntryQuery) Match(e Entry) bool {
+	if q.MinSalary != nil && e.Salary < *q.MinSalary {
+		return false
+	}
+	if q.MaxSalary != nil && e.Salary > *q.MaxSalary {
+		return false
+	}
+	return true
+}
+
+// ソート条件の順に並び替える
+func SortEntries(entries []Entry, keys []SortKey) {
+	if len(keys) == 0 {
+		return
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		for _, key := range keys {
+			c := compareEntry(entries[i], entries[j], key.Field)
+			if c == 0 {
+				continue
+			}
+			if key.Desc {
+				return c > 0
+			}
+			return c < 0
+		}
+		return false
+	})
+}
+
+func compareEntry(a, b Entry, field string) int {
+	switch field {
+	case sortFieldName:
+		return strings.Compare(a.Name, b.Name)
+	case sortFieldUserID:
+		return compareInt(a.UserID, b.UserID)
+	case sortFieldSalary:
+		return compareInt(a.Salary, b.Salary)
+	}
+	return 0
+}
+
+func compareInt(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// ユーザーIDの共通部分を取得する
+func intersectIDs(ids []int, want []int) []int {
+	set := map[int]bool{}
+	for _, id := range want {
+		set[id] = true
+	}
+	var result []int
+	for _, id := range ids {
+		if set[id] {
+			result = append(result, id)
+		}
+	}
+	return result
+}
+
+// 検証エラーをJSONで返却する
+func writeValidationError(w http.ResponseWriter, status int, verr *ValidationError) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(verr)
+}
@@ -0,0 +1,150 @@
+package chapter3
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseEntryQuery(t *testing.T) {
+	minSalary, maxSalary := 100, 200
+
+	success := map[string]struct {
+		query url.Values
+		want  *EntryQuery
+	}{
+		"正常ケース：名前のみ": {
+			query: url.Values{"name": {"dip 太郎"}},
+			want:  &EntryQuery{Names: []string{"dip 太郎"}, Format: EntryFormatV2},
+		},
+		"正常ケース：空の名前もそのまま渡す": {
+			query: url.Values{"name": {""}},
+			want:  &EntryQuery{Names: []string{""}, Format: EntryFormatV2},
+		},
+		"正常ケース：全ての条件あり": {
+			query: url.Values{
+				"name":       {"dip 太郎"},
+				"user_id":    {"1", "2"},
+				"min_salary": {"100"},
+				"max_salary": {"200"},
+				"sort":       {"salary,-name"},
+			},
+			want: &EntryQuery{
+				Names:     []string{"dip 太郎"},
+				UserIDs:   []int{1, 2},
+				MinSalary: &minSalary,
+				MaxSalary: &maxSalary,
+				Sort: []SortKey{
+					{Field: "salary"},
+					{Field: "name", Desc: true},
+				},
+				Format: EntryFormatV2,
+			},
+		},
+	}
+	fail := map[string]struct {
+		query      url.Values
+		wantFields []string
+	}{
+		"異常ケース：名前もユーザーIDも無い": {
+			query:      url.Values{},
+			wantFields: []string{"name"},
+		},
+		"異常ケース：給与が複数指定されている": {
+			query:      url.Values{"user_id": {"1"}, "min_salary": {"1", "2"}},
+			wantFields: []string{"min_salary"},
+		},
+		"異常ケース：給与が負の値": {
+			query:      url.Values{"user_id": {"1"}, "max_salary": {"-1"}},
+			wantFields: []string{"max_salary"},
+		},
+		"異常ケース：ソートキーが重複": {
+			query:      url.Values{"user_id": {"1"}, "sort": {"salary,-salary"}},
+			wantFields: []string{"sort"},
+		},
+		"異常ケース：複数のエラー": {
+			query:      url.Values{"user_id": {"abc"}, "sort": {"age"}},
+			wantFields: []string{"user_id", "sort"},
+		},
+	}
+
+	for tn, tc := range success {
+		t.Run(tn, func(t *testing.T) {
+			got, err := ParseEntryQuery(tc.query, EntryFormatV2)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+	for tn, tc := range fail {
+		t.Run(tn, func(t *testing.T) {
+			_, err := ParseEntryQuery(tc.query, EntryFormatV2)
+			var verr *ValidationError
+			if !assert.ErrorAs(t, err, &verr) {
+				return
+			}
+			fields := []string{}
+			for _, fe := range verr.Errors {
+				fields = append(fields, fe.Field)
+			}
+			assert.ElementsMatch(t, tc.wantFields, fields)
+		})
+	}
+}
+
+func TestSortEntries(t *testing.T) {
+	entries := []Entry{
+		{Name: "b", UserID: 1, Salary: 100},
+		{Name: "a", UserID: 2, Salary: 200},
+		{Name: "c", UserID: 3, Salary: 100},
+	}
+	SortEntries(entries, []SortKey{{Field: "salary"}, {Field: "name", Desc: true}})
+	assert.Equal(t, []Entry{
+		{Name: "c", UserID: 3, Salary: 100},
+		{Name: "b", UserID: 1, Salary: 100},
+		{Name: "a", UserID: 2, Salary: 200},
+	}, entries)
+}
+
+// v1ではソート条件もレスポンスと同じPascalCaseのキーで指定する
+func TestParseEntryQueryV1(t *testing.T) {
+	t.Run("正常ケース：v1のキーでソート", func(t *testing.T) {
+		got, err := ParseEntryQuery(url.Values{"user_id": {"1"}, "sort": {"Salary,-UserID"}}, EntryFormatV1)
+		if assert.NoError(t, err) {
+			assert.Equal(t, []SortKey{{Field: sortFieldSalary}, {Field: sortFieldUserID, Desc: true}}, got.Sort)
+		}
+	})
+	t.Run("異常ケース：v1でv2のキーを指定", func(t *testing.T) {
+		_, err := ParseEntryQuery(url.Values{"user_id": {"1"}, "sort": {"user_id"}}, EntryFormatV1)
+		var verr *ValidationError
+		if assert.ErrorAs(t, err, &verr) {
+			assert.Equal(t, []FieldError{{Field: "sort", Message: "unknown sort key: user_id"}}, verr.Errors)
+		}
+	})
+}
+
+func TestUpstreamParams(t *testing.T) {
+	minSalary, maxSalary := 100, 200
+
+	success := map[string]struct {
+		query *EntryQuery
+		ids   []int
+		want  map[string][]string
+	}{
+		"正常ケース：ユーザーIDのみ": {
+			query: &EntryQuery{},
+			ids:   []int{1, 2},
+			want:  map[string][]string{"userID": {"1", "2"}},
+		},
+		"正常ケース：給与の条件も渡す": {
+			query: &EntryQuery{MinSalary: &minSalary, MaxSalary: &maxSalary},
+			ids:   []int{1},
+			want:  map[string][]string{"userID": {"1"}, "minSalary": {"100"}, "maxSalary": {"200"}},
+		},
+	}
+	for tn, tc := range success {
+		t.Run(tn, func(t *testing.T) {
+			assert.Equal(t, tc.want, tc.query.UpstreamParams(tc.ids))
+		})
+	}
+}
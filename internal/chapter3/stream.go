@@ -0,0 +1,210 @@
+package chapter3
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/dip-dev/go-tutorial/internal/helper/networking"
+)
+
+// 何件書き出すごとにクライアントへフラッシュするか
+const streamFlushInterval = 50
+
+// レスポンスの書き出しに失敗した場合のエラー
+var errEncoding = errors.New("Encoding response is failed")
+
+// 外部APIのレスポンスを扱えない場合のエラー（502を返す）
+var errBadUpstream = errors.New("bad response from mock-api")
+
+// 案件情報一覧のデコーダー
+// 件数が多くてもメモリに載せないよう、JSONの配列を1件ずつ読み進める（JSON以外の形式は扱わない）
+var entryDecoders = func() *networking.Decoders {
+	d := &networking.Decoders{}
+	d.Register("application/json", decodeEntryStream)
+	return d
+}()
+
+// 案件情報を1件ずつ取得してチャンネルへ送信する
+// 全件送信し終えたらchを閉じる
+func StreamEntries(ctx context.Context, ch chan Entry, errch chan error, params map[string][]string) {
+	// ヘッダーの設定
+	header := map[string][]string{"key": {"dip"}}
+
+	// Clientのインスタンス化
+	c, err := networking.NewClient(targetURL, upstreamOptions...)
+	if err != nil {
+		sendError(ctx, errch, err)
+		return
+	}
+
+	// 外部APIへリクエスト
+	res, err := c.NewRequestAndDo(ctx, http.MethodGet, c.BaseURL.JoinPath("/entries"), header, params, nil)
+	if err != nil {
+		sendError(ctx, errch, err)
+		return
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		sendError(ctx, errch, fmt.Errorf("%w: unexpected status %d", errBadUpstream, res.StatusCode))
+		return
+	}
+	err = entryDecoders.DecodeResponse(res, func(e Entry) error {
+		select {
+		case ch <- e:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+	if errors.Is(err, networking.ErrUnsupportedContentType) {
+		err = fmt.Errorf("%w: %v", errBadUpstream, err)
+	}
+	if err != nil {
+		sendError(ctx, errch, err)
+		return
+	}
+	close(ch)
+}
+
+// entryDecodersに登録するデコーダー（vには要素ごとに呼び出す関数を指定する）
+func decodeEntryStream(r io.Reader, v any) error {
+	fn, ok := v.(func(Entry) error)
+	if !ok {
+		return fmt.Errorf("cannot decode entries into %T", v)
+	}
+	return decodeEntries(r, fn)
+}
+
+// 受信側が処理を終えている場合に送信で止まらないようにする
+func sendError(ctx context.Context, errch chan error, err error) {
+	select {
+	case errch <- err:
+	case <-ctx.Done():
+	}
+}
+
+// JSONの配列をトークン単位で読み進め、要素ごとにfnを呼び出す
+// 配列全体をメモリに載せないため、件数が多くても使用メモリは一定になる
+func decodeEntries(r io.Reader, fn func(Entry) error) error {
+	dec := json.NewDecoder(r)
+
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	// 該当データが無い場合はnullが返ってくる
+	if tok == nil {
+		return nil
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '[' {
+		return fmt.Errorf("unexpected token %v: entries must be an array", tok)
+	}
+
+	for dec.More() {
+		var u upstreamEntry
+		if err = dec.Decode(&u); err != nil {
+			return err
+		}
+		if err = fn(u.toEntry()); err != nil {
+			return err
+		}
+	}
+
+	// 閉じ括弧まで読めなければ途中で切断されている
+	if _, err = dec.Token(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// 案件情報一覧をレスポンスへ逐次書き出す
+// ヘッダーは最初の書き出し時に送信するため、それまでに発生したエラーは通常のエラーレスポンスにできる
+type entryStream struct {
+	w         http.ResponseWriter
+	rc        *http.ResponseController
+	enc       entryEncoder
+	mediaType string
+	started   bool
+	count     int
+}
+
+func newEntryStream(w http.ResponseWriter, mediaType string, q *EntryQuery) *entryStream {
+	return &entryStream{
+		w:         w,
+		rc:        http.NewResponseController(w),
+		enc:       newEntryEncoder(mediaType, w, q.Fields, q.Format),
+		mediaType: mediaType,
+	}
+}
+
+func (s *entryStream) begin() error {
+	s.started = true
+	h := s.w.Header()
+	h.Set("Content-Type", s.enc.contentType())
+	h.Add("Vary", "Accept")
+	if s.mediaType == mediaTypeCSV {
+		h.Set("Content-Disposition", `attachment; filename="entries.csv"`)
+	}
+	return s.enc.begin()
+}
+
+// 案件情報を1件書き出す
+func (s *entryStream) write(e Entry) error {
+	if !s.started {
+		if err := s.begin(); err != nil {
+			return err
+		}
+	}
+	if err := s.enc.encode(e); err != nil {
+		return err
+	}
+	s.count++
+	if s.count%streamFlushInterval == 0 {
+		return s.flush()
+	}
+	return nil
+}
+
+// 一覧の書き出しを終える
+func (s *entryStream) close() error {
+	if !s.started {
+		if err := s.begin(); err != nil {
+			return err
+		}
+	}
+	if err := s.enc.end(); err != nil {
+		return err
+	}
+	return s.flush()
+}
+
+// エラーでストリーミングを中断する
+// 書き出し前であれば500（外部APIのレスポンスを扱えない場合は502）を返し、書き出し後であれば接続を切断する
+// 正常な終端を送らないため、クライアントは途中で切れた一覧を成功と誤認しない
+func (s *entryStream) abort(err error) {
+	if !s.started {
+		status := http.StatusInternalServerError
+		if errors.Is(err, errBadUpstream) {
+			status = http.StatusBadGateway
+		}
+		http.Error(s.w, err.Error(), status)
+		return
+	}
+	log.Printf("entries stream aborted after %d entries: %+v", s.count, err)
+	// 書き出し済みの分は送ってから切断する
+	_ = s.flush()
+	panic(http.ErrAbortHandler)
+}
+
+func (s *entryStream) flush() error {
+	if err := s.rc.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,318 @@
+package chapter3
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/dip-dev/go-tutorial/internal/helper/openapi"
+	"github.com/dip-dev/go-tutorial/internal/helper/test"
+)
+
+func TestDecodeEntries(t *testing.T) {
+	success := map[string]struct {
+		body string
+		want []Entry
+	}{
+		"正常ケース：データあり": {
+			body: `[{"name":"案件情報1","user_id":1,"salary":100},{"name":"案件情報2","user_id":2,"salary":200}]`,
+			want: []Entry{
+				{Name: "案件情報1", UserID: 1, Salary: 100},
+				{Name: "案件情報2", UserID: 2, Salary: 200},
+			},
+		},
+		"正常ケース：空の配列": {
+			body: `[]`,
+			want: []Entry{},
+		},
+		"正常ケース：null": {
+			body: `null`,
+			want: []Entry{},
+		},
+	}
+	fail := map[string]struct {
+		body string
+	}{
+		"異常ケース：配列ではない": {
+			body: `{"entries":[]}`,
+		},
+		"異常ケース：JSONではない": {
+			body: `Encoding json is failed`,
+		},
+		"異常ケース：途中で切断された": {
+			body: `[{"name":"案件情報1","user_id":1,"salary":100},`,
+		},
+		"異常ケース：閉じ括弧が無い": {
+			body: `[{"name":"案件情報1","user_id":1,"salary":100}`,
+		},
+	}
+
+	for tn, tc := range success {
+		t.Run(tn, func(t *testing.T) {
+			got := []Entry{}
+			err := decodeEntries(strings.NewReader(tc.body), func(e Entry) error {
+				got = append(got, e)
+				return nil
+			})
+			assert.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+	for tn, tc := range fail {
+		t.Run(tn, func(t *testing.T) {
+			err := decodeEntries(strings.NewReader(tc.body), func(e Entry) error {
+				return nil
+			})
+			assert.Error(t, err)
+		})
+	}
+	t.Run("異常ケース：コールバックのエラーで中断", func(t *testing.T) {
+		stop := errors.New("stop")
+		count := 0
+		err := decodeEntries(strings.NewReader(`[{"name":"1"},{"name":"2"}]`), func(e Entry) error {
+			count++
+			return stop
+		})
+		assert.ErrorIs(t, err, stop)
+		assert.Equal(t, 1, count)
+	})
+}
+
+func TestStreamEntries(t *testing.T) {
+	// 外部APIのモック
+	ts := httptest.NewServer(test.Route(successMockGetEntriesHandler))
+	defer ts.Close()
+
+	// 環境変数を一時的に変更
+	oldURL := os.Getenv("MOCK_API_URL")
+	os.Setenv("MOCK_API_URL", ts.URL)
+	defer os.Setenv("MOCK_API_URL", oldURL)
+
+	t.Run("正常ケース：全件送信後にチャンネルが閉じられる", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		ch := make(chan Entry)
+		errch := make(chan error)
+		go StreamEntries(ctx, ch, errch, map[string][]string{"userID": {"123456", "234567"}})
+
+		got := []Entry{}
+		for done := false; !done; {
+			select {
+			case e, ok := <-ch:
+				if !ok {
+					done = true
+					break
+				}
+				got = append(got, e)
+			case err := <-errch:
+				t.Fatalf("Error is occured : %v", err)
+			}
+		}
+		assert.Len(t, got, 2)
+	})
+	t.Run("正常ケース：受信側が中断しても送信側が止まらない", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+
+		ch := make(chan Entry)
+		errch := make(chan error)
+		finished := make(chan struct{})
+		go func() {
+			StreamEntries(ctx, ch, errch, map[string][]string{"userID": {"123456", "234567"}})
+			close(finished)
+		}()
+
+		<-ch
+		cancel()
+		<-finished
+	})
+}
+
+func TestGetStreaming(t *testing.T) {
+	const total = 10000
+
+	handlers := []test.Handler{
+		{
+			Path: "/entries",
+			Handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				bw := bufio.NewWriter(w)
+				defer bw.Flush()
+				fmt.Fprint(bw, "[")
+				for i := 1; i <= total; i++ {
+					if i > 1 {
+						fmt.Fprint(bw, ",")
+					}
+					fmt.Fprintf(bw, `{"name":"案件情報%d","user_id":%d,"salary":%d}`, i, i, i*1000)
+				}
+				fmt.Fprint(bw, "]")
+			},
+		},
+	}
+	ts := httptest.NewServer(test.Route(handlers...))
+	defer ts.Close()
+
+	// 環境変数を一時的に変更
+	oldURL := os.Getenv("MOCK_API_URL")
+	os.Setenv("MOCK_API_URL", ts.URL)
+	defer os.Setenv("MOCK_API_URL", oldURL)
+
+	t.Run("正常ケース：大量のデータを逐次書き出す", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "http://localhost/?user_id=1&min_salary=5000000", nil)
+		r.Header.Set("Accept", "application/x-ndjson")
+		openapi.Check(t, "/v2/entries", GetWithFormat(EntryFormatV2))(w, r)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.True(t, w.Flushed)
+		lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+		assert.Len(t, lines, total-5000+1)
+		var last EntryV2
+		assert.NoError(t, json.Unmarshal([]byte(lines[len(lines)-1]), &last))
+		assert.Equal(t, total, last.UserID)
+	})
+}
+
+func TestGetStreamingFailure(t *testing.T) {
+	handlers := []test.Handler{
+		{
+			Path: "/entries",
+			Handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				fmt.Fprint(w, `[{"name":"案件情報1","user_id":123456,"salary":123456},{"name":"案件`)
+				w.(http.Flusher).Flush()
+				// 途中で接続を切断する
+				panic(http.ErrAbortHandler)
+			},
+		},
+	}
+	ts := httptest.NewServer(test.Route(handlers...))
+	defer ts.Close()
+
+	// 環境変数を一時的に変更
+	oldURL := os.Getenv("MOCK_API_URL")
+	os.Setenv("MOCK_API_URL", ts.URL)
+	defer os.Setenv("MOCK_API_URL", oldURL)
+
+	t.Run("異常ケース：ストリーミング中に外部APIが失敗", func(t *testing.T) {
+		srv := httptest.NewServer(GetWithFormat(EntryFormatV2))
+		defer srv.Close()
+
+		res, err := http.Get(srv.URL + "/?user_id=123456")
+		if !assert.NoError(t, err) {
+			return
+		}
+		defer res.Body.Close()
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		// 受信済みの1件は書き出されるが、接続が切断されるため途中で切れたことがわかる
+		body, err := io.ReadAll(res.Body)
+		assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+		assert.Equal(t, `{"entries":[{"name":"案件情報1","user_id":123456,"salary":123456}`, string(body))
+	})
+	t.Run("異常ケース：ソート指定時は書き出し前に失敗する", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "http://localhost/?user_id=123456&sort=name", nil)
+		openapi.Check(t, "/v2/entries", GetWithFormat(EntryFormatV2))(w, r)
+
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+	})
+}
+
+func TestGetUpstreamResponse(t *testing.T) {
+	entries := `[{"name":"案件情報1","user_id":123456,"salary":123456}]`
+
+	success := map[string]struct {
+		contentType string
+	}{
+		"正常ケース：JSON": {
+			contentType: "application/json; charset=utf-8",
+		},
+		"正常ケース：+json": {
+			contentType: "application/vnd.dip+json",
+		},
+		"正常ケース：Content-Typeなし": {
+			contentType: "",
+		},
+	}
+	for tn, tc := range success {
+		t.Run(tn, func(t *testing.T) {
+			ts := httptest.NewServer(test.Route(test.Handler{
+				Path: "/entries",
+				Handler: func(w http.ResponseWriter, r *http.Request) {
+					// nilを設定するとContent-Typeの自動判定も行わない
+					w.Header()["Content-Type"] = nil
+					if tc.contentType != "" {
+						w.Header().Set("Content-Type", tc.contentType)
+					}
+					fmt.Fprint(w, entries)
+				},
+			}))
+			defer ts.Close()
+			t.Setenv("MOCK_API_URL", ts.URL)
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "http://localhost/?user_id=123456", nil)
+			openapi.Check(t, "/v2/entries", GetWithFormat(EntryFormatV2))(w, r)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, `{"entries":`+entries+"}\n", w.Body.String())
+		})
+	}
+
+	fail := map[string]struct {
+		contentType string
+		status      int
+		body        string
+		wantError   string
+	}{
+		"異常ケース：JSON以外の形式": {
+			contentType: "text/plain; charset=utf-8",
+			status:      http.StatusOK,
+			body:        entries,
+			wantError:   "unsupported Content-Type",
+		},
+		"異常ケース：XML": {
+			contentType: "application/xml",
+			status:      http.StatusOK,
+			body:        `<entries></entries>`,
+			wantError:   "unsupported Content-Type",
+		},
+		"異常ケース：外部APIがエラーを返す": {
+			contentType: "application/json",
+			status:      http.StatusServiceUnavailable,
+			body:        `{"message":"unavailable"}`,
+			wantError:   "unexpected status 503",
+		},
+	}
+	for tn, tc := range fail {
+		t.Run(tn, func(t *testing.T) {
+			ts := httptest.NewServer(test.Route(test.Handler{
+				Path: "/entries",
+				Handler: func(w http.ResponseWriter, r *http.Request) {
+					w.Header().Set("Content-Type", tc.contentType)
+					w.WriteHeader(tc.status)
+					fmt.Fprint(w, tc.body)
+				},
+			}))
+			defer ts.Close()
+			t.Setenv("MOCK_API_URL", ts.URL)
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "http://localhost/?user_id=123456", nil)
+			openapi.Check(t, "/v2/entries", GetWithFormat(EntryFormatV2))(w, r)
+
+			assert.Equal(t, http.StatusBadGateway, w.Code)
+			assert.Contains(t, w.Body.String(), tc.wantError)
+		})
+	}
+}
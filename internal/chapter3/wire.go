@@ -0,0 +1,125 @@
+package chapter3
+
+import (
+	"encoding/json"
+	"os"
+	"strconv"
+)
+
+// mock-apiの案件情報
+// mock-apiはsnake_caseで返すため、キーを明示する
+type upstreamEntry struct {
+	Name   string `json:"name"`
+	UserID int    `json:"user_id"`
+	Salary int    `json:"salary"`
+}
+
+func (u upstreamEntry) toEntry() Entry {
+	return Entry{Name: u.Name, UserID: u.UserID, Salary: u.Salary}
+}
+
+func newUpstreamEntry(e Entry) upstreamEntry {
+	return upstreamEntry{Name: e.Name, UserID: e.UserID, Salary: e.Salary}
+}
+
+// レスポンスの案件情報（v1）
+// JSONタグを付ける前のPascalCaseの形式
+type EntryV1 struct {
+	Name   string `json:"Name"`
+	UserID int    `json:"UserID"`
+	Salary int    `json:"Salary"`
+}
+
+// レスポンスの案件情報（v2）
+// READMEとmock-apiに合わせたsnake_caseの形式
+type EntryV2 struct {
+	Name   string `json:"name"`
+	UserID int    `json:"user_id"`
+	Salary int    `json:"salary"`
+}
+
+// レスポンスの案件情報の形式
+type EntryFormat int
+
+const (
+	EntryFormatV1 EntryFormat = iota + 1
+	EntryFormatV2
+)
+
+// falseの場合はsnake_case（v2）の形式を既定にする
+// 移行を告知するまではPascalCase（v1）を既定とし、snake_caseはv2を指定した場合のみ返す
+const legacyEntryFormatEnv = "ENTRIES_LEGACY_PASCAL_CASE"
+
+// 既定のレスポンス形式
+func DefaultEntryFormat() EntryFormat {
+	if legacy, err := strconv.ParseBool(os.Getenv(legacyEntryFormatEnv)); err == nil && !legacy {
+		return EntryFormatV2
+	}
+	return EntryFormatV1
+}
+
+// 項目名（v2の形式）とv1の形式のキーの対応
+var entryFieldNamesV1 = map[string]string{
+	"name":    "Name",
+	"user_id": "UserID",
+	"salary":  "Salary",
+}
+
+// 項目名（v2の形式）をレスポンスの形式のキーに変換する
+// 検証エラーの項目名をリクエストのキーに合わせるために使う
+func (f EntryFormat) fieldName(name string) string {
+	if f == EntryFormatV1 {
+		if key, ok := entryFieldNamesV1[name]; ok {
+			return key
+		}
+	}
+	return name
+}
+
+// レスポンスの形式のキーを項目名（v2の形式）に変換する
+// 形式に無いキーの場合はfalseを返す
+func (f EntryFormat) parseFieldName(key string) (string, bool) {
+	if f != EntryFormatV1 {
+		_, ok := entryFieldNamesV1[key]
+		return key, ok
+	}
+	for name, v1 := range entryFieldNamesV1 {
+		if v1 == key {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// 項目の指定やCSVのヘッダーに使う構造体
+func (f EntryFormat) model() any {
+	if f == EntryFormatV1 {
+		return EntryV1{}
+	}
+	return EntryV2{}
+}
+
+// 案件情報をレスポンスの形式に変換する
+func (f EntryFormat) wire(e Entry) any {
+	if f == EntryFormatV1 {
+		return EntryV1{Name: e.Name, UserID: e.UserID, Salary: e.Salary}
+	}
+	return EntryV2{Name: e.Name, UserID: e.UserID, Salary: e.Salary}
+}
+
+// リクエストボディの案件情報を読み込む
+// 登録時もレスポンスと同じ形式のキーで受け付ける
+func (f EntryFormat) decode(b []byte) (Entry, error) {
+	if f == EntryFormatV1 {
+		var v EntryV1
+		if err := json.Unmarshal(b, &v); err != nil {
+			return Entry{}, err
+		}
+		return Entry{Name: v.Name, UserID: v.UserID, Salary: v.Salary}, nil
+	}
+	var v EntryV2
+	if err := json.Unmarshal(b, &v); err != nil {
+		return Entry{}, err
+	}
+	return Entry{Name: v.Name, UserID: v.UserID, Salary: v.Salary}, nil
+}
@@ -0,0 +1,85 @@
+package chapter3
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefaultEntryFormat(t *testing.T) {
+	success := map[string]struct {
+		env  string
+		want EntryFormat
+	}{
+		"正常ケース：未設定":   {env: "", want: EntryFormatV1},
+		"正常ケース：互換モード": {env: "true", want: EntryFormatV1},
+		"正常ケース：移行後":   {env: "false", want: EntryFormatV2},
+		"正常ケース：不正な値":  {env: "snake", want: EntryFormatV1},
+	}
+	for tn, tc := range success {
+		t.Run(tn, func(t *testing.T) {
+			t.Setenv(legacyEntryFormatEnv, tc.env)
+			assert.Equal(t, tc.want, DefaultEntryFormat())
+		})
+	}
+}
+
+func TestEntryFieldName(t *testing.T) {
+	success := map[string]struct {
+		format EntryFormat
+		name   string
+		key    string
+	}{
+		"正常ケース：v1": {format: EntryFormatV1, name: "user_id", key: "UserID"},
+		"正常ケース：v2": {format: EntryFormatV2, name: "user_id", key: "user_id"},
+		"正常ケース：給与": {format: EntryFormatV1, name: "salary", key: "Salary"},
+	}
+	for tn, tc := range success {
+		t.Run(tn, func(t *testing.T) {
+			assert.Equal(t, tc.key, tc.format.fieldName(tc.name))
+			name, ok := tc.format.parseFieldName(tc.key)
+			assert.True(t, ok)
+			assert.Equal(t, tc.name, name)
+		})
+	}
+
+	fail := map[string]struct {
+		format EntryFormat
+		key    string
+	}{
+		"異常ケース：v1でv2のキー": {format: EntryFormatV1, key: "user_id"},
+		"異常ケース：v2でv1のキー": {format: EntryFormatV2, key: "UserID"},
+		"異常ケース：存在しない項目":  {format: EntryFormatV2, key: "age"},
+	}
+	for tn, tc := range fail {
+		t.Run(tn, func(t *testing.T) {
+			_, ok := tc.format.parseFieldName(tc.key)
+			assert.False(t, ok)
+		})
+	}
+}
+
+func TestEntryWire(t *testing.T) {
+	t.Run("正常ケース：mock-apiのsnake_caseを読み込む", func(t *testing.T) {
+		var u upstreamEntry
+		assert.NoError(t, json.Unmarshal([]byte(`{"name":"案件情報1","user_id":123456,"salary":100}`), &u))
+		assert.Equal(t, Entry{Name: "案件情報1", UserID: 123456, Salary: 100}, u.toEntry())
+	})
+
+	e := Entry{Name: "案件情報1", UserID: 123456, Salary: 100}
+	success := map[string]struct {
+		format EntryFormat
+		want   string
+	}{
+		"正常ケース：v1": {format: EntryFormatV1, want: `{"Name":"案件情報1","UserID":123456,"Salary":100}`},
+		"正常ケース：v2": {format: EntryFormatV2, want: `{"name":"案件情報1","user_id":123456,"salary":100}`},
+	}
+	for tn, tc := range success {
+		t.Run(tn, func(t *testing.T) {
+			b, err := json.Marshal(tc.format.wire(e))
+			assert.NoError(t, err)
+			assert.JSONEq(t, tc.want, string(b))
+		})
+	}
+}
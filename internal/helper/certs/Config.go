@@ -0,0 +1,79 @@
+package certs
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// CA証明書（PEM）のファイルから証明書プールを作成する
+func LoadCertPool(files ...string) (*x509.CertPool, error) {
+	pool := x509.NewCertPool()
+	for _, name := range files {
+		b, err := os.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+		if !pool.AppendCertsFromPEM(b) {
+			return nil, fmt.Errorf("%s: no certificates found", name)
+		}
+	}
+	return pool, nil
+}
+
+// サーバーのTLSの設定
+type ServerConfig struct {
+	// サーバー証明書と秘密鍵（PEM）のファイル
+	CertFile string
+	KeyFile  string
+	// クライアント証明書を検証するCA証明書のファイル
+	// 指定した場合はクライアント証明書を必須にする（mTLS）
+	ClientCAFile string
+	// 許可するTLSの最小バージョン（0の場合はTLS 1.2）
+	MinVersion uint16
+}
+
+// tls.Configを作成する
+// 証明書はファイルが更新されると読み込み直す
+func (c ServerConfig) TLSConfig(options ...ReloaderOption) (*tls.Config, error) {
+	if c.CertFile == "" || c.KeyFile == "" {
+		return nil, errors.New("certificate and key files are required")
+	}
+	reloader, err := NewReloader(c.CertFile, c.KeyFile, options...)
+	if err != nil {
+		return nil, err
+	}
+	config := &tls.Config{
+		GetCertificate: reloader.GetCertificate,
+		MinVersion:     c.MinVersion,
+	}
+	if config.MinVersion == 0 {
+		config.MinVersion = tls.VersionTLS12
+	}
+	if c.ClientCAFile != "" {
+		pool, err := LoadCertPool(c.ClientCAFile)
+		if err != nil {
+			return nil, err
+		}
+		config.ClientCAs = pool
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return config, nil
+}
+
+// TLSのバージョン名（1.2, 1.3）を定数に変換する
+func ParseVersion(s string) (uint16, error) {
+	switch s {
+	case "1.0":
+		return tls.VersionTLS10, nil
+	case "1.1":
+		return tls.VersionTLS11, nil
+	case "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	}
+	return 0, fmt.Errorf("unsupported TLS version: %q", s)
+}
@@ -0,0 +1,137 @@
+package certs
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/dip-dev/go-tutorial/internal/helper/test"
+)
+
+func TestLoadCertPool(t *testing.T) {
+	ca := test.NewCertificateAuthority(t)
+
+	t.Run("正常ケース：CA証明書を読み込む", func(t *testing.T) {
+		pool, err := LoadCertPool(ca.CertFile)
+		assert.NoError(t, err)
+		assert.NotNil(t, pool)
+	})
+	t.Run("異常ケース：証明書が含まれない", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "empty.pem")
+		assert.NoError(t, os.WriteFile(path, []byte("not a certificate"), 0o600))
+		_, err := LoadCertPool(path)
+		assert.Error(t, err)
+	})
+	t.Run("異常ケース：ファイルが存在しない", func(t *testing.T) {
+		_, err := LoadCertPool(filepath.Join(t.TempDir(), "missing.pem"))
+		assert.Error(t, err)
+	})
+}
+
+func TestServerConfig(t *testing.T) {
+	ca := test.NewCertificateAuthority(t)
+	server := ca.IssueServer(t, "server", "localhost")
+	client := ca.IssueClient(t, "client")
+
+	// 設定したTLSで待ち受けるサーバー
+	start := func(t *testing.T, config ServerConfig) *httptest.Server {
+		t.Helper()
+		tlsConfig, err := config.TLSConfig()
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if len(r.TLS.PeerCertificates) > 0 {
+				w.Header().Set("X-Client", r.TLS.PeerCertificates[0].Subject.CommonName)
+			}
+		}))
+		ts.TLS = tlsConfig
+		ts.StartTLS()
+		t.Cleanup(ts.Close)
+		return ts
+	}
+	// CAを信頼し、SNIでlocalhostを指定するクライアント
+	newClient := func(t *testing.T, certs ...tls.Certificate) *http.Client {
+		t.Helper()
+		pool, err := LoadCertPool(ca.CertFile)
+		assert.NoError(t, err)
+		return &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+			RootCAs:      pool,
+			ServerName:   "localhost",
+			Certificates: certs,
+		}}}
+	}
+
+	t.Run("正常ケース：HTTPS", func(t *testing.T) {
+		ts := start(t, ServerConfig{CertFile: server.CertFile, KeyFile: server.KeyFile})
+
+		res, err := newClient(t).Get(ts.URL)
+		if !assert.NoError(t, err) {
+			return
+		}
+		defer res.Body.Close()
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, uint16(tls.VersionTLS13), res.TLS.Version)
+	})
+	t.Run("正常ケース：mTLS", func(t *testing.T) {
+		ts := start(t, ServerConfig{CertFile: server.CertFile, KeyFile: server.KeyFile, ClientCAFile: ca.CertFile})
+		cert, err := tls.LoadX509KeyPair(client.CertFile, client.KeyFile)
+		assert.NoError(t, err)
+
+		res, err := newClient(t, cert).Get(ts.URL)
+		if !assert.NoError(t, err) {
+			return
+		}
+		defer res.Body.Close()
+		assert.Equal(t, "client", res.Header.Get("X-Client"))
+	})
+	t.Run("異常ケース：mTLSでクライアント証明書がない", func(t *testing.T) {
+		ts := start(t, ServerConfig{CertFile: server.CertFile, KeyFile: server.KeyFile, ClientCAFile: ca.CertFile})
+
+		res, err := newClient(t).Get(ts.URL)
+		if err == nil {
+			res.Body.Close()
+		}
+		assert.Error(t, err)
+	})
+
+	fail := map[string]struct {
+		config ServerConfig
+	}{
+		"異常ケース：証明書の指定がない":       {config: ServerConfig{KeyFile: server.KeyFile}},
+		"異常ケース：証明書と秘密鍵が一致しない":   {config: ServerConfig{CertFile: server.CertFile, KeyFile: client.KeyFile}},
+		"異常ケース：クライアントのCA証明書がない": {config: ServerConfig{CertFile: server.CertFile, KeyFile: server.KeyFile, ClientCAFile: "missing.pem"}},
+	}
+	for tn, tc := range fail {
+		t.Run(tn, func(t *testing.T) {
+			_, err := tc.config.TLSConfig()
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestParseVersion(t *testing.T) {
+	success := map[string]struct {
+		in   string
+		want uint16
+	}{
+		"正常ケース：1.2": {in: "1.2", want: tls.VersionTLS12},
+		"正常ケース：1.3": {in: "1.3", want: tls.VersionTLS13},
+	}
+	for tn, tc := range success {
+		t.Run(tn, func(t *testing.T) {
+			got, err := ParseVersion(tc.in)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+	t.Run("異常ケース：未対応のバージョン", func(t *testing.T) {
+		_, err := ParseVersion("TLS1.3")
+		assert.Error(t, err)
+	})
+}
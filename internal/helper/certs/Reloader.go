@@ -0,0 +1,120 @@
+package certs
+
+import (
+	"crypto/tls"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// ファイルの更新を確認する間隔の既定値
+const defaultCheckInterval = 10 * time.Second
+
+// 証明書と秘密鍵のファイルを読み込み、更新されたら読み込み直す
+// 証明書を更新してもサーバーを再起動せずに反映できる
+type Reloader struct {
+	certFile string
+	keyFile  string
+	interval time.Duration
+	now      func() time.Time
+
+	mu        sync.Mutex
+	cert      *tls.Certificate
+	modTime   time.Time
+	checkedAt time.Time
+}
+
+// Reloaderのオプション
+type ReloaderOption func(r *Reloader)
+
+// ファイルの更新を確認する間隔を指定するオプション
+// 0を指定するとハンドシェイクのたびに確認する
+func WithCheckInterval(d time.Duration) ReloaderOption {
+	return func(r *Reloader) {
+		r.interval = d
+	}
+}
+
+// Reloaderの初期化処理
+// 最初の読み込みに失敗した場合はエラーを返す
+func NewReloader(certFile, keyFile string, options ...ReloaderOption) (*Reloader, error) {
+	r := &Reloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+		interval: defaultCheckInterval,
+		now:      time.Now,
+	}
+	for _, option := range options {
+		option(r)
+	}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// ファイルを読み込み直す
+func (r *Reloader) Reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.load()
+}
+
+func (r *Reloader) load() error {
+	modTime, err := r.latestModTime()
+	if err != nil {
+		return err
+	}
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return err
+	}
+	r.cert = &cert
+	r.modTime = modTime
+	r.checkedAt = r.now()
+	return nil
+}
+
+// 証明書と秘密鍵のうち、新しい方の更新日時
+func (r *Reloader) latestModTime() (time.Time, error) {
+	var latest time.Time
+	for _, name := range []string{r.certFile, r.keyFile} {
+		info, err := os.Stat(name)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
+
+// 現在の証明書を返す
+// 確認の間隔が過ぎていればファイルの更新を確認し、更新されていれば読み込み直す
+// 読み込みに失敗した場合（書き込み途中など）は前回の証明書を使い続ける
+func (r *Reloader) Certificate() *tls.Certificate {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.now().Sub(r.checkedAt) < r.interval {
+		return r.cert
+	}
+	r.checkedAt = r.now()
+	if modTime, err := r.latestModTime(); err == nil && !modTime.Equal(r.modTime) {
+		if err := r.load(); err != nil {
+			log.Printf("failed to reload certificate %s: %+v", r.certFile, err)
+		}
+	}
+	return r.cert
+}
+
+// tls.ConfigのGetCertificateに指定する（サーバー用）
+func (r *Reloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return r.Certificate(), nil
+}
+
+// tls.ConfigのGetClientCertificateに指定する（mTLSのクライアント用）
+func (r *Reloader) GetClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	return r.Certificate(), nil
+}
@@ -0,0 +1,85 @@
+package certs
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/dip-dev/go-tutorial/internal/helper/test"
+)
+
+func commonName(t *testing.T, cert *tls.Certificate) string {
+	t.Helper()
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if !assert.NoError(t, err) {
+		return ""
+	}
+	return leaf.Subject.CommonName
+}
+
+// 発行した証明書で既存のファイルを上書きする
+func replaceFiles(t *testing.T, dst, src test.CertificateFiles, modTime time.Time) {
+	t.Helper()
+	for _, p := range [][2]string{{src.CertFile, dst.CertFile}, {src.KeyFile, dst.KeyFile}} {
+		b, err := os.ReadFile(p[0])
+		assert.NoError(t, err)
+		assert.NoError(t, os.WriteFile(p[1], b, 0o600))
+		assert.NoError(t, os.Chtimes(p[1], modTime, modTime))
+	}
+}
+
+func TestReloader(t *testing.T) {
+	ca := test.NewCertificateAuthority(t)
+
+	t.Run("正常ケース：ファイルが更新されたら読み込み直す", func(t *testing.T) {
+		files := ca.IssueServer(t, "old", "localhost")
+		r, err := NewReloader(files.CertFile, files.KeyFile, WithCheckInterval(0))
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, "old", commonName(t, r.Certificate()))
+
+		replaceFiles(t, files, ca.IssueServer(t, "new", "localhost"), time.Now().Add(time.Minute))
+
+		cert, err := r.GetCertificate(&tls.ClientHelloInfo{})
+		assert.NoError(t, err)
+		assert.Equal(t, "new", commonName(t, cert))
+	})
+	t.Run("正常ケース：確認の間隔が過ぎるまでは読み込まない", func(t *testing.T) {
+		files := ca.IssueServer(t, "old", "localhost")
+		r, err := NewReloader(files.CertFile, files.KeyFile, WithCheckInterval(time.Hour))
+		if !assert.NoError(t, err) {
+			return
+		}
+		now := time.Now()
+		r.now = func() time.Time { return now }
+
+		replaceFiles(t, files, ca.IssueServer(t, "new", "localhost"), time.Now().Add(time.Minute))
+		assert.Equal(t, "old", commonName(t, r.Certificate()))
+
+		now = now.Add(time.Hour)
+		assert.Equal(t, "new", commonName(t, r.Certificate()))
+	})
+	t.Run("正常ケース：読み込みに失敗した場合は前回の証明書を使う", func(t *testing.T) {
+		files := ca.IssueClient(t, "client")
+		r, err := NewReloader(files.CertFile, files.KeyFile, WithCheckInterval(0))
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		assert.NoError(t, os.WriteFile(files.CertFile, []byte("broken"), 0o600))
+		assert.NoError(t, os.Chtimes(files.CertFile, time.Now().Add(time.Minute), time.Now().Add(time.Minute)))
+
+		cert, err := r.GetClientCertificate(&tls.CertificateRequestInfo{})
+		assert.NoError(t, err)
+		assert.Equal(t, "client", commonName(t, cert))
+	})
+	t.Run("異常ケース：ファイルが存在しない", func(t *testing.T) {
+		_, err := NewReloader("missing.pem", "missing-key.pem")
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,37 @@
+package etag
+
+import "strings"
+
+// If-Matchの値にETagが含まれるか（強い比較）
+func IfMatch(header, etag string) bool {
+	return match(header, etag, false)
+}
+
+// If-None-Matchの値にETagが含まれるか（弱い比較）
+func IfNoneMatch(header, etag string) bool {
+	return match(header, etag, true)
+}
+
+// ヘッダーの値にETagが含まれるか
+// 弱い比較の場合はW/の有無を区別しない
+func match(header, etag string, weak bool) bool {
+	if header == "" {
+		return false
+	}
+	if strings.TrimSpace(header) == "*" {
+		return true
+	}
+	for _, v := range strings.Split(header, ",") {
+		v = strings.TrimSpace(v)
+		if strings.HasPrefix(v, "W/") {
+			if !weak {
+				continue
+			}
+			v = strings.TrimPrefix(v, "W/")
+		}
+		if v == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,77 @@
+package etag
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIfMatch(t *testing.T) {
+	success := map[string]struct {
+		header string
+		want   bool
+	}{
+		"正常ケース：一致": {
+			header: `"abc"`,
+			want:   true,
+		},
+		"正常ケース：複数のうちいずれかに一致": {
+			header: `"xyz", "abc"`,
+			want:   true,
+		},
+		"正常ケース：ワイルドカード": {
+			header: "*",
+			want:   true,
+		},
+		"正常ケース：弱いETagは一致しない": {
+			header: `W/"abc"`,
+			want:   false,
+		},
+		"正常ケース：一致しない": {
+			header: `"xyz"`,
+			want:   false,
+		},
+		"正常ケース：ヘッダーなし": {
+			header: "",
+			want:   false,
+		},
+	}
+	for tn, tc := range success {
+		t.Run(tn, func(t *testing.T) {
+			assert.Equal(t, tc.want, IfMatch(tc.header, `"abc"`))
+		})
+	}
+}
+
+func TestIfNoneMatch(t *testing.T) {
+	success := map[string]struct {
+		header string
+		want   bool
+	}{
+		"正常ケース：一致": {
+			header: `"abc"`,
+			want:   true,
+		},
+		"正常ケース：弱いETagも一致": {
+			header: `"xyz", W/"abc"`,
+			want:   true,
+		},
+		"正常ケース：ワイルドカード": {
+			header: " * ",
+			want:   true,
+		},
+		"正常ケース：一致しない": {
+			header: `W/"xyz"`,
+			want:   false,
+		},
+		"正常ケース：ヘッダーなし": {
+			header: "",
+			want:   false,
+		},
+	}
+	for tn, tc := range success {
+		t.Run(tn, func(t *testing.T) {
+			assert.Equal(t, tc.want, IfNoneMatch(tc.header, `"abc"`))
+		})
+	}
+}
@@ -0,0 +1,226 @@
+package fieldset
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode"
+)
+
+// レスポンスに含める項目の集合
+// 値が空の場合は全ての項目を含める
+type Fields struct {
+	fields []field
+}
+
+// 構造体のフィールドとJSONのキーの対応
+type field struct {
+	index int
+	key   string
+}
+
+// 存在しない項目が指定された場合のエラー
+type UnknownFieldError struct {
+	Fields  []string
+	Allowed []string
+}
+
+func (e *UnknownFieldError) Error() string {
+	return fmt.Sprintf("unknown fields: %s (allowed: %s)", strings.Join(e.Fields, ","), strings.Join(e.Allowed, ","))
+}
+
+// カンマ区切りの項目名をmodelの構造体に照らして解析する
+// 項目名はJSONのキー、Goのフィールド名、スネークケースのいずれでも指定できる
+func Parse(raw string, model any) (Fields, error) {
+	if strings.TrimSpace(raw) == "" {
+		return Fields{}, nil
+	}
+	t := reflect.TypeOf(model)
+	for t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return Fields{}, fmt.Errorf("fieldset: model must be a struct, got %s", t)
+	}
+	candidates := structFields(t)
+
+	var f Fields
+	var unknown []string
+	seen := map[int]bool{}
+	for _, name := range strings.Split(raw, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		c, ok := lookup(candidates, t, name)
+		if !ok {
+			unknown = append(unknown, name)
+			continue
+		}
+		if seen[c.index] {
+			continue
+		}
+		seen[c.index] = true
+		f.fields = append(f.fields, c)
+	}
+	if len(unknown) > 0 {
+		allowed := make([]string, 0, len(candidates))
+		for _, c := range candidates {
+			allowed = append(allowed, c.key)
+		}
+		return Fields{}, &UnknownFieldError{Fields: unknown, Allowed: allowed}
+	}
+	return f, nil
+}
+
+// modelの全ての項目を含む集合を返す
+func All(model any) Fields {
+	t := reflect.TypeOf(model)
+	for t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return Fields{}
+	}
+	return Fields{fields: structFields(t)}
+}
+
+// 項目の指定がないか
+func (f Fields) Empty() bool {
+	return len(f.fields) == 0
+}
+
+// 選択された項目のJSONのキー
+func (f Fields) Keys() []string {
+	keys := make([]string, 0, len(f.fields))
+	for _, c := range f.fields {
+		keys = append(keys, c.key)
+	}
+	return keys
+}
+
+// 構造体、または構造体のスライスを選択された項目だけに絞り込む
+// 項目の指定がない場合はそのまま返す
+func (f Fields) Project(v any) any {
+	if f.Empty() {
+		return v
+	}
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return v
+		}
+		rv = rv.Elem()
+	}
+	switch rv.Kind() {
+	case reflect.Struct:
+		return f.object(rv)
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() == reflect.Slice && rv.IsNil() {
+			return v
+		}
+		objects := make([]Object, 0, rv.Len())
+		for i := 0; i < rv.Len(); i++ {
+			e := rv.Index(i)
+			for e.Kind() == reflect.Pointer {
+				e = e.Elem()
+			}
+			objects = append(objects, f.object(e))
+		}
+		return objects
+	}
+	return v
+}
+
+func (f Fields) object(rv reflect.Value) Object {
+	o := make(Object, 0, len(f.fields))
+	for _, c := range f.fields {
+		o = append(o, Member{Key: c.key, Value: rv.Field(c.index).Interface()})
+	}
+	return o
+}
+
+// 項目の順序を保持したJSONオブジェクト
+type Object []Member
+
+// JSONオブジェクトの要素
+type Member struct {
+	Key   string
+	Value any
+}
+
+func (o Object) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, m := range o {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(m.Key)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		value, err := json.Marshal(m.Value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// JSONとして出力される構造体のフィールドを列挙する
+func structFields(t reflect.Type) []field {
+	var fields []field
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		key := sf.Name
+		if tag, ok := sf.Tag.Lookup("json"); ok {
+			name, _, _ := strings.Cut(tag, ",")
+			if name == "-" {
+				continue
+			}
+			if name != "" {
+				key = name
+			}
+		}
+		fields = append(fields, field{index: i, key: key})
+	}
+	return fields
+}
+
+func lookup(candidates []field, t reflect.Type, name string) (field, bool) {
+	for _, c := range candidates {
+		goName := t.Field(c.index).Name
+		if c.key == name || strings.EqualFold(goName, name) || snakeCase(goName) == name {
+			return c, true
+		}
+	}
+	return field{}, false
+}
+
+// Goのフィールド名をスネークケースに変換する (例: UserID -> user_id)
+func snakeCase(s string) string {
+	rs := []rune(s)
+	var b strings.Builder
+	for i, r := range rs {
+		if unicode.IsUpper(r) {
+			prevLower := i > 0 && !unicode.IsUpper(rs[i-1])
+			nextLower := i > 0 && i+1 < len(rs) && unicode.IsLower(rs[i+1])
+			if prevLower || nextLower {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
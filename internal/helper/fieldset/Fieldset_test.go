@@ -0,0 +1,98 @@
+package fieldset
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type entry struct {
+	Name   string
+	UserID int `json:"user_id"`
+	Salary int
+	Secret string `json:"-"`
+}
+
+func TestParse(t *testing.T) {
+	success := map[string]struct {
+		raw  string
+		want []string
+	}{
+		"正常ケース：指定なし": {
+			raw:  "",
+			want: []string{},
+		},
+		"正常ケース：JSONのキーで指定": {
+			raw:  "user_id,Salary",
+			want: []string{"user_id", "Salary"},
+		},
+		"正常ケース：フィールド名・小文字で指定": {
+			raw:  "name, userid",
+			want: []string{"Name", "user_id"},
+		},
+		"正常ケース：重複した指定": {
+			raw:  "name,Name",
+			want: []string{"Name"},
+		},
+	}
+	fail := map[string]struct {
+		raw   string
+		model any
+	}{
+		"異常ケース：存在しない項目": {
+			raw:   "name,age",
+			model: entry{},
+		},
+		"異常ケース：JSONに出力されない項目": {
+			raw:   "secret",
+			model: entry{},
+		},
+		"異常ケース：構造体ではない": {
+			raw:   "name",
+			model: "entry",
+		},
+	}
+
+	for tn, tc := range success {
+		t.Run(tn, func(t *testing.T) {
+			got, err := Parse(tc.raw, []entry{})
+			assert.NoError(t, err)
+			assert.Equal(t, tc.want, got.Keys())
+		})
+	}
+	for tn, tc := range fail {
+		t.Run(tn, func(t *testing.T) {
+			_, err := Parse(tc.raw, tc.model)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestAll(t *testing.T) {
+	assert.Equal(t, []string{"Name", "user_id", "Salary"}, All([]entry{}).Keys())
+	assert.True(t, All("entry").Empty())
+}
+
+func TestProject(t *testing.T) {
+	entries := []entry{
+		{Name: "案件情報1", UserID: 1, Salary: 100},
+		{Name: "案件情報2", UserID: 2, Salary: 200},
+	}
+
+	t.Run("正常ケース：スライスを絞り込む", func(t *testing.T) {
+		f, _ := Parse("salary,name", entry{})
+		got, err := json.Marshal(f.Project(entries))
+		assert.NoError(t, err)
+		assert.Equal(t, `[{"Salary":100,"Name":"案件情報1"},{"Salary":200,"Name":"案件情報2"}]`, string(got))
+	})
+	t.Run("正常ケース：構造体のポインタを絞り込む", func(t *testing.T) {
+		f, _ := Parse("user_id", entry{})
+		got, err := json.Marshal(f.Project(&entries[0]))
+		assert.NoError(t, err)
+		assert.Equal(t, `{"user_id":1}`, string(got))
+	})
+	t.Run("正常ケース：指定なしの場合はそのまま返す", func(t *testing.T) {
+		assert.Equal(t, entries, Fields{}.Project(entries))
+	})
+}
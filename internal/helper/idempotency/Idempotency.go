@@ -0,0 +1,190 @@
+package idempotency
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+)
+
+// リトライ時の重複処理を防ぐためのヘッダー
+const KeyHeader = "Idempotency-Key"
+
+// 保存した結果を返したことを示すヘッダー
+const ReplayedHeader = "Idempotent-Replayed"
+
+const (
+	// 処理結果を保持する期間の既定値
+	defaultTTL = 24 * time.Hour
+	// キーの最大長
+	maxKeyLength = 255
+	// 読み込むリクエストボディの上限の既定値
+	defaultMaxBodySize = 1 << 20
+)
+
+// Idempotency-Keyが指定されたリクエストの処理結果を保存し、
+// 同じキーで再送された場合は処理せずに保存した結果を返すミドルウェア
+type Middleware struct {
+	store       Store
+	ttl         time.Duration
+	maxBodySize int64
+}
+
+// ミドルウェアのオプション
+type Option func(m *Middleware)
+
+// 処理結果を保持する期間を指定するオプション
+func WithTTL(ttl time.Duration) Option {
+	return func(m *Middleware) {
+		m.ttl = ttl
+	}
+}
+
+// 読み込むリクエストボディの上限を指定するオプション
+func WithMaxBodySize(n int64) Option {
+	return func(m *Middleware) {
+		m.maxBodySize = n
+	}
+}
+
+// ミドルウェアの初期化処理
+func New(store Store, options ...Option) *Middleware {
+	m := &Middleware{
+		store:       store,
+		ttl:         defaultTTL,
+		maxBodySize: defaultMaxBodySize,
+	}
+	for _, option := range options {
+		option(m)
+	}
+	return m
+}
+
+// ハンドラにミドルウェアを適用する
+// POSTとPATCH以外、またはキーがないリクエストはそのまま処理する
+func (m *Middleware) Handler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(KeyHeader)
+		if key == "" || (r.Method != http.MethodPost && r.Method != http.MethodPatch) {
+			h.ServeHTTP(w, r)
+			return
+		}
+		if len(key) > maxKeyLength {
+			http.Error(w, "Idempotency-Key is too long", http.StatusBadRequest)
+			return
+		}
+
+		// リクエストボディを読み込み、ハンドラで読み直せるように差し替える
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, m.maxBodySize))
+		if err != nil {
+			var maxErr *http.MaxBytesError
+			if errors.As(err, &maxErr) {
+				http.Error(w, "Request Entity Too Large", http.StatusRequestEntityTooLarge)
+				return
+			}
+			http.Error(w, "Invalid body", http.StatusBadRequest)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		fp := fingerprint(r, body)
+
+		// 同じキーのリクエストが処理中の場合は終わるまで待つ
+		unlock, err := m.store.Lock(r.Context(), key)
+		if err != nil {
+			http.Error(w, "Failed to lock Idempotency-Key", http.StatusServiceUnavailable)
+			return
+		}
+		defer unlock()
+
+		rec, ok, err := m.store.Get(r.Context(), key)
+		if err != nil {
+			http.Error(w, "Failed to load Idempotency-Key", http.StatusInternalServerError)
+			return
+		}
+		if ok {
+			if rec.Fingerprint != fp {
+				http.Error(w, "Idempotency-Key is already used for a different request", http.StatusUnprocessableEntity)
+				return
+			}
+			replay(w, rec.Response)
+			return
+		}
+
+		rw := &recorder{ResponseWriter: w}
+		h.ServeHTTP(rw, r)
+
+		// サーバー側の失敗は再送で成功する可能性があるため保存しない
+		status := rw.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		if status >= http.StatusInternalServerError {
+			return
+		}
+		res := Response{Status: status, Header: rw.header, Body: rw.body.Bytes()}
+		if res.Header == nil {
+			res.Header = w.Header().Clone()
+		}
+		// 保存に失敗してもレスポンスは返却済みのため、次の再送で再び処理される
+		_ = m.store.Set(r.Context(), key, &Record{Fingerprint: fp, Response: res}, m.ttl)
+	})
+}
+
+// ハンドラ関数にミドルウェアを適用する
+func (m *Middleware) HandlerFunc(h http.HandlerFunc) http.HandlerFunc {
+	return m.Handler(h).ServeHTTP
+}
+
+// メソッド・URL・ボディから同じリクエストかを判定する値を計算する
+// ルーターでパスを書き換える場合（バージョンの接頭辞など）に備え、受信した時点のURLを使う
+func fingerprint(r *http.Request, body []byte) string {
+	target := r.RequestURI
+	if target == "" {
+		target = r.URL.RequestURI()
+	}
+	h := sha256.New()
+	_, _ = io.WriteString(h, r.Method+" "+target+"\n")
+	_, _ = h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// 保存したレスポンスを返却する
+func replay(w http.ResponseWriter, res Response) {
+	for k, vs := range res.Header {
+		w.Header()[k] = append([]string(nil), vs...)
+	}
+	w.Header().Set(ReplayedHeader, "true")
+	w.WriteHeader(res.Status)
+	_, _ = w.Write(res.Body)
+}
+
+// レスポンスを書き出しつつ、ステータス・ヘッダー・ボディを保持する
+type recorder struct {
+	http.ResponseWriter
+	status int
+	header http.Header
+	body   bytes.Buffer
+}
+
+func (w *recorder) WriteHeader(status int) {
+	if w.status == 0 && status >= http.StatusOK {
+		w.status = status
+		w.header = w.ResponseWriter.Header().Clone()
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *recorder) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *recorder) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,208 @@
+package idempotency
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// 呼び出し回数を数え、ボディをそのまま返すハンドラ
+func countingHandler(calls *int32, status int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(calls, 1)
+		b, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "text/plain")
+		w.Header().Set("X-Call", strings.Repeat("I", int(n)))
+		w.WriteHeader(status)
+		_, _ = w.Write(b)
+	}
+}
+
+type idempotencyRequest struct {
+	method string
+	path   string
+	key    string
+	body   string
+}
+
+func serve(h http.Handler, req idempotencyRequest) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(req.method, "http://localhost"+req.path, strings.NewReader(req.body))
+	if req.key != "" {
+		r.Header.Set(KeyHeader, req.key)
+	}
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestMiddleware(t *testing.T) {
+	first := idempotencyRequest{method: http.MethodPost, path: "/users", key: "key-1", body: "name=dip"}
+
+	success := map[string]struct {
+		status     int
+		second     idempotencyRequest
+		wantCalls  int32
+		wantStatus int
+		wantReplay bool
+	}{
+		"正常ケース：同じキーの再送は保存した結果を返す": {
+			status:     http.StatusCreated,
+			second:     first,
+			wantCalls:  1,
+			wantStatus: http.StatusCreated,
+			wantReplay: true,
+		},
+		"正常ケース：4xxも保存する": {
+			status:     http.StatusBadRequest,
+			second:     first,
+			wantCalls:  1,
+			wantStatus: http.StatusBadRequest,
+			wantReplay: true,
+		},
+		"正常ケース：5xxは保存しない": {
+			status:     http.StatusBadGateway,
+			second:     first,
+			wantCalls:  2,
+			wantStatus: http.StatusBadGateway,
+		},
+		"正常ケース：キーが異なる": {
+			status:     http.StatusCreated,
+			second:     idempotencyRequest{method: http.MethodPost, path: "/users", key: "key-2", body: "name=dip"},
+			wantCalls:  2,
+			wantStatus: http.StatusCreated,
+		},
+		"正常ケース：キーなし": {
+			status:     http.StatusCreated,
+			second:     idempotencyRequest{method: http.MethodPost, path: "/users", body: "name=dip"},
+			wantCalls:  2,
+			wantStatus: http.StatusCreated,
+		},
+		"正常ケース：POST・PATCH以外は対象外": {
+			status:     http.StatusOK,
+			second:     idempotencyRequest{method: http.MethodPut, path: "/users", key: "key-1", body: "name=dip"},
+			wantCalls:  2,
+			wantStatus: http.StatusOK,
+		},
+	}
+	for tn, tc := range success {
+		t.Run(tn, func(t *testing.T) {
+			var calls int32
+			h := New(NewMemoryStore()).Handler(countingHandler(&calls, tc.status))
+
+			w1 := serve(h, first)
+			w2 := serve(h, tc.second)
+
+			assert.Equal(t, tc.wantCalls, calls)
+			assert.Equal(t, tc.wantStatus, w2.Code)
+			assert.Equal(t, tc.second.body, w2.Body.String())
+			if tc.wantReplay {
+				assert.Equal(t, "true", w2.Header().Get(ReplayedHeader))
+				assert.Equal(t, w1.Header().Get("X-Call"), w2.Header().Get("X-Call"))
+			} else {
+				assert.Empty(t, w2.Header().Get(ReplayedHeader))
+			}
+		})
+	}
+
+	fail := map[string]struct {
+		second     idempotencyRequest
+		options    []Option
+		wantStatus int
+		wantCalls  int32
+	}{
+		"異常ケース：同じキーでボディが異なる": {
+			second:     idempotencyRequest{method: http.MethodPost, path: "/users", key: "key-1", body: "name=other"},
+			wantStatus: http.StatusUnprocessableEntity,
+			wantCalls:  1,
+		},
+		"異常ケース：同じキーでパスが異なる": {
+			second:     idempotencyRequest{method: http.MethodPost, path: "/entries", key: "key-1", body: "name=dip"},
+			wantStatus: http.StatusUnprocessableEntity,
+			wantCalls:  1,
+		},
+		"異常ケース：キーが長すぎる": {
+			second:     idempotencyRequest{method: http.MethodPost, path: "/users", key: strings.Repeat("k", maxKeyLength+1), body: "name=dip"},
+			wantStatus: http.StatusBadRequest,
+			wantCalls:  1,
+		},
+		"異常ケース：ボディが上限を超える": {
+			second:     idempotencyRequest{method: http.MethodPost, path: "/users", key: "key-2", body: strings.Repeat("a", 9)},
+			options:    []Option{WithMaxBodySize(8)},
+			wantStatus: http.StatusRequestEntityTooLarge,
+			wantCalls:  1,
+		},
+	}
+	for tn, tc := range fail {
+		t.Run(tn, func(t *testing.T) {
+			var calls int32
+			h := New(NewMemoryStore(), tc.options...).Handler(countingHandler(&calls, http.StatusCreated))
+
+			serve(h, first)
+			w := serve(h, tc.second)
+
+			assert.Equal(t, tc.wantStatus, w.Code)
+			assert.Equal(t, tc.wantCalls, calls)
+		})
+	}
+}
+
+func TestMiddlewareTTL(t *testing.T) {
+	var calls int32
+	store := NewMemoryStore()
+	now := time.Date(2026, time.October, 1, 0, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+	h := New(store, WithTTL(time.Hour)).Handler(countingHandler(&calls, http.StatusCreated))
+	req := idempotencyRequest{method: http.MethodPost, path: "/users", key: "key-1", body: "name=dip"}
+
+	serve(h, req)
+	now = now.Add(59 * time.Minute)
+	assert.Equal(t, "true", serve(h, req).Header().Get(ReplayedHeader))
+	assert.Equal(t, int32(1), calls)
+
+	// 期限が切れた後は再び処理する
+	now = now.Add(time.Minute)
+	assert.Empty(t, serve(h, req).Header().Get(ReplayedHeader))
+	assert.Equal(t, int32(2), calls)
+}
+
+func TestMiddlewareConcurrent(t *testing.T) {
+	var calls int32
+	release := make(chan struct{})
+	started := make(chan struct{}, 1)
+	h := New(NewMemoryStore()).HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		started <- struct{}{}
+		<-release
+		w.WriteHeader(http.StatusCreated)
+	})
+	req := idempotencyRequest{method: http.MethodPost, path: "/users", key: "key-1", body: "name=dip"}
+
+	const n = 5
+	codes := make([]int, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			codes[i] = serve(h, req).Code
+		}(i)
+	}
+
+	// 最初のリクエストの処理中に他のリクエストはロックを待つ
+	<-started
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), calls)
+	for _, code := range codes {
+		assert.Equal(t, http.StatusCreated, code)
+	}
+}
@@ -0,0 +1,131 @@
+package idempotency
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// 保存したレスポンス
+type Response struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+// Idempotency-Keyごとの処理結果
+type Record struct {
+	// リクエストの内容から計算した値（同じキーで内容が異なるリクエストの検出に使う）
+	Fingerprint string
+	Response    Response
+}
+
+// 処理結果の保存先
+// 複数のインスタンスで共有する場合はRedisなどで実装する
+type Store interface {
+	// キーごとの排他制御を行う
+	// 同じキーのリクエストを同時に処理しないよう、解放されるまで待つ
+	Lock(ctx context.Context, key string) (unlock func(), err error)
+	// 保存した処理結果を取得する
+	Get(ctx context.Context, key string) (*Record, bool, error)
+	// 処理結果を保存する
+	Set(ctx context.Context, key string, rec *Record, ttl time.Duration) error
+}
+
+// メモリ上に保存するStore
+// 1つのプロセス内でのみ有効
+type MemoryStore struct {
+	mu      sync.Mutex
+	records map[string]memoryRecord
+	locks   map[string]*keyLock
+
+	// 現在時刻（テストで差し替える）
+	now func() time.Time
+}
+
+type memoryRecord struct {
+	rec     Record
+	expires time.Time
+}
+
+type keyLock struct {
+	ch   chan struct{}
+	refs int
+}
+
+// MemoryStoreの初期化処理
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		records: map[string]memoryRecord{},
+		locks:   map[string]*keyLock{},
+		now:     time.Now,
+	}
+}
+
+func (s *MemoryStore) Lock(ctx context.Context, key string) (func(), error) {
+	s.mu.Lock()
+	l, ok := s.locks[key]
+	if !ok {
+		l = &keyLock{ch: make(chan struct{}, 1)}
+		s.locks[key] = l
+	}
+	l.refs++
+	s.mu.Unlock()
+
+	select {
+	case l.ch <- struct{}{}:
+	case <-ctx.Done():
+		s.releaseRef(key, l)
+		return nil, ctx.Err()
+	}
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			<-l.ch
+			s.releaseRef(key, l)
+		})
+	}, nil
+}
+
+// 待っているリクエストがなくなったらロックを削除する
+func (s *MemoryStore) releaseRef(key string, l *keyLock) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	l.refs--
+	if l.refs == 0 {
+		delete(s.locks, key)
+	}
+}
+
+func (s *MemoryStore) Get(_ context.Context, key string) (*Record, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.records[key]
+	if !ok {
+		return nil, false, nil
+	}
+	if !s.now().Before(r.expires) {
+		delete(s.records, key)
+		return nil, false, nil
+	}
+	rec := r.rec
+	return &rec, true, nil
+}
+
+func (s *MemoryStore) Set(_ context.Context, key string, rec *Record, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	// 期限切れの結果を削除してから保存する
+	for k, r := range s.records {
+		if !now.Before(r.expires) {
+			delete(s.records, k)
+		}
+	}
+	s.records[key] = memoryRecord{rec: *rec, expires: now.Add(ttl)}
+	return nil
+}
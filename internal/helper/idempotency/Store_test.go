@@ -0,0 +1,64 @@
+package idempotency
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryStore(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("正常ケース：保存した結果を取得", func(t *testing.T) {
+		s := NewMemoryStore()
+		rec := &Record{Fingerprint: "fp", Response: Response{Status: http.StatusCreated, Body: []byte("ok")}}
+		assert.NoError(t, s.Set(ctx, "key", rec, time.Hour))
+
+		got, ok, err := s.Get(ctx, "key")
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, rec, got)
+	})
+	t.Run("正常ケース：期限切れの結果は取得できない", func(t *testing.T) {
+		s := NewMemoryStore()
+		now := time.Date(2026, time.October, 1, 0, 0, 0, 0, time.UTC)
+		s.now = func() time.Time { return now }
+		assert.NoError(t, s.Set(ctx, "key", &Record{Fingerprint: "fp"}, time.Minute))
+
+		now = now.Add(time.Minute)
+		_, ok, err := s.Get(ctx, "key")
+		assert.NoError(t, err)
+		assert.False(t, ok)
+	})
+	t.Run("正常ケース：解放後は再びロックできる", func(t *testing.T) {
+		s := NewMemoryStore()
+		unlock, err := s.Lock(ctx, "key")
+		if !assert.NoError(t, err) {
+			return
+		}
+		unlock()
+		// 2回呼んでも問題ない
+		unlock()
+
+		unlock, err = s.Lock(ctx, "key")
+		assert.NoError(t, err)
+		unlock()
+		assert.Empty(t, s.locks)
+	})
+	t.Run("異常ケース：ロックの待機中にキャンセル", func(t *testing.T) {
+		s := NewMemoryStore()
+		unlock, err := s.Lock(ctx, "key")
+		if !assert.NoError(t, err) {
+			return
+		}
+		defer unlock()
+
+		cctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+		defer cancel()
+		_, err = s.Lock(cctx, "key")
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+}
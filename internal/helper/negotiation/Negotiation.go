@@ -0,0 +1,89 @@
+package negotiation
+
+import (
+	"mime"
+	"strconv"
+	"strings"
+)
+
+// Acceptヘッダーの要素
+type mediaRange struct {
+	typ     string
+	subtype string
+	q       float64
+}
+
+// Acceptヘッダーとサーバーが提供できるメディアタイプから返却するメディアタイプを決定する
+// Acceptヘッダーが空の場合は先頭のメディアタイプを返す
+// 一致するものが無い場合はokがfalseになる
+func Negotiate(accept string, offers ...string) (string, bool) {
+	if len(offers) == 0 {
+		return "", false
+	}
+	if strings.TrimSpace(accept) == "" {
+		return offers[0], true
+	}
+	ranges := parseAccept(accept)
+
+	best := ""
+	bestQ := 0.0
+	bestSpecificity := -1
+	for _, offer := range offers {
+		typ, subtype, ok := strings.Cut(offer, "/")
+		if !ok {
+			continue
+		}
+		// 最も具体的に一致した要素のq値を採用する
+		q, specificity := 0.0, -1
+		for _, r := range ranges {
+			s := match(r, typ, subtype)
+			if s > specificity {
+				q, specificity = r.q, s
+			}
+		}
+		if specificity < 0 || q <= 0 {
+			continue
+		}
+		if q > bestQ || (q == bestQ && specificity > bestSpecificity) {
+			best, bestQ, bestSpecificity = offer, q, specificity
+		}
+	}
+	return best, best != ""
+}
+
+// メディアタイプの一致度を返す（一致しない場合は-1）
+func match(r mediaRange, typ, subtype string) int {
+	switch {
+	case r.typ == "*" && r.subtype == "*":
+		return 0
+	case strings.EqualFold(r.typ, typ) && r.subtype == "*":
+		return 1
+	case strings.EqualFold(r.typ, typ) && strings.EqualFold(r.subtype, subtype):
+		return 2
+	}
+	return -1
+}
+
+func parseAccept(accept string) []mediaRange {
+	var ranges []mediaRange
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		typ, subtype, ok := strings.Cut(mediaType, "/")
+		if !ok {
+			continue
+		}
+		r := mediaRange{typ: typ, subtype: subtype, q: 1}
+		if v, ok := params["q"]; ok {
+			q, err := strconv.ParseFloat(v, 64)
+			if err != nil || q < 0 || q > 1 {
+				continue
+			}
+			r.q = q
+		}
+		ranges = append(ranges, r)
+	}
+	return ranges
+}
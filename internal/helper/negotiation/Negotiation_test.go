@@ -0,0 +1,72 @@
+package negotiation
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNegotiate(t *testing.T) {
+	offers := []string{"application/json", "text/csv", "application/x-ndjson"}
+
+	success := map[string]struct {
+		accept string
+		want   string
+	}{
+		"正常ケース：Acceptヘッダーなし": {
+			accept: "",
+			want:   "application/json",
+		},
+		"正常ケース：完全一致": {
+			accept: "text/csv",
+			want:   "text/csv",
+		},
+		"正常ケース：ワイルドカード": {
+			accept: "*/*",
+			want:   "application/json",
+		},
+		"正常ケース：サブタイプのワイルドカード": {
+			accept: "text/*",
+			want:   "text/csv",
+		},
+		"正常ケース：q値の高いものを優先": {
+			accept: "application/json;q=0.5, application/x-ndjson",
+			want:   "application/x-ndjson",
+		},
+		"正常ケース：具体的な指定を優先": {
+			accept: "*/*;q=0.1, text/csv;q=0.8, application/json;q=0",
+			want:   "text/csv",
+		},
+		"正常ケース：パラメータ付き": {
+			accept: "text/csv; charset=utf-8",
+			want:   "text/csv",
+		},
+	}
+	fail := map[string]struct {
+		accept string
+	}{
+		"異常ケース：一致するものが無い": {
+			accept: "application/xml",
+		},
+		"異常ケース：q値が0": {
+			accept: "text/csv;q=0",
+		},
+		"異常ケース：不正な形式": {
+			accept: "json",
+		},
+	}
+
+	for tn, tc := range success {
+		t.Run(tn, func(t *testing.T) {
+			got, ok := Negotiate(tc.accept, offers...)
+			assert.True(t, ok)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+	for tn, tc := range fail {
+		t.Run(tn, func(t *testing.T) {
+			_, ok := Negotiate(tc.accept, offers...)
+			assert.False(t, ok)
+		})
+	}
+}
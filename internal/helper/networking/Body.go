@@ -0,0 +1,171 @@
+package networking
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/textproto"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// リクエストのボディ
+// NewRequestAndDoに渡すと、Content-Type・Content-Lengthを設定し、リトライ用にGetBodyも設定する
+// ヘッダーでContent-Typeを指定した場合はそちらを優先する（Multipartはboundaryを含むため指定できない）
+type Body interface {
+	// 送信する内容とContent-Typeを返す
+	Encode() (content []byte, contentType string, err error)
+}
+
+// ボディのContent-Type
+const (
+	ContentTypeForm  = "application/x-www-form-urlencoded"
+	ContentTypeJSON  = "application/json"
+	ContentTypeXML   = "application/xml; charset=utf-8"
+	ContentTypeBytes = "application/octet-stream"
+)
+
+// form-urlencoded形式のボディ
+func Form(values url.Values) Body {
+	return formBody{values: values}
+}
+
+type formBody struct {
+	values url.Values
+}
+
+func (b formBody) Encode() ([]byte, string, error) {
+	return []byte(b.values.Encode()), ContentTypeForm, nil
+}
+
+// JSON形式のボディ
+func JSON(v any) Body {
+	return jsonBody{v: v}
+}
+
+type jsonBody struct {
+	v any
+}
+
+func (b jsonBody) Encode() ([]byte, string, error) {
+	content, err := json.Marshal(b.v)
+	if err != nil {
+		return nil, "", err
+	}
+	return content, ContentTypeJSON, nil
+}
+
+// XML形式のボディ（XML宣言を付ける）
+func XML(v any) Body {
+	return xmlBody{v: v}
+}
+
+type xmlBody struct {
+	v any
+}
+
+func (b xmlBody) Encode() ([]byte, string, error) {
+	content, err := xml.Marshal(b.v)
+	if err != nil {
+		return nil, "", err
+	}
+	return append([]byte(xml.Header), content...), ContentTypeXML, nil
+}
+
+// 任意の形式のボディ
+// リトライできるよう、送信前にすべて読み込む（読み込みながら送信する場合はio.Readerをそのまま渡す）
+// contentTypeを省略した場合はapplication/octet-stream
+func Raw(r io.Reader, contentType string) Body {
+	return rawBody{r: r, contentType: contentType}
+}
+
+type rawBody struct {
+	r           io.Reader
+	contentType string
+}
+
+func (b rawBody) Encode() ([]byte, string, error) {
+	contentType := b.contentType
+	if contentType == "" {
+		contentType = ContentTypeBytes
+	}
+	if b.r == nil {
+		return nil, contentType, nil
+	}
+	content, err := io.ReadAll(b.r)
+	if err != nil {
+		return nil, "", err
+	}
+	return content, contentType, nil
+}
+
+// multipart/form-dataで送信するファイル
+type File struct {
+	// フォームの項目名
+	Field string
+	// ファイル名
+	Name string
+	// ファイルのContent-Type（省略した場合はapplication/octet-stream）
+	ContentType string
+	// ファイルの内容
+	Content io.Reader
+}
+
+// multipart/form-data形式のボディ
+// fieldsは項目名の順、filesは指定した順に書き込む
+func Multipart(fields map[string][]string, files []File) Body {
+	return multipartBody{fields: fields, files: files}
+}
+
+type multipartBody struct {
+	fields map[string][]string
+	files  []File
+}
+
+// ファイル名などに含まれる"と\をエスケープする（mime/multipartと同じ）
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+func (b multipartBody) Encode() ([]byte, string, error) {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+
+	keys := make([]string, 0, len(b.fields))
+	for k := range b.fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		for _, v := range b.fields[k] {
+			if err := mw.WriteField(k, v); err != nil {
+				return nil, "", err
+			}
+		}
+	}
+
+	for _, f := range b.files {
+		contentType := f.ContentType
+		if contentType == "" {
+			contentType = ContentTypeBytes
+		}
+		h := textproto.MIMEHeader{}
+		h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`, quoteEscaper.Replace(f.Field), quoteEscaper.Replace(f.Name)))
+		h.Set("Content-Type", contentType)
+		part, err := mw.CreatePart(h)
+		if err != nil {
+			return nil, "", err
+		}
+		if f.Content != nil {
+			if _, err := io.Copy(part, f.Content); err != nil {
+				return nil, "", err
+			}
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), mw.FormDataContentType(), nil
+}
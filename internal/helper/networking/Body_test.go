@@ -0,0 +1,213 @@
+package networking
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// 受け取ったボディの内容を返すサーバー
+type receivedBody struct {
+	ContentType   string              `json:"content_type"`
+	ContentLength int64               `json:"content_length"`
+	Body          string              `json:"body"`
+	Fields        map[string][]string `json:"fields,omitempty"`
+	Files         []receivedFile      `json:"files,omitempty"`
+}
+
+type receivedFile struct {
+	Field       string `json:"field"`
+	Name        string `json:"name"`
+	ContentType string `json:"content_type"`
+	Content     string `json:"content"`
+}
+
+func bodyEchoHandler(w http.ResponseWriter, r *http.Request) {
+	rb := receivedBody{ContentType: r.Header.Get("Content-Type"), ContentLength: r.ContentLength}
+	if mediaType, _, _ := mime.ParseMediaType(rb.ContentType); mediaType == "multipart/form-data" {
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		rb.Fields = r.MultipartForm.Value
+		for field, headers := range r.MultipartForm.File {
+			for _, fh := range headers {
+				f, _ := fh.Open()
+				b, _ := io.ReadAll(f)
+				f.Close()
+				rb.Files = append(rb.Files, receivedFile{Field: field, Name: fh.Filename, ContentType: fh.Header.Get("Content-Type"), Content: string(b)})
+			}
+		}
+	} else {
+		b, _ := io.ReadAll(r.Body)
+		rb.Body = string(b)
+	}
+	_ = json.NewEncoder(w).Encode(rb)
+}
+
+func doBody(t *testing.T, c *Client, header map[string][]string, body any) (receivedBody, error) {
+	t.Helper()
+	res, err := c.NewRequestAndDo(context.Background(), http.MethodPost, c.BaseURL, header, nil, body)
+	if err != nil {
+		return receivedBody{}, err
+	}
+	defer res.Body.Close()
+	var rb receivedBody
+	err = json.NewDecoder(res.Body).Decode(&rb)
+	return rb, err
+}
+
+func TestBody(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(bodyEchoHandler))
+	defer ts.Close()
+
+	type entry struct {
+		Name string `json:"name" xml:"name"`
+		Age  int    `json:"age" xml:"age"`
+	}
+
+	success := map[string]struct {
+		header   map[string][]string
+		body     Body
+		expected receivedBody
+	}{
+		"正常ケース：form-urlencoded": {
+			body:     Form(url.Values{"name": {"dip 次郎"}, "age": {"24"}}),
+			expected: receivedBody{ContentType: ContentTypeForm, Body: "age=24&name=dip+%E6%AC%A1%E9%83%8E"},
+		},
+		"正常ケース：JSON": {
+			body:     JSON(entry{Name: "dip", Age: 24}),
+			expected: receivedBody{ContentType: ContentTypeJSON, Body: `{"name":"dip","age":24}`},
+		},
+		"正常ケース：XML": {
+			body: XML(struct {
+				entry
+				XMLName xml.Name `xml:"user"`
+			}{entry: entry{Name: "dip", Age: 24}}),
+			expected: receivedBody{ContentType: ContentTypeXML, Body: "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<user><name>dip</name><age>24</age></user>"},
+		},
+		"正常ケース：任意の形式": {
+			body:     Raw(strings.NewReader(`{"age":null}`), "application/merge-patch+json"),
+			expected: receivedBody{ContentType: "application/merge-patch+json", Body: `{"age":null}`},
+		},
+		"正常ケース：任意の形式・Content-Typeの省略": {
+			body:     Raw(strings.NewReader("binary"), ""),
+			expected: receivedBody{ContentType: ContentTypeBytes, Body: "binary"},
+		},
+		"正常ケース：ヘッダーのContent-Typeを優先する": {
+			header:   map[string][]string{"Content-Type": {"application/vnd.dip+json"}},
+			body:     JSON(entry{Name: "dip", Age: 24}),
+			expected: receivedBody{ContentType: "application/vnd.dip+json", Body: `{"name":"dip","age":24}`},
+		},
+	}
+	for tn, tc := range success {
+		t.Run(tn, func(t *testing.T) {
+			c, _ := NewClient(ts.URL)
+			rb, err := doBody(t, c, tc.header, tc.body)
+			if !assert.NoError(t, err) {
+				return
+			}
+			tc.expected.ContentLength = int64(len(tc.expected.Body))
+			assert.Equal(t, tc.expected, rb)
+		})
+	}
+
+	t.Run("正常ケース：multipart/form-data", func(t *testing.T) {
+		c, _ := NewClient(ts.URL)
+		rb, err := doBody(t, c, nil, Multipart(
+			map[string][]string{"name": {"dip 次郎"}, "tags": {"a", "b"}},
+			[]File{
+				{Field: "csv", Name: "users.csv", ContentType: "text/csv", Content: strings.NewReader("name,age\n")},
+				{Field: "note", Name: `say "hi".txt`, Content: strings.NewReader("hi")},
+			},
+		))
+		if !assert.NoError(t, err) {
+			return
+		}
+		mediaType, _, _ := mime.ParseMediaType(rb.ContentType)
+		assert.Equal(t, "multipart/form-data", mediaType)
+		assert.Greater(t, rb.ContentLength, int64(0))
+		assert.Equal(t, map[string][]string{"name": {"dip 次郎"}, "tags": {"a", "b"}}, rb.Fields)
+		assert.ElementsMatch(t, []receivedFile{
+			{Field: "csv", Name: "users.csv", ContentType: "text/csv", Content: "name,age\n"},
+			{Field: "note", Name: `say "hi".txt`, ContentType: ContentTypeBytes, Content: "hi"},
+		}, rb.Files)
+	})
+
+	t.Run("異常ケース：multipart/form-dataのContent-Typeを上書きする", func(t *testing.T) {
+		c, _ := NewClient(ts.URL)
+		_, err := doBody(t, c, map[string][]string{"content-type": {"multipart/form-data"}}, Multipart(
+			map[string][]string{"name": {"dip 次郎"}}, nil,
+		))
+		assert.Error(t, err)
+	})
+
+	t.Run("正常ケース：GetBodyで送り直せる", func(t *testing.T) {
+		// 1回目は503を返すサーバー
+		attempts := 0
+		flaky := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			attempts++
+			if attempts == 1 {
+				_, _ = io.Copy(io.Discard, r.Body)
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			bodyEchoHandler(w, r)
+		}))
+		defer flaky.Close()
+
+		// 503の場合にGetBodyでボディを作り直して1回だけ再送するミドルウェア
+		retry := func(next http.RoundTripper) http.RoundTripper {
+			return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				res, err := next.RoundTrip(req)
+				if err != nil || res.StatusCode != http.StatusServiceUnavailable || req.GetBody == nil {
+					return res, err
+				}
+				res.Body.Close()
+				body, err := req.GetBody()
+				if err != nil {
+					return nil, err
+				}
+				retried := req.Clone(req.Context())
+				retried.Body = body
+				return next.RoundTrip(retried)
+			})
+		}
+		c, _ := NewClient(flaky.URL, WithMiddleware(retry))
+		rb, err := doBody(t, c, nil, Form(url.Values{"name": {"dip"}}))
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, 2, attempts)
+		assert.Equal(t, "name=dip", rb.Body)
+		assert.Equal(t, int64(len("name=dip")), rb.ContentLength)
+	})
+
+	readErr := errors.New("read failed")
+	fail := map[string]struct {
+		body Body
+	}{
+		"異常ケース：JSONのマーシャルに失敗": {body: JSON(make(chan int))},
+		"異常ケース：XMLのマーシャルに失敗":  {body: XML(make(chan int))},
+		"異常ケース：ボディの読み込みに失敗":   {body: Raw(iotest.ErrReader(readErr), "text/plain")},
+		"異常ケース：ファイルの読み込みに失敗":  {body: Multipart(nil, []File{{Field: "f", Name: "f.txt", Content: iotest.ErrReader(readErr)}})},
+	}
+	for tn, tc := range fail {
+		t.Run(tn, func(t *testing.T) {
+			c, _ := NewClient(ts.URL)
+			_, err := doBody(t, c, nil, tc.body)
+			assert.Error(t, err)
+		})
+	}
+}
@@ -4,17 +4,28 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"io"
 	"net/http"
 	"net/url"
 	"os"
 	"strings"
+	"sync"
 )
 
 // 外部APIへリクエストするためのクライアント
 type Client struct {
 	BaseURL *url.URL
 	Client  *http.Client
+
+	// 接続の設定（WithHTTPClientを指定した場合は使わない）
+	transport    TransportConfig
+	customClient bool
+
+	// フックとミドルウェア（共有する接続とは別に、クライアントごとに適用する）
+	requestHooks  []RequestHook
+	responseHooks []ResponseHook
+	middlewares   []Middleware
 }
 
 // クライアントの初期化処理
@@ -27,35 +38,90 @@ func NewClient(baseURL string, options ...Option) (*Client, error) {
 		return nil, err
 	}
 	c := &Client{
-		BaseURL: u,
-		Client:  &http.Client{},
+		BaseURL:   u,
+		Client:    &http.Client{},
+		transport: DefaultTransportConfig(),
+	}
+	for _, option := range defaultOptions() {
+		option(c)
 	}
 	for _, option := range options {
 		option(c)
 	}
+	// 同じ設定のクライアント間でコネクションプールを共有する
+	if !c.customClient {
+		if c.Client.Transport, err = sharedTransport(c.transport); err != nil {
+			return nil, err
+		}
+	}
+	if len(c.requestHooks) > 0 || len(c.responseHooks) > 0 || len(c.middlewares) > 0 {
+		// WithHTTPClientで渡されたClientを変更しないよう、複製してから包む
+		httpClient := *c.Client
+		httpClient.Transport = c.wrapTransport(httpClient.Transport)
+		c.Client = &httpClient
+	}
 	return c, nil
 }
 
+// 全てのクライアントに適用するオプション
+var (
+	defaultOptionsMu sync.RWMutex
+	defaultOpts      []Option
+)
+
+// NewClientで生成する全てのクライアントに適用するオプションを設定する
+// ハンドラ内で生成されるクライアントの差し替え（テストでの記録・再生など）に使う
+// 引数なしで呼ぶと解除する
+func SetDefaultOptions(options ...Option) {
+	defaultOptionsMu.Lock()
+	defer defaultOptionsMu.Unlock()
+	defaultOpts = options
+}
+
+func defaultOptions() []Option {
+	defaultOptionsMu.RLock()
+	defer defaultOptionsMu.RUnlock()
+	return defaultOpts
+}
+
 // API呼び出し時のオプション
 type Option func(c *Client)
 
 // Clientを上書きするオプション
+// 指定したClientのTransportは変更しないため、接続の設定のオプションは適用されない
 func WithHTTPClient(httpClient *http.Client) Option {
 	return func(c *Client) {
 		c.Client = httpClient
+		c.customClient = true
 	}
 }
 
 // リクエストの生成と実行
 func (c *Client) NewRequestAndDo(ctx context.Context, method string, apiURL *url.URL, header map[string][]string, params map[string][]string, body any) (*http.Response, error) {
 	var reqBody io.Reader
+	var contentType string
 
 	switch v := body.(type) {
+	case Body:
+		// multipart/form-dataのboundaryはエンコード時に決まるため、ヘッダーのContent-Typeでは上書きできない
+		if _, ok := v.(multipartBody); ok && hasHeader(header, "Content-Type") {
+			return nil, errors.New("networking: Content-Type cannot be overridden for a multipart body")
+		}
+		// 形式を指定したボディの場合（bytes.ReaderのためContent-LengthとGetBodyが設定される）
+		content, ct, err := v.Encode()
+		if err != nil {
+			return nil, err
+		}
+		reqBody = bytes.NewReader(content)
+		contentType = ct
 	case string:
 		// form-urlencoded形式の文字列の場合
 		reqBody = strings.NewReader(v)
 	case nil:
 		reqBody = nil
+	case io.Reader:
+		// 読み込みながら送信する場合
+		reqBody = v
 	default:
 		// JSONの場合
 		jsonBytes, err := json.Marshal(v)
@@ -71,14 +137,16 @@ func (c *Client) NewRequestAndDo(ctx context.Context, method string, apiURL *url
 		return nil, err
 	}
 	// ヘッダーの設定
-	if header != nil {
-		for k, vs := range header {
-			for _, v := range vs {
-				req.Header.Set(k, v)
-			}
+	for k, vs := range header {
+		for _, v := range vs {
+			req.Header.Set(k, v)
 		}
-	} else {
-		req.Header.Set("Content-Type", "application/json")
+	}
+	switch {
+	case contentType != "" && req.Header.Get("Content-Type") == "":
+		req.Header.Set("Content-Type", contentType)
+	case contentType == "" && header == nil:
+		req.Header.Set("Content-Type", ContentTypeJSON)
 	}
 	// クエリパラメータの設定
 	if params != nil {
@@ -93,3 +161,13 @@ func (c *Client) NewRequestAndDo(ctx context.Context, method string, apiURL *url
 	// リクエストの実行
 	return c.Client.Do(req)
 }
+
+// ヘッダーに値があるか（名前の大文字小文字は区別しない）
+func hasHeader(header map[string][]string, name string) bool {
+	for k, vs := range header {
+		if len(vs) > 0 && http.CanonicalHeaderKey(k) == http.CanonicalHeaderKey(name) {
+			return true
+		}
+	}
+	return false
+}
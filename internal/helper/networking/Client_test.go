@@ -3,12 +3,16 @@ package networking
 import (
 	"context"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+
+	"github.com/dip-dev/go-tutorial/internal/helper/test"
 )
 
 const targetURL = "http://mock-api"
@@ -95,6 +99,17 @@ func TestNewRequestAndDo(t *testing.T) {
 			},
 			body: map[string]string{"name": "dip 次郎", "age": "24"},
 		},
+		"正常ケース:ボディを読み込みながら送る": {
+			method: http.MethodPost,
+			url: url.URL{
+				Scheme: "http",
+				Host:   "mock:80",
+			},
+			header: map[string][]string{
+				"Content-Type": {"application/x-www-form-urlencoded"},
+			},
+			body: strings.NewReader(url.Values{"name": {"dip 次郎"}, "age": {"24"}}.Encode()),
+		},
 	}
 	fail := map[string]struct {
 		method string
@@ -112,9 +127,14 @@ func TestNewRequestAndDo(t *testing.T) {
 		},
 	}
 
+	// mock-apiの代わりにローカルのサーバーを使う
+	ts := httptest.NewServer(test.NewMockAPI(test.DefaultMockData()))
+	defer ts.Close()
+
 	for tn, tc := range success {
 		t.Run(tn, func(t *testing.T) {
 			c, _ := NewClient(targetURL)
+			tc.url.Host = ts.Listener.Addr().String()
 			res, err := c.NewRequestAndDo(ctx, tc.method, &tc.url, tc.header, tc.params, tc.body)
 			if err != nil {
 				t.Errorf("error: %#v", err)
@@ -0,0 +1,164 @@
+package networking
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+)
+
+// 展開後のボディの大きさの既定の上限（圧縮爆弾対策）
+const DefaultMaxDecompressedSize = 10 << 20
+
+// 展開後のボディが上限を超えた場合のエラー
+var ErrDecompressedTooLarge = errors.New("networking: decompressed body too large")
+
+// 対応するContent-Encoding（Accept-Encodingに指定する）
+const acceptEncoding = "gzip, deflate, br"
+
+// 圧縮されたレスポンスを受け付け、展開してから返すオプション
+// maxSizeは展開後の大きさの上限（0以下の場合はDefaultMaxDecompressedSize）
+// 上限を超えるとボディの読み込みがErrDecompressedTooLargeになる
+func WithDecompression(maxSize int64) Option {
+	return func(c *Client) {
+		c.middlewares = append(c.middlewares, decompressionMiddleware(maxSize))
+	}
+}
+
+func decompressionMiddleware(maxSize int64) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			// 呼び出し元が指定した場合はそのまま送る
+			// Rangeの場合は圧縮データの一部を展開できないため要求しない（net/httpと同じ）
+			if req.Header.Get("Accept-Encoding") == "" && req.Header.Get("Range") == "" {
+				req = req.Clone(req.Context())
+				req.Header.Set("Accept-Encoding", acceptEncoding)
+			}
+			res, err := next.RoundTrip(req)
+			if err != nil {
+				return nil, err
+			}
+			if err := DecompressResponse(res, maxSize); err != nil {
+				res.Body.Close()
+				return nil, err
+			}
+			return res, nil
+		})
+	}
+}
+
+// Content-Encodingに従ってレスポンスのボディを展開する
+// 展開した場合はContent-Encoding・Content-Lengthを削除し、Uncompressedをtrueにする
+// maxSizeは展開後の大きさの上限（0以下の場合はDefaultMaxDecompressedSize）
+func DecompressResponse(res *http.Response, maxSize int64) error {
+	encodings := contentEncodings(res.Header)
+	if len(encodings) == 0 || !hasBody(res) {
+		return nil
+	}
+	if maxSize <= 0 {
+		maxSize = DefaultMaxDecompressedSize
+	}
+
+	// 複数指定された場合は適用された順に並ぶため、後ろから展開する
+	body := res.Body
+	var r io.Reader = body
+	for i := len(encodings) - 1; i >= 0; i-- {
+		dr, err := newDecompressor(encodings[i], r)
+		if err != nil {
+			return err
+		}
+		r = dr
+	}
+
+	res.Body = &limitedBody{r: r, remaining: maxSize, closer: body}
+	res.Header.Del("Content-Encoding")
+	res.Header.Del("Content-Length")
+	res.ContentLength = -1
+	res.Uncompressed = true
+	return nil
+}
+
+// ボディを持ち得るレスポンスか（HEADや204・304は空のため展開しない）
+func hasBody(res *http.Response) bool {
+	if res.StatusCode == http.StatusNoContent || res.StatusCode == http.StatusNotModified {
+		return false
+	}
+	return res.Request == nil || res.Request.Method != http.MethodHead
+}
+
+// Content-Encodingの値（identityを除く、小文字）
+func contentEncodings(h http.Header) []string {
+	var encodings []string
+	for _, v := range h.Values("Content-Encoding") {
+		for _, e := range strings.Split(v, ",") {
+			e = strings.ToLower(strings.TrimSpace(e))
+			if e != "" && e != "identity" {
+				encodings = append(encodings, e)
+			}
+		}
+	}
+	return encodings
+}
+
+func newDecompressor(encoding string, r io.Reader) (io.Reader, error) {
+	switch encoding {
+	case "gzip", "x-gzip":
+		return gzip.NewReader(r)
+	case "deflate":
+		return newDeflateReader(r)
+	case "br":
+		return brotli.NewReader(r), nil
+	}
+	return nil, fmt.Errorf("networking: unsupported Content-Encoding: %q", encoding)
+}
+
+// HTTPのdeflateはzlib形式だが、ヘッダーの無い生のdeflateを返すサーバーもあるため両方に対応する
+func newDeflateReader(r io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	header, err := br.Peek(2)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	if len(header) == 2 && header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+		return zlib.NewReader(br)
+	}
+	return flate.NewReader(br), nil
+}
+
+// 展開後の大きさを制限するボディ
+type limitedBody struct {
+	r         io.Reader
+	remaining int64
+	closer    io.Closer
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.remaining <= 0 {
+		// 上限ちょうどで終わる場合は正常に終了する
+		var one [1]byte
+		if _, err := io.ReadFull(b.r, one[:]); err != nil {
+			if errors.Is(err, io.EOF) {
+				return 0, io.EOF
+			}
+			return 0, err
+		}
+		return 0, ErrDecompressedTooLarge
+	}
+	if int64(len(p)) > b.remaining {
+		p = p[:b.remaining]
+	}
+	n, err := b.r.Read(p)
+	b.remaining -= int64(n)
+	return n, err
+}
+
+func (b *limitedBody) Close() error {
+	return b.closer.Close()
+}
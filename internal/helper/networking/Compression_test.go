@@ -0,0 +1,153 @@
+package networking
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/stretchr/testify/assert"
+)
+
+// 指定した形式で圧縮する
+func compress(t *testing.T, encoding string, b []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	switch encoding {
+	case "gzip":
+		w = gzip.NewWriter(&buf)
+	case "deflate":
+		w = zlib.NewWriter(&buf)
+	case "raw-deflate":
+		w, _ = flate.NewWriter(&buf, flate.DefaultCompression)
+	case "br":
+		w = brotli.NewWriter(&buf)
+	default:
+		t.Fatalf("unknown encoding: %s", encoding)
+	}
+	if _, err := w.Write(b); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// 圧縮したボディを返すサーバー
+// 受け取ったAccept-EncodingをX-Accept-Encodingで返す
+func newCompressedServer(t *testing.T, encoding string, body []byte) *httptest.Server {
+	t.Helper()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Accept-Encoding", r.Header.Get("Accept-Encoding"))
+		w.Header().Set("Content-Type", "application/json")
+		if encoding != "" {
+			w.Header().Set("Content-Encoding", encoding)
+		}
+		_, _ = w.Write(body)
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func TestWithDecompression(t *testing.T) {
+	body := []byte(`{"name":"` + strings.Repeat("dip ", 1000) + `"}`)
+
+	success := map[string]struct {
+		header   string
+		content  []byte
+		maxSize  int64
+		expected []byte
+	}{
+		"正常ケース：gzip":            {header: "gzip", content: compress(t, "gzip", body), expected: body},
+		"正常ケース：deflate（zlib）":   {header: "deflate", content: compress(t, "deflate", body), expected: body},
+		"正常ケース：deflate（ヘッダーなし）": {header: "deflate", content: compress(t, "raw-deflate", body), expected: body},
+		"正常ケース：brotli":          {header: "br", content: compress(t, "br", body), expected: body},
+		"正常ケース：複数の圧縮":           {header: "gzip, br", content: compress(t, "br", compress(t, "gzip", body)), expected: body},
+		"正常ケース：圧縮なし":            {header: "", content: body, expected: body},
+		"正常ケース：上限ちょうど":          {header: "gzip", content: compress(t, "gzip", body), maxSize: int64(len(body)), expected: body},
+	}
+	for tn, tc := range success {
+		t.Run(tn, func(t *testing.T) {
+			ts := newCompressedServer(t, tc.header, tc.content)
+			c, err := NewClient(ts.URL, WithDecompression(tc.maxSize))
+			if !assert.NoError(t, err) {
+				return
+			}
+			res, err := c.NewRequestAndDo(context.Background(), http.MethodGet, c.BaseURL, nil, nil, nil)
+			if !assert.NoError(t, err) {
+				return
+			}
+			defer res.Body.Close()
+			got, err := io.ReadAll(res.Body)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, got)
+			assert.Equal(t, acceptEncoding, res.Header.Get("X-Accept-Encoding"))
+			assert.Empty(t, res.Header.Get("Content-Encoding"))
+		})
+	}
+
+	t.Run("正常ケース：指定したAccept-Encodingを優先する", func(t *testing.T) {
+		ts := newCompressedServer(t, "", body)
+		c, _ := NewClient(ts.URL, WithDecompression(0))
+		res, err := c.NewRequestAndDo(context.Background(), http.MethodGet, c.BaseURL, map[string][]string{"Accept-Encoding": {"identity"}}, nil, nil)
+		if !assert.NoError(t, err) {
+			return
+		}
+		defer res.Body.Close()
+		assert.Equal(t, "identity", res.Header.Get("X-Accept-Encoding"))
+	})
+
+	t.Run("正常ケース：HEADは展開しない", func(t *testing.T) {
+		ts := newCompressedServer(t, "gzip", nil)
+		c, _ := NewClient(ts.URL, WithDecompression(0))
+		res, err := c.NewRequestAndDo(context.Background(), http.MethodHead, c.BaseURL, nil, nil, nil)
+		if !assert.NoError(t, err) {
+			return
+		}
+		res.Body.Close()
+		assert.Equal(t, "gzip", res.Header.Get("Content-Encoding"))
+	})
+
+	t.Run("異常ケース：展開後の大きさが上限を超える", func(t *testing.T) {
+		// 10MiBの0が数KBに圧縮される
+		bomb := compress(t, "gzip", make([]byte, 10<<20))
+		ts := newCompressedServer(t, "gzip", bomb)
+		c, _ := NewClient(ts.URL, WithDecompression(1<<20))
+		res, err := c.NewRequestAndDo(context.Background(), http.MethodGet, c.BaseURL, nil, nil, nil)
+		if !assert.NoError(t, err) {
+			return
+		}
+		defer res.Body.Close()
+		got, err := io.ReadAll(res.Body)
+		assert.ErrorIs(t, err, ErrDecompressedTooLarge)
+		assert.Equal(t, 1<<20, len(got))
+	})
+
+	fail := map[string]struct {
+		header  string
+		content []byte
+	}{
+		"異常ケース：未対応の形式":       {header: "compress", content: body},
+		"異常ケース：gzipのヘッダーが不正": {header: "gzip", content: body},
+	}
+	for tn, tc := range fail {
+		t.Run(tn, func(t *testing.T) {
+			ts := newCompressedServer(t, tc.header, tc.content)
+			c, _ := NewClient(ts.URL, WithDecompression(0))
+			res, err := c.NewRequestAndDo(context.Background(), http.MethodGet, c.BaseURL, nil, nil, nil)
+			if res != nil {
+				res.Body.Close()
+			}
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package networking
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// 対応するデコーダーが無い場合のエラー
+var ErrUnsupportedContentType = errors.New("networking: unsupported Content-Type")
+
+// レスポンスのボディをvへ変換する
+type Decoder func(r io.Reader, v any) error
+
+// JSONのデコーダー
+func DecodeJSON(r io.Reader, v any) error {
+	return json.NewDecoder(r).Decode(v)
+}
+
+// XMLのデコーダー
+func DecodeXML(r io.Reader, v any) error {
+	return xml.NewDecoder(r).Decode(v)
+}
+
+// form-urlencodedのデコーダー
+// vには*url.Values・*map[string][]string・*map[string]string（先頭の値）を指定する
+func DecodeForm(r io.Reader, v any) error {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	values, err := url.ParseQuery(string(b))
+	if err != nil {
+		return err
+	}
+	switch dst := v.(type) {
+	case *url.Values:
+		*dst = values
+	case *map[string][]string:
+		*dst = values
+	case *map[string]string:
+		m := make(map[string]string, len(values))
+		for k := range values {
+			m[k] = values.Get(k)
+		}
+		*dst = m
+	default:
+		return fmt.Errorf("networking: cannot decode form into %T", v)
+	}
+	return nil
+}
+
+// Content-Type（メディアタイプ）ごとのデコーダー
+// ゼロ値は何も登録されていない状態で、使う形式だけを登録できる
+type Decoders struct {
+	mu       sync.RWMutex
+	decoders map[string]Decoder
+}
+
+// JSON・XML・form-urlencodedを登録したDecodersを生成する
+func NewDecoders() *Decoders {
+	d := &Decoders{decoders: map[string]Decoder{}}
+	d.Register("application/json", DecodeJSON)
+	d.Register("application/xml", DecodeXML)
+	d.Register("text/xml", DecodeXML)
+	d.Register(ContentTypeForm, DecodeForm)
+	return d
+}
+
+// 既定のデコーダー（DecodeResponseで使う）
+var DefaultDecoders = NewDecoders()
+
+// メディアタイプのデコーダーを登録する（既にある場合は上書きする）
+func (d *Decoders) Register(mediaType string, decoder Decoder) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.decoders == nil {
+		d.decoders = map[string]Decoder{}
+	}
+	d.decoders[strings.ToLower(mediaType)] = decoder
+}
+
+// Content-Typeからデコーダーを選ぶ
+// 登録されていない場合は構造化構文の接尾辞（+json・+xml）で選ぶ
+// Content-Typeが無い場合はJSONとして扱う
+func (d *Decoders) Lookup(contentType string) (Decoder, error) {
+	mediaType := "application/json"
+	if contentType != "" {
+		var err error
+		if mediaType, _, err = mime.ParseMediaType(contentType); err != nil {
+			return nil, fmt.Errorf("%w: %q", ErrUnsupportedContentType, contentType)
+		}
+	}
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if decoder, ok := d.decoders[mediaType]; ok {
+		return decoder, nil
+	}
+	switch {
+	case strings.HasSuffix(mediaType, "+json"):
+		if decoder, ok := d.decoders["application/json"]; ok {
+			return decoder, nil
+		}
+	case strings.HasSuffix(mediaType, "+xml"):
+		if decoder, ok := d.decoders["application/xml"]; ok {
+			return decoder, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %q", ErrUnsupportedContentType, contentType)
+}
+
+// レスポンスのボディをContent-Typeに合ったデコーダーでvへ変換する
+// 圧縮されたまま（WithDecompressionを指定していない場合など）であれば展開してから変換する
+func (d *Decoders) DecodeResponse(res *http.Response, v any) error {
+	decoder, err := d.Lookup(res.Header.Get("Content-Type"))
+	if err != nil {
+		return err
+	}
+	if err := DecompressResponse(res, 0); err != nil {
+		return err
+	}
+	return decoder(res.Body, v)
+}
+
+// 既定のデコーダーでレスポンスのボディをvへ変換する
+func DecodeResponse(res *http.Response, v any) error {
+	return DefaultDecoders.DecodeResponse(res, v)
+}
@@ -0,0 +1,120 @@
+package networking
+
+import (
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type decodedUser struct {
+	ID   int    `json:"id" xml:"id"`
+	Name string `json:"name" xml:"name"`
+}
+
+// テスト用のレスポンス
+func newResponse(contentType, encoding string, body []byte) *http.Response {
+	h := http.Header{}
+	if contentType != "" {
+		h.Set("Content-Type", contentType)
+	}
+	if encoding != "" {
+		h.Set("Content-Encoding", encoding)
+	}
+	return &http.Response{StatusCode: http.StatusOK, Header: h, Body: io.NopCloser(strings.NewReader(string(body)))}
+}
+
+func TestDecodeResponse(t *testing.T) {
+	success := map[string]struct {
+		contentType string
+		body        string
+	}{
+		"正常ケース：JSON":           {contentType: "application/json; charset=utf-8", body: `{"id":1,"name":"dip"}`},
+		"正常ケース：+json":          {contentType: "application/vnd.dip+json", body: `{"id":1,"name":"dip"}`},
+		"正常ケース：Content-Typeなし": {contentType: "", body: `{"id":1,"name":"dip"}`},
+		"正常ケース：XML":            {contentType: "application/xml", body: `<?xml version="1.0"?><user><id>1</id><name>dip</name></user>`},
+		"正常ケース：text/xml":       {contentType: "text/xml; charset=utf-8", body: `<user><id>1</id><name>dip</name></user>`},
+		"正常ケース：+xml":           {contentType: "application/atom+xml", body: `<user><id>1</id><name>dip</name></user>`},
+	}
+	for tn, tc := range success {
+		t.Run(tn, func(t *testing.T) {
+			var got decodedUser
+			err := DecodeResponse(newResponse(tc.contentType, "", []byte(tc.body)), &got)
+			assert.NoError(t, err)
+			assert.Equal(t, decodedUser{ID: 1, Name: "dip"}, got)
+		})
+	}
+
+	t.Run("正常ケース：圧縮されたJSON", func(t *testing.T) {
+		var got decodedUser
+		err := DecodeResponse(newResponse("application/json", "br", compress(t, "br", []byte(`{"id":1,"name":"dip"}`))), &got)
+		assert.NoError(t, err)
+		assert.Equal(t, decodedUser{ID: 1, Name: "dip"}, got)
+	})
+
+	t.Run("正常ケース：form-urlencoded", func(t *testing.T) {
+		body := []byte("name=dip+%E6%AC%A1%E9%83%8E&tag=a&tag=b")
+		var values url.Values
+		assert.NoError(t, DecodeResponse(newResponse(ContentTypeForm, "", body), &values))
+		assert.Equal(t, url.Values{"name": {"dip 次郎"}, "tag": {"a", "b"}}, values)
+
+		var m map[string]string
+		assert.NoError(t, DecodeResponse(newResponse(ContentTypeForm, "", body), &m))
+		assert.Equal(t, map[string]string{"name": "dip 次郎", "tag": "a"}, m)
+	})
+
+	t.Run("正常ケース：デコーダーを登録できる", func(t *testing.T) {
+		decoders := NewDecoders()
+		decoders.Register("text/csv", func(r io.Reader, v any) error {
+			b, err := io.ReadAll(r)
+			if err != nil {
+				return err
+			}
+			*(v.(*[]string)) = strings.Split(strings.TrimSpace(string(b)), ",")
+			return nil
+		})
+		var got []string
+		err := decoders.DecodeResponse(newResponse("text/csv", "", []byte("a,b\n")), &got)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"a", "b"}, got)
+
+		// 既定のデコーダーには影響しない
+		_, err = DefaultDecoders.Lookup("text/csv")
+		assert.ErrorIs(t, err, ErrUnsupportedContentType)
+	})
+	t.Run("正常ケース：ゼロ値には登録した形式だけを使う", func(t *testing.T) {
+		var decoders Decoders
+		decoders.Register("application/json", DecodeJSON)
+
+		var got decodedUser
+		assert.NoError(t, decoders.DecodeResponse(newResponse("application/vnd.dip+json", "", []byte(`{"id":1,"name":"dip"}`)), &got))
+		assert.Equal(t, decodedUser{ID: 1, Name: "dip"}, got)
+
+		_, err := decoders.Lookup("application/xml")
+		assert.ErrorIs(t, err, ErrUnsupportedContentType)
+	})
+
+	fail := map[string]struct {
+		contentType string
+		body        string
+		target      any
+		wantErr     error
+	}{
+		"異常ケース：未対応のContent-Type": {contentType: "text/plain", body: "Internal Server Error", target: &decodedUser{}, wantErr: ErrUnsupportedContentType},
+		"異常ケース：不正なContent-Type":  {contentType: "application/", body: "{}", target: &decodedUser{}, wantErr: ErrUnsupportedContentType},
+		"異常ケース：JSONが不正":          {contentType: "application/json", body: "{", target: &decodedUser{}},
+		"異常ケース：formの変換先が不正":      {contentType: ContentTypeForm, body: "a=1", target: &decodedUser{}},
+	}
+	for tn, tc := range fail {
+		t.Run(tn, func(t *testing.T) {
+			err := DecodeResponse(newResponse(tc.contentType, "", []byte(tc.body)), tc.target)
+			assert.Error(t, err)
+			if tc.wantErr != nil {
+				assert.ErrorIs(t, err, tc.wantErr)
+			}
+		})
+	}
+}
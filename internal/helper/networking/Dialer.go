@@ -0,0 +1,111 @@
+package networking
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/http/httpproxy"
+)
+
+// 名前解決を差し替えるためのインターフェース（*net.Resolverも満たす）
+type Resolver interface {
+	LookupHost(ctx context.Context, host string) ([]string, error)
+}
+
+// 接続先の名前解決を変更するDialer
+// 同じ*Dialerを指定したクライアントは接続を共有するため、1度だけ生成して使い回す
+type Dialer struct {
+	// ホスト名（小文字）→IPアドレスの固定の対応（/etc/hostsと同様）
+	Hosts map[string]string
+	// 固定の対応にないホストの名前解決（nilの場合はシステムの設定を使う）
+	Resolver Resolver
+}
+
+// 接続先の名前解決を変更するオプション
+func WithDialer(d *Dialer) Option {
+	return func(c *Client) {
+		c.transport.Dialer = d
+	}
+}
+
+// HTTPプロキシを経由して接続するオプション
+// noProxyにはNO_PROXYと同じ形式（カンマ区切りのホスト名・ドメイン・CIDR）で直接接続する宛先を指定する
+// localhostとループバックアドレスへは常に直接接続する
+func WithProxy(proxyURL, noProxy string) Option {
+	return func(c *Client) {
+		c.transport.ProxyURL = proxyURL
+		c.transport.NoProxy = noProxy
+	}
+}
+
+// 指定したDNSサーバー（host:port）へ問い合わせるResolverを生成する
+func NewDNSResolver(server string) *net.Resolver {
+	var d net.Dialer
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			return d.DialContext(ctx, network, server)
+		},
+	}
+}
+
+// 設定に従って接続先のアドレスを決めて接続する
+func (d *Dialer) dialContext(base *net.Dialer) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		if ip, ok := d.Hosts[strings.ToLower(host)]; ok {
+			return base.DialContext(ctx, network, net.JoinHostPort(ip, port))
+		}
+		if d.Resolver == nil || net.ParseIP(host) != nil {
+			return base.DialContext(ctx, network, addr)
+		}
+
+		addrs, err := d.Resolver.LookupHost(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		if len(addrs) == 0 {
+			return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+		}
+		// 接続できるまで順に試す
+		var errs []error
+		for _, a := range addrs {
+			conn, err := base.DialContext(ctx, network, net.JoinHostPort(a, port))
+			if err == nil {
+				return conn, nil
+			}
+			errs = append(errs, err)
+		}
+		return nil, errors.Join(errs...)
+	}
+}
+
+// プロキシの設定からhttp.TransportのProxyを生成する
+// 指定がない場合は環境変数（HTTP_PROXY・HTTPS_PROXY・NO_PROXY）に従う
+func (config TransportConfig) proxy() (func(*http.Request) (*url.URL, error), error) {
+	if config.ProxyURL == "" {
+		return http.ProxyFromEnvironment, nil
+	}
+	u, err := url.Parse(config.ProxyURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return nil, errors.New("proxy URL must be absolute: " + config.ProxyURL)
+	}
+	proxyFunc := (&httpproxy.Config{
+		HTTPProxy:  config.ProxyURL,
+		HTTPSProxy: config.ProxyURL,
+		NoProxy:    config.NoProxy,
+	}).ProxyFunc()
+	return func(req *http.Request) (*url.URL, error) {
+		return proxyFunc(req.URL)
+	}, nil
+}
@@ -0,0 +1,212 @@
+package networking
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// 固定の結果を返すResolver
+type fakeResolver struct {
+	addrs []string
+	err   error
+	calls int32
+}
+
+func (r *fakeResolver) LookupHost(_ context.Context, _ string) ([]string, error) {
+	atomic.AddInt32(&r.calls, 1)
+	return r.addrs, r.err
+}
+
+// 接続先のポートを残してホスト名だけを差し替えたURL
+func urlWithHost(t *testing.T, ts *httptest.Server, host string) string {
+	t.Helper()
+	u, err := url.Parse(ts.URL)
+	assert.NoError(t, err)
+	u.Host = net.JoinHostPort(host, u.Port())
+	return u.String()
+}
+
+func TestDialer(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(okHandler))
+	defer ts.Close()
+
+	success := map[string]struct {
+		dialer    *Dialer
+		wantCalls int32
+	}{
+		"正常ケース：固定の対応で接続する": {
+			dialer: &Dialer{Hosts: map[string]string{"mock-api": "127.0.0.1"}, Resolver: &fakeResolver{err: errors.New("unused")}},
+		},
+		"正常ケース：Resolverで名前解決する": {
+			dialer:    &Dialer{Resolver: &fakeResolver{addrs: []string{"127.0.0.1"}}},
+			wantCalls: 1,
+		},
+		"正常ケース：接続できるアドレスまで順に試す": {
+			dialer:    &Dialer{Resolver: &fakeResolver{addrs: []string{"127.0.0.2", "127.0.0.1"}}},
+			wantCalls: 1,
+		},
+	}
+	for tn, tc := range success {
+		t.Run(tn, func(t *testing.T) {
+			c, err := NewClient(urlWithHost(t, ts, "MOCK-API"), WithDialer(tc.dialer))
+			if !assert.NoError(t, err) {
+				return
+			}
+			status, err := getStatus(c)
+			assert.NoError(t, err)
+			assert.Equal(t, http.StatusOK, status)
+			assert.Equal(t, tc.wantCalls, tc.dialer.Resolver.(*fakeResolver).calls)
+		})
+	}
+
+	fail := map[string]struct {
+		resolver *fakeResolver
+	}{
+		"異常ケース：名前解決に失敗":     {resolver: &fakeResolver{err: errors.New("lookup failed")}},
+		"異常ケース：アドレスが見つからない": {resolver: &fakeResolver{}},
+	}
+	for tn, tc := range fail {
+		t.Run(tn, func(t *testing.T) {
+			c, err := NewClient(urlWithHost(t, ts, "mock-api"), WithDialer(&Dialer{Resolver: tc.resolver}))
+			if !assert.NoError(t, err) {
+				return
+			}
+			_, err = getStatus(c)
+			assert.Error(t, err)
+		})
+	}
+
+	t.Run("正常ケース：同じDialerのクライアントは接続を共有する", func(t *testing.T) {
+		d := &Dialer{Hosts: map[string]string{"mock-api": "127.0.0.1"}}
+		c1, _ := NewClient("http://mock-api", WithDialer(d))
+		c2, _ := NewClient("http://mock-api", WithDialer(d))
+		assert.True(t, c1.Client.Transport == c2.Client.Transport)
+	})
+}
+
+func TestProxy(t *testing.T) {
+	// 受け取ったリクエストの宛先を返すプロキシ
+	var proxied int32
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&proxied, 1)
+		w.Header().Set("X-Proxy-Target", r.URL.String())
+	}))
+	defer proxy.Close()
+	direct := httptest.NewServer(http.HandlerFunc(okHandler))
+	defer direct.Close()
+	dialer := &Dialer{Hosts: map[string]string{"mock-api": "127.0.0.1", "mock-api.internal": "127.0.0.1"}}
+
+	t.Run("正常ケース：プロキシを経由する", func(t *testing.T) {
+		atomic.StoreInt32(&proxied, 0)
+		c, err := NewClient("http://mock-api/users?age=25", WithProxy(proxy.URL, ""))
+		if !assert.NoError(t, err) {
+			return
+		}
+		res, err := c.NewRequestAndDo(context.Background(), http.MethodGet, c.BaseURL, nil, nil, nil)
+		if !assert.NoError(t, err) {
+			return
+		}
+		defer res.Body.Close()
+		assert.Equal(t, "http://mock-api/users?age=25", res.Header.Get("X-Proxy-Target"))
+		assert.Equal(t, int32(1), atomic.LoadInt32(&proxied))
+	})
+
+	success := map[string]struct {
+		host    string
+		noProxy string
+	}{
+		"正常ケース：NO_PROXYのホストは直接接続する":    {host: "mock-api", noProxy: "example.com, mock-api"},
+		"正常ケース：NO_PROXYのドメインは直接接続する":   {host: "mock-api.internal", noProxy: ".internal"},
+		"正常ケース：NO_PROXYが*の場合は全て直接接続する": {host: "mock-api", noProxy: "*"},
+	}
+	for tn, tc := range success {
+		t.Run(tn, func(t *testing.T) {
+			atomic.StoreInt32(&proxied, 0)
+			c, err := NewClient(urlWithHost(t, direct, tc.host), WithProxy(proxy.URL, tc.noProxy), WithDialer(dialer))
+			if !assert.NoError(t, err) {
+				return
+			}
+			status, err := getStatus(c)
+			assert.NoError(t, err)
+			assert.Equal(t, http.StatusOK, status)
+			assert.Equal(t, int32(0), atomic.LoadInt32(&proxied))
+		})
+	}
+
+	t.Run("異常ケース：プロキシのURLが不正", func(t *testing.T) {
+		_, err := NewClient("http://mock-api", WithProxy("proxy:8080", ""))
+		assert.Error(t, err)
+	})
+}
+
+// 全てのAレコードの問い合わせに127.0.0.1を返すDNSサーバー
+func newDNSServer(t *testing.T) string {
+	t.Helper()
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { pc.Close() })
+
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := pc.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			var p dnsmessage.Parser
+			h, err := p.Start(buf[:n])
+			if err != nil {
+				continue
+			}
+			q, err := p.Question()
+			if err != nil {
+				continue
+			}
+			b := dnsmessage.NewBuilder(nil, dnsmessage.Header{ID: h.ID, Response: true, Authoritative: true})
+			_ = b.StartQuestions()
+			_ = b.Question(q)
+			_ = b.StartAnswers()
+			if q.Type == dnsmessage.TypeA {
+				_ = b.AResource(
+					dnsmessage.ResourceHeader{Name: q.Name, Class: dnsmessage.ClassINET, TTL: 60},
+					dnsmessage.AResource{A: [4]byte{127, 0, 0, 1}},
+				)
+			}
+			msg, err := b.Finish()
+			if err != nil {
+				continue
+			}
+			_, _ = pc.WriteTo(msg, addr)
+		}
+	}()
+	return pc.LocalAddr().String()
+}
+
+func TestNewDNSResolver(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(okHandler))
+	defer ts.Close()
+	resolver := NewDNSResolver(newDNSServer(t))
+
+	addrs, err := resolver.LookupHost(context.Background(), "mock-api.example")
+	assert.NoError(t, err)
+	assert.Contains(t, addrs, "127.0.0.1")
+
+	c, err := NewClient(urlWithHost(t, ts, "mock-api.example"), WithDialer(&Dialer{Resolver: resolver}))
+	if !assert.NoError(t, err) {
+		return
+	}
+	status, err := getStatus(c)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, status)
+}
@@ -0,0 +1,90 @@
+package networking
+
+import (
+	"net/http"
+)
+
+// http.RoundTripperを関数で実装するための型
+type RoundTripperFunc func(req *http.Request) (*http.Response, error)
+
+func (f RoundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// RoundTripperを包んで処理を追加するミドルウェア
+type Middleware func(next http.RoundTripper) http.RoundTripper
+
+// 送信前にリクエストを処理するフック（署名やヘッダーの付与など）
+// エラーを返すと送信せずに中断する
+type RequestHook func(req *http.Request) error
+
+// 受信後にレスポンスを処理するフック（ログやメトリクスなど）
+// エラーを返すとレスポンスのBodyを閉じて中断する
+type ResponseHook func(res *http.Response) error
+
+// 送信前のフックを追加するオプション
+// 登録した順に実行し、リダイレクト先へのリクエストでも実行する
+func WithRequestHook(hook RequestHook) Option {
+	return func(c *Client) {
+		c.requestHooks = append(c.requestHooks, hook)
+	}
+}
+
+// 受信後のフックを追加するオプション
+// 登録した順に実行し、リダイレクトのレスポンスでも実行する
+func WithResponseHook(hook ResponseHook) Option {
+	return func(c *Client) {
+		c.responseHooks = append(c.responseHooks, hook)
+	}
+}
+
+// ミドルウェアを追加するオプション
+// 先に登録したものほど外側になる（リクエストは登録順、レスポンスは逆順に通る）
+// フックはミドルウェアより内側（送信の直前・受信の直後）で実行する
+func WithMiddleware(middlewares ...Middleware) Option {
+	return func(c *Client) {
+		c.middlewares = append(c.middlewares, middlewares...)
+	}
+}
+
+// フックとミドルウェアでRoundTripperを包む
+func (c *Client) wrapTransport(transport http.RoundTripper) http.RoundTripper {
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	if len(c.requestHooks) > 0 || len(c.responseHooks) > 0 {
+		transport = hookTransport(transport, c.requestHooks, c.responseHooks)
+	}
+	for i := len(c.middlewares) - 1; i >= 0; i-- {
+		transport = c.middlewares[i](transport)
+	}
+	return transport
+}
+
+func hookTransport(next http.RoundTripper, requestHooks []RequestHook, responseHooks []ResponseHook) http.RoundTripper {
+	return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		// RoundTripperは元のリクエストを変更してはいけないため、複製してからフックに渡す
+		if len(requestHooks) > 0 {
+			req = req.Clone(req.Context())
+		}
+		for _, hook := range requestHooks {
+			if err := hook(req); err != nil {
+				if req.Body != nil {
+					req.Body.Close()
+				}
+				return nil, err
+			}
+		}
+		res, err := next.RoundTrip(req)
+		if err != nil {
+			return nil, err
+		}
+		for _, hook := range responseHooks {
+			if err := hook(res); err != nil {
+				res.Body.Close()
+				return nil, err
+			}
+		}
+		return res, nil
+	})
+}
@@ -0,0 +1,124 @@
+package networking
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// 呼び出された順を記録するミドルウェア
+func recordingMiddleware(name string, calls *[]string) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			*calls = append(*calls, name+":request")
+			res, err := next.RoundTrip(req)
+			*calls = append(*calls, name+":response")
+			return res, err
+		})
+	}
+}
+
+func TestHooks(t *testing.T) {
+	var served int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&served, 1)
+		w.Header().Set("X-Signature", r.Header.Get("X-Signature"))
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer ts.Close()
+
+	t.Run("正常ケース：ミドルウェアとフックを決まった順に実行する", func(t *testing.T) {
+		var calls []string
+		c, err := NewClient(ts.URL,
+			WithRequestHook(func(*http.Request) error { calls = append(calls, "request1"); return nil }),
+			WithResponseHook(func(*http.Response) error { calls = append(calls, "response1"); return nil }),
+			WithMiddleware(recordingMiddleware("outer", &calls), recordingMiddleware("inner", &calls)),
+			WithRequestHook(func(*http.Request) error { calls = append(calls, "request2"); return nil }),
+			WithResponseHook(func(*http.Response) error { calls = append(calls, "response2"); return nil }),
+		)
+		if !assert.NoError(t, err) {
+			return
+		}
+		status, err := getStatus(c)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, status)
+		assert.Equal(t, []string{
+			"outer:request", "inner:request", "request1", "request2",
+			"response1", "response2", "inner:response", "outer:response",
+		}, calls)
+	})
+
+	t.Run("正常ケース：フックで付与したヘッダーを送信し、元のリクエストは変更しない", func(t *testing.T) {
+		c, err := NewClient(ts.URL, WithRequestHook(func(req *http.Request) error {
+			req.Header.Set("X-Signature", "signed:"+req.Method)
+			return nil
+		}))
+		if !assert.NoError(t, err) {
+			return
+		}
+		req, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, ts.URL, nil)
+		res, err := c.Client.Do(req)
+		if !assert.NoError(t, err) {
+			return
+		}
+		defer res.Body.Close()
+		assert.Equal(t, "signed:GET", res.Header.Get("X-Signature"))
+		assert.Empty(t, req.Header.Get("X-Signature"))
+	})
+
+	t.Run("正常ケース：WithHTTPClientのClientは変更しない", func(t *testing.T) {
+		httpClient := &http.Client{}
+		c, err := NewClient(ts.URL, WithHTTPClient(httpClient), WithRequestHook(func(*http.Request) error { return nil }))
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.True(t, c.Client != httpClient)
+		assert.Nil(t, httpClient.Transport)
+		status, err := getStatus(c)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, status)
+	})
+
+	hookErr := errors.New("hook failed")
+	fail := map[string]struct {
+		options    []Option
+		wantServed int32
+	}{
+		"異常ケース：送信前のフックのエラーで中断する": {
+			options: []Option{
+				WithRequestHook(func(*http.Request) error { return hookErr }),
+				WithRequestHook(func(*http.Request) error { t.Error("unexpected call"); return nil }),
+			},
+			wantServed: 0,
+		},
+		"異常ケース：受信後のフックのエラーで中断する": {
+			options: []Option{
+				WithResponseHook(func(*http.Response) error { return hookErr }),
+				WithResponseHook(func(*http.Response) error { t.Error("unexpected call"); return nil }),
+			},
+			wantServed: 1,
+		},
+	}
+	for tn, tc := range fail {
+		t.Run(tn, func(t *testing.T) {
+			atomic.StoreInt32(&served, 0)
+			c, err := NewClient(ts.URL, tc.options...)
+			if !assert.NoError(t, err) {
+				return
+			}
+			res, err := c.NewRequestAndDo(context.Background(), http.MethodGet, c.BaseURL, nil, nil, nil)
+			if res != nil {
+				_, _ = io.Copy(io.Discard, res.Body)
+				res.Body.Close()
+			}
+			assert.ErrorIs(t, err, hookErr)
+			assert.Equal(t, tc.wantServed, atomic.LoadInt32(&served))
+		})
+	}
+}
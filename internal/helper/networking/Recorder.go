@@ -0,0 +1,367 @@
+package networking
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// 記録・再生のモード
+type RecorderMode int
+
+const (
+	// 記録済みのレスポンスを返す
+	ModeReplay RecorderMode = iota
+	// 実際にリクエストしてレスポンスを記録する
+	ModeRecord
+)
+
+// 記録・再生のモードを切り替える環境変数
+const recorderModeEnv = "VCR_MODE"
+
+// 値を伏せたヘッダーに記録する値
+const redactedValue = "[REDACTED]"
+
+// 環境変数VCR_MODEからモードを決める（recordの場合のみ記録）
+func RecorderModeFromEnv() RecorderMode {
+	if strings.EqualFold(os.Getenv(recorderModeEnv), "record") {
+		return ModeRecord
+	}
+	return ModeReplay
+}
+
+// 記録したリクエスト
+type RecordedRequest struct {
+	Method       string      `json:"method"`
+	URL          string      `json:"url"`
+	Header       http.Header `json:"header,omitempty"`
+	Body         string      `json:"body,omitempty"`
+	BodyEncoding string      `json:"body_encoding,omitempty"`
+}
+
+// 記録したレスポンス
+type RecordedResponse struct {
+	Status       int         `json:"status"`
+	Header       http.Header `json:"header,omitempty"`
+	Body         string      `json:"body,omitempty"`
+	BodyEncoding string      `json:"body_encoding,omitempty"`
+}
+
+// 1回分のリクエストとレスポンス
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+// ゴールデンファイルの内容
+type Cassette struct {
+	// 記録の出所などの補足（手書きのフィクスチャであることの明記など）
+	Note         string        `json:"note,omitempty"`
+	Interactions []Interaction `json:"interactions"`
+}
+
+// リクエストが記録と一致するかを判定する
+type Matcher func(req *http.Request, body []byte, rec RecordedRequest) bool
+
+// メソッドが一致する
+func MatchMethod(req *http.Request, _ []byte, rec RecordedRequest) bool {
+	return req.Method == rec.Method
+}
+
+// スキーム・ホスト・パスが一致する
+func MatchURL(req *http.Request, _ []byte, rec RecordedRequest) bool {
+	u, err := url.Parse(rec.URL)
+	if err != nil {
+		return false
+	}
+	return req.URL.Scheme == u.Scheme && req.URL.Host == u.Host && req.URL.Path == u.Path
+}
+
+// パスが一致する（記録時と再生時でホストが異なってもよい）
+func MatchPath(req *http.Request, _ []byte, rec RecordedRequest) bool {
+	u, err := url.Parse(rec.URL)
+	if err != nil {
+		return false
+	}
+	return req.URL.Path == u.Path
+}
+
+// クエリパラメータが順序を問わず一致する
+func MatchQuery(req *http.Request, _ []byte, rec RecordedRequest) bool {
+	u, err := url.Parse(rec.URL)
+	if err != nil {
+		return false
+	}
+	return reflect.DeepEqual(normalizeQuery(req.URL.Query()), normalizeQuery(u.Query()))
+}
+
+// ボディが一致する
+func MatchBody(_ *http.Request, body []byte, rec RecordedRequest) bool {
+	recorded, err := decodeRecordedBody(rec.Body, rec.BodyEncoding)
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(body, recorded)
+}
+
+func normalizeQuery(v url.Values) url.Values {
+	if len(v) == 0 {
+		return nil
+	}
+	return v
+}
+
+// 既定の判定条件
+var defaultMatchers = []Matcher{MatchMethod, MatchPath, MatchQuery}
+
+// Recorderの設定
+type RecorderOption func(r *Recorder)
+
+// 判定条件を置き換える
+func WithMatchers(matchers ...Matcher) RecorderOption {
+	return func(r *Recorder) {
+		r.matchers = matchers
+	}
+}
+
+// 値を伏せて記録するヘッダーを追加する（keyは常に伏せる）
+func WithRedactedHeaders(names ...string) RecorderOption {
+	return func(r *Recorder) {
+		for _, name := range names {
+			r.redacted[http.CanonicalHeaderKey(name)] = true
+		}
+	}
+}
+
+// 記録時に実際のリクエストに使うRoundTripperを指定する
+func WithRecordTransport(transport http.RoundTripper) RecorderOption {
+	return func(r *Recorder) {
+		r.transport = transport
+	}
+}
+
+// 外部APIとのやり取りをゴールデンファイルに記録・再生するRoundTripper
+// networking.WithHTTPClient(&http.Client{Transport: recorder})で組み込む
+type Recorder struct {
+	mode      RecorderMode
+	path      string
+	transport http.RoundTripper
+	matchers  []Matcher
+	redacted  map[string]bool
+
+	mu       sync.Mutex
+	cassette Cassette
+	used     []bool
+}
+
+// Recorderの初期化処理
+// 再生モードの場合はゴールデンファイルを読み込む
+func NewRecorder(path string, mode RecorderMode, options ...RecorderOption) (*Recorder, error) {
+	r := &Recorder{
+		mode:      mode,
+		path:      path,
+		transport: http.DefaultTransport,
+		matchers:  defaultMatchers,
+		redacted:  map[string]bool{"Key": true},
+	}
+	for _, option := range options {
+		option(r)
+	}
+	if mode == ModeReplay {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		if err = json.NewDecoder(f).Decode(&r.cassette); err != nil {
+			return nil, fmt.Errorf("networking: invalid cassette %s: %w", path, err)
+		}
+		r.used = make([]bool, len(r.cassette.Interactions))
+	}
+	return r, nil
+}
+
+// Recorderを使うhttp.Client
+func (r *Recorder) Client() *http.Client {
+	return &http.Client{Transport: r}
+}
+
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	// RoundTripperは呼び出し元のリクエストを変更してはならないため、複製して送る
+	req, body, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	if r.mode == ModeRecord {
+		return r.record(req, body)
+	}
+	return r.replay(req, body)
+}
+
+func (r *Recorder) record(req *http.Request, body []byte) (*http.Response, error) {
+	res, err := r.transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	resBody, err := io.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	res.Body = io.NopCloser(bytes.NewReader(resBody))
+
+	reqText, reqEnc := encodeRecordedBody(body)
+	resText, resEnc := encodeRecordedBody(resBody)
+	r.mu.Lock()
+	r.cassette.Interactions = append(r.cassette.Interactions, Interaction{
+		Request: RecordedRequest{
+			Method:       req.Method,
+			URL:          req.URL.String(),
+			Header:       r.redact(req.Header),
+			Body:         reqText,
+			BodyEncoding: reqEnc,
+		},
+		Response: RecordedResponse{
+			Status:       res.StatusCode,
+			Header:       r.redact(res.Header),
+			Body:         resText,
+			BodyEncoding: resEnc,
+		},
+	})
+	r.mu.Unlock()
+	return res, nil
+}
+
+// まだ使っていない記録のうち最初に一致したものを返す
+func (r *Recorder) replay(req *http.Request, body []byte) (*http.Response, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, in := range r.cassette.Interactions {
+		if r.used[i] || !r.match(req, body, in.Request) {
+			continue
+		}
+		r.used[i] = true
+		resBody, err := decodeRecordedBody(in.Response.Body, in.Response.BodyEncoding)
+		if err != nil {
+			return nil, err
+		}
+		header := in.Response.Header.Clone()
+		if header == nil {
+			header = http.Header{}
+		}
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", in.Response.Status, http.StatusText(in.Response.Status)),
+			StatusCode:    in.Response.Status,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        header,
+			Body:          io.NopCloser(bytes.NewReader(resBody)),
+			ContentLength: int64(len(resBody)),
+			Request:       req,
+		}, nil
+	}
+	return nil, fmt.Errorf("networking: no recorded interaction matches %s %s", req.Method, req.URL)
+}
+
+func (r *Recorder) match(req *http.Request, body []byte, rec RecordedRequest) bool {
+	for _, m := range r.matchers {
+		if !m(req, body, rec) {
+			return false
+		}
+	}
+	return true
+}
+
+// 再生されなかった記録の数
+func (r *Recorder) Unused() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	n := 0
+	for _, used := range r.used {
+		if !used {
+			n++
+		}
+	}
+	return n
+}
+
+// 記録モードの場合、記録したやり取りをゴールデンファイルに書き出す
+func (r *Recorder) Save() error {
+	if r.mode != ModeRecord {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	b, err := json.MarshalIndent(r.cassette, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(r.path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(r.path, append(b, '\n'), 0o644)
+}
+
+func (r *Recorder) redact(h http.Header) http.Header {
+	if len(h) == 0 {
+		return nil
+	}
+	redacted := h.Clone()
+	for k, vs := range redacted {
+		if r.redacted[http.CanonicalHeaderKey(k)] {
+			masked := make([]string, len(vs))
+			for i := range masked {
+				masked[i] = redactedValue
+			}
+			redacted[k] = masked
+		}
+	}
+	return redacted
+}
+
+// ボディを読み取り、同じボディを読めるリクエストの複製を返す
+// 元のリクエストのボディは読み終えて閉じる（RoundTripperの約束どおり）
+func readRequestBody(req *http.Request) (*http.Request, []byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return req, nil, nil
+	}
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, nil, err
+	}
+	clone := req.Clone(req.Context())
+	clone.Body = io.NopCloser(bytes.NewReader(body))
+	clone.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+	return clone, body, nil
+}
+
+// テキストでなければbase64で記録する
+func encodeRecordedBody(b []byte) (string, string) {
+	if utf8.Valid(b) {
+		return string(b), ""
+	}
+	return base64.StdEncoding.EncodeToString(b), "base64"
+}
+
+func decodeRecordedBody(s, encoding string) ([]byte, error) {
+	if encoding == "base64" {
+		return base64.StdEncoding.DecodeString(s)
+	}
+	return []byte(s), nil
+}
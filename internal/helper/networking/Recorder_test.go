@@ -0,0 +1,182 @@
+package networking
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/dip-dev/go-tutorial/internal/helper/test"
+)
+
+// 記録モードでmock-apiとのやり取りをゴールデンファイルに書き出す
+func recordCassette(t *testing.T, path string, options ...RecorderOption) {
+	ts := httptest.NewServer(test.NewMockAPI(test.DefaultMockData()))
+	defer ts.Close()
+
+	rec, err := NewRecorder(path, ModeRecord, options...)
+	if !assert.NoError(t, err) {
+		return
+	}
+	c, _ := NewClient(ts.URL, WithHTTPClient(rec.Client()))
+	ctx := context.Background()
+	header := map[string][]string{"key": {"dip"}}
+
+	res, err := c.NewRequestAndDo(ctx, http.MethodGet, c.BaseURL.JoinPath("/users"), header, map[string][]string{"age": {"25"}, "name": {"dip 太郎"}}, nil)
+	if assert.NoError(t, err) {
+		res.Body.Close()
+	}
+	header = map[string][]string{"key": {"dip"}, "Content-Type": {"application/x-www-form-urlencoded"}}
+	res, err = c.NewRequestAndDo(ctx, http.MethodPost, c.BaseURL.JoinPath("/users"), header, nil, url.Values{"name": {"dip 次郎"}, "age": {"24"}}.Encode())
+	if assert.NoError(t, err) {
+		res.Body.Close()
+	}
+	assert.NoError(t, rec.Save())
+}
+
+func TestRecorder(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "testdata", "users.json")
+	recordCassette(t, path)
+
+	t.Run("正常ケース：keyヘッダーを伏せて記録", func(t *testing.T) {
+		b, err := os.ReadFile(path)
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.NotContains(t, string(b), `"dip"`)
+		var cassette Cassette
+		assert.NoError(t, json.Unmarshal(b, &cassette))
+		if assert.Len(t, cassette.Interactions, 2) {
+			assert.Equal(t, []string{redactedValue}, cassette.Interactions[0].Request.Header["Key"])
+			assert.Equal(t, http.StatusOK, cassette.Interactions[0].Response.Status)
+		}
+	})
+	t.Run("正常ケース：サーバーなしで再生", func(t *testing.T) {
+		rec, err := NewRecorder(path, ModeReplay)
+		if !assert.NoError(t, err) {
+			return
+		}
+		// 記録時とホストが異なってもパスで一致させる
+		c, _ := NewClient("http://mock-api", WithHTTPClient(rec.Client()))
+		res, err := c.NewRequestAndDo(context.Background(), http.MethodGet, c.BaseURL.JoinPath("/users"), nil, map[string][]string{"name": {"dip 太郎"}, "age": {"25"}}, nil)
+		if !assert.NoError(t, err) {
+			return
+		}
+		defer res.Body.Close()
+		body, _ := io.ReadAll(res.Body)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, "application/json", res.Header.Get("Content-Type"))
+		assert.JSONEq(t, `[{"id":123456,"name":"dip 太郎","age":25}]`, string(body))
+		assert.Equal(t, 1, rec.Unused())
+	})
+	t.Run("異常ケース：一致する記録が無い", func(t *testing.T) {
+		rec, _ := NewRecorder(path, ModeReplay)
+		c, _ := NewClient("http://mock-api", WithHTTPClient(rec.Client()))
+		res, err := c.NewRequestAndDo(context.Background(), http.MethodGet, c.BaseURL.JoinPath("/users"), nil, map[string][]string{"age": {"30"}}, nil)
+		assert.Error(t, err)
+		if res != nil {
+			res.Body.Close()
+		}
+	})
+	t.Run("異常ケース：同じ記録は一度しか再生しない", func(t *testing.T) {
+		rec, _ := NewRecorder(path, ModeReplay, WithMatchers(MatchMethod, MatchPath))
+		c, _ := NewClient("http://mock-api", WithHTTPClient(rec.Client()))
+		for i, wantErr := range []bool{false, true} {
+			res, err := c.NewRequestAndDo(context.Background(), http.MethodGet, c.BaseURL.JoinPath("/users"), nil, nil, nil)
+			assert.Equal(t, wantErr, err != nil, "request %d", i)
+			if res != nil {
+				res.Body.Close()
+			}
+		}
+	})
+	t.Run("正常ケース：ボディで一致させる", func(t *testing.T) {
+		rec, _ := NewRecorder(path, ModeReplay, WithMatchers(MatchMethod, MatchBody))
+		c, _ := NewClient("http://mock-api", WithHTTPClient(rec.Client()))
+		res, err := c.NewRequestAndDo(context.Background(), http.MethodPost, c.BaseURL.JoinPath("/"), nil, nil, url.Values{"name": {"dip 次郎"}, "age": {"24"}}.Encode())
+		if assert.NoError(t, err) {
+			defer res.Body.Close()
+			body, _ := io.ReadAll(res.Body)
+			assert.Contains(t, string(body), "dip 次郎")
+		}
+
+		res, err = c.NewRequestAndDo(context.Background(), http.MethodPost, c.BaseURL.JoinPath("/"), nil, nil, "name=other")
+		assert.Error(t, err)
+		if res != nil {
+			res.Body.Close()
+		}
+	})
+	t.Run("正常ケース：呼び出し元のリクエストを変更しない", func(t *testing.T) {
+		rec, _ := NewRecorder(path, ModeReplay, WithMatchers(MatchMethod, MatchBody))
+		body := io.NopCloser(strings.NewReader(url.Values{"name": {"dip 次郎"}, "age": {"24"}}.Encode()))
+		req, _ := http.NewRequest(http.MethodPost, "http://mock-api/", body)
+		res, err := rec.RoundTrip(req)
+		if assert.NoError(t, err) {
+			res.Body.Close()
+		}
+		assert.Equal(t, body, req.Body)
+	})
+	t.Run("正常ケース：スキーム・ホストも含めて一致させる", func(t *testing.T) {
+		rec, _ := NewRecorder(path, ModeReplay, WithMatchers(MatchMethod, MatchURL))
+		c, _ := NewClient("http://mock-api", WithHTTPClient(rec.Client()))
+		res, err := c.NewRequestAndDo(context.Background(), http.MethodGet, c.BaseURL.JoinPath("/users"), nil, nil, nil)
+		assert.Error(t, err)
+		if res != nil {
+			res.Body.Close()
+		}
+	})
+	t.Run("正常ケース：ヘッダーを追加で伏せる", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "users.json")
+		recordCassette(t, path, WithRedactedHeaders("Content-Type"))
+		b, _ := os.ReadFile(path)
+		assert.NotContains(t, string(b), "application/json")
+	})
+	t.Run("異常ケース：ゴールデンファイルが無い", func(t *testing.T) {
+		_, err := NewRecorder(filepath.Join(t.TempDir(), "missing.json"), ModeReplay)
+		assert.Error(t, err)
+	})
+	t.Run("異常ケース：ゴールデンファイルが不正", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "invalid.json")
+		_ = os.WriteFile(path, []byte("{"), 0o644)
+		_, err := NewRecorder(path, ModeReplay)
+		assert.Error(t, err)
+	})
+}
+
+func TestRecorderModeFromEnv(t *testing.T) {
+	t.Setenv(recorderModeEnv, "record")
+	assert.Equal(t, ModeRecord, RecorderModeFromEnv())
+	t.Setenv(recorderModeEnv, "")
+	assert.Equal(t, ModeReplay, RecorderModeFromEnv())
+}
+
+func TestSetDefaultOptions(t *testing.T) {
+	httpClient := &http.Client{}
+	SetDefaultOptions(WithHTTPClient(httpClient))
+	defer SetDefaultOptions()
+
+	t.Run("正常ケース：全てのクライアントに適用される", func(t *testing.T) {
+		c, err := NewClient("http://mock:80")
+		assert.NoError(t, err)
+		assert.Equal(t, httpClient, c.Client)
+	})
+	t.Run("正常ケース：個別のオプションが優先される", func(t *testing.T) {
+		other := &http.Client{}
+		c, err := NewClient("http://mock:80", WithHTTPClient(other))
+		assert.NoError(t, err)
+		assert.True(t, c.Client == other)
+	})
+	t.Run("正常ケース：解除できる", func(t *testing.T) {
+		SetDefaultOptions()
+		c, _ := NewClient("http://mock:80")
+		assert.False(t, c.Client == httpClient)
+		assert.True(t, strings.HasPrefix(c.BaseURL.String(), "http://"))
+	})
+}
@@ -0,0 +1,141 @@
+package networking
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/dip-dev/go-tutorial/internal/helper/certs"
+	"github.com/dip-dev/go-tutorial/internal/helper/test"
+)
+
+// httptestのサーバー証明書をCA証明書のファイルとして書き出す
+func writeServerCA(t *testing.T, ts *httptest.Server) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "ca.pem")
+	b := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ts.Certificate().Raw})
+	assert.NoError(t, os.WriteFile(path, b, 0o600))
+	return path
+}
+
+func getStatus(c *Client) (int, error) {
+	res, err := c.NewRequestAndDo(context.Background(), http.MethodGet, c.BaseURL, nil, nil, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+	return res.StatusCode, nil
+}
+
+func TestTLS(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(okHandler))
+	defer ts.Close()
+	caFile := writeServerCA(t, ts)
+
+	success := map[string]struct {
+		options []Option
+	}{
+		"正常ケース：CA証明書を指定":      {options: []Option{WithRootCAFile(caFile)}},
+		"正常ケース：証明書のホスト名を指定する": {options: []Option{WithRootCAFile(caFile), WithServerName("example.com")}},
+		"正常ケース：TLS 1.3以上":     {options: []Option{WithRootCAFile(caFile), WithMinTLSVersion(tls.VersionTLS13)}},
+	}
+	for tn, tc := range success {
+		t.Run(tn, func(t *testing.T) {
+			c, err := NewClient(ts.URL, tc.options...)
+			if !assert.NoError(t, err) {
+				return
+			}
+			status, err := getStatus(c)
+			assert.NoError(t, err)
+			assert.Equal(t, http.StatusOK, status)
+		})
+	}
+
+	fail := map[string]struct {
+		options []Option
+	}{
+		"異常ケース：CA証明書を指定しない":  {},
+		"異常ケース：証明書のホスト名が異なる": {options: []Option{WithRootCAFile(caFile), WithServerName("mock-api")}},
+	}
+	for tn, tc := range fail {
+		t.Run(tn, func(t *testing.T) {
+			c, err := NewClient(ts.URL, tc.options...)
+			if !assert.NoError(t, err) {
+				return
+			}
+			_, err = getStatus(c)
+			assert.Error(t, err)
+		})
+	}
+	t.Run("異常ケース：CA証明書のファイルが存在しない", func(t *testing.T) {
+		_, err := NewClient(ts.URL, WithRootCAFile(filepath.Join(t.TempDir(), "missing.pem")))
+		assert.Error(t, err)
+	})
+}
+
+func TestTLSMinVersion(t *testing.T) {
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(okHandler))
+	ts.TLS = &tls.Config{MaxVersion: tls.VersionTLS12}
+	ts.StartTLS()
+	defer ts.Close()
+
+	c, err := NewClient(ts.URL, WithRootCAFile(writeServerCA(t, ts)), WithMinTLSVersion(tls.VersionTLS13))
+	if !assert.NoError(t, err) {
+		return
+	}
+	_, err = getStatus(c)
+	assert.Error(t, err)
+}
+
+func TestMutualTLS(t *testing.T) {
+	ca := test.NewCertificateAuthority(t)
+	server := ca.IssueServer(t, "mock-api", "mock-api")
+	client := ca.IssueClient(t, "go-tutorial")
+
+	tlsConfig, err := certs.ServerConfig{CertFile: server.CertFile, KeyFile: server.KeyFile, ClientCAFile: ca.CertFile}.TLSConfig()
+	if !assert.NoError(t, err) {
+		return
+	}
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Client", r.TLS.PeerCertificates[0].Subject.CommonName)
+	}))
+	ts.TLS = tlsConfig
+	ts.StartTLS()
+	defer ts.Close()
+
+	t.Run("正常ケース：クライアント証明書を送る", func(t *testing.T) {
+		c, err := NewClient(ts.URL,
+			WithRootCAFile(ca.CertFile),
+			WithServerName("mock-api"),
+			WithClientCertificateFile(client.CertFile, client.KeyFile),
+		)
+		if !assert.NoError(t, err) {
+			return
+		}
+		res, err := c.NewRequestAndDo(context.Background(), http.MethodGet, c.BaseURL, nil, nil, nil)
+		if !assert.NoError(t, err) {
+			return
+		}
+		defer res.Body.Close()
+		assert.Equal(t, "go-tutorial", res.Header.Get("X-Client"))
+	})
+	t.Run("異常ケース：クライアント証明書がない", func(t *testing.T) {
+		c, err := NewClient(ts.URL, WithRootCAFile(ca.CertFile), WithServerName("mock-api"))
+		if !assert.NoError(t, err) {
+			return
+		}
+		_, err = getStatus(c)
+		assert.Error(t, err)
+	})
+	t.Run("異常ケース：クライアント証明書のファイルが存在しない", func(t *testing.T) {
+		_, err := NewClient(ts.URL, WithClientCertificateFile("missing.pem", "missing-key.pem"))
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,279 @@
+package networking
+
+import (
+	"container/list"
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/net/http2"
+
+	"github.com/dip-dev/go-tutorial/internal/helper/certs"
+)
+
+// 接続の設定
+// 同じ設定のクライアントは1つのTransport（コネクションプール）を共有する
+type TransportConfig struct {
+	// 全てのホストで保持するアイドル接続の上限
+	MaxIdleConns int
+	// ホストごとに保持するアイドル接続の上限
+	MaxIdleConnsPerHost int
+	// アイドル接続を閉じるまでの時間
+	IdleConnTimeout time.Duration
+	// 接続を確立するまでの制限時間
+	DialTimeout time.Duration
+	// TCPのキープアライブの間隔（負の値で無効）
+	KeepAlive time.Duration
+	// TLSハンドシェイクの制限時間
+	TLSHandshakeTimeout time.Duration
+	// リクエストを送ってからレスポンスヘッダーを受け取るまでの制限時間（0で無制限）
+	ResponseHeaderTimeout time.Duration
+	// 接続を使い回さない
+	DisableKeepAlives bool
+	// TLSの接続でHTTP/2を使う
+	HTTP2 bool
+	// 平文のHTTP/2（h2c）で接続する
+	// 1つの接続を多重化して使うため、アイドル接続の設定は適用しない
+	H2C bool
+
+	// 信頼するCA証明書（PEM）のファイル（空の場合はシステムの証明書を使う）
+	RootCAFile string
+	// mTLSのクライアント証明書と秘密鍵（PEM）のファイル
+	ClientCertFile string
+	ClientKeyFile  string
+	// 許可するTLSの最小バージョン
+	MinTLSVersion uint16
+	// SNIと証明書の検証に使うサーバー名（空の場合は接続先のホスト名）
+	ServerName string
+
+	// 経由するHTTPプロキシ（空の場合は環境変数に従う）
+	ProxyURL string
+	// プロキシを経由せずに接続する宛先（NO_PROXYと同じ形式）
+	NoProxy string
+	// 接続先の名前解決の変更（nilの場合はシステムの設定を使う）
+	Dialer *Dialer
+}
+
+// 接続の設定の既定値
+func DefaultTransportConfig() TransportConfig {
+	return TransportConfig{
+		MaxIdleConns:        100,
+		MaxIdleConnsPerHost: 32,
+		IdleConnTimeout:     90 * time.Second,
+		DialTimeout:         5 * time.Second,
+		KeepAlive:           30 * time.Second,
+		TLSHandshakeTimeout: 5 * time.Second,
+		HTTP2:               true,
+		MinTLSVersion:       tls.VersionTLS12,
+	}
+}
+
+// ホストごとに保持するアイドル接続の上限を指定するオプション
+func WithMaxIdleConnsPerHost(n int) Option {
+	return func(c *Client) {
+		c.transport.MaxIdleConnsPerHost = n
+		if c.transport.MaxIdleConns < n {
+			c.transport.MaxIdleConns = n
+		}
+	}
+}
+
+// アイドル接続を閉じるまでの時間を指定するオプション
+func WithIdleConnTimeout(d time.Duration) Option {
+	return func(c *Client) {
+		c.transport.IdleConnTimeout = d
+	}
+}
+
+// 接続を確立するまでの制限時間を指定するオプション
+func WithDialTimeout(d time.Duration) Option {
+	return func(c *Client) {
+		c.transport.DialTimeout = d
+	}
+}
+
+// TLSハンドシェイクの制限時間を指定するオプション
+func WithTLSHandshakeTimeout(d time.Duration) Option {
+	return func(c *Client) {
+		c.transport.TLSHandshakeTimeout = d
+	}
+}
+
+// レスポンスヘッダーを受け取るまでの制限時間を指定するオプション
+func WithResponseHeaderTimeout(d time.Duration) Option {
+	return func(c *Client) {
+		c.transport.ResponseHeaderTimeout = d
+	}
+}
+
+// TCPのキープアライブの間隔を指定するオプション（負の値で無効）
+func WithKeepAlive(period time.Duration) Option {
+	return func(c *Client) {
+		c.transport.KeepAlive = period
+	}
+}
+
+// 接続を使い回さないオプション
+func WithDisableKeepAlives() Option {
+	return func(c *Client) {
+		c.transport.DisableKeepAlives = true
+	}
+}
+
+// TLSの接続でHTTP/2を使うかを指定するオプション
+func WithHTTP2(enabled bool) Option {
+	return func(c *Client) {
+		c.transport.HTTP2 = enabled
+	}
+}
+
+// 平文のHTTP/2（h2c）で接続するオプション
+// 外部APIがh2cに対応している場合のみ指定する
+func WithH2C() Option {
+	return func(c *Client) {
+		c.transport.H2C = true
+	}
+}
+
+// 信頼するCA証明書のファイルを指定するオプション
+// 社内の認証局が発行した証明書の外部APIへ接続する場合に指定する
+func WithRootCAFile(path string) Option {
+	return func(c *Client) {
+		c.transport.RootCAFile = path
+	}
+}
+
+// mTLSのクライアント証明書を指定するオプション
+// ファイルが更新されると読み込み直す
+func WithClientCertificateFile(certFile, keyFile string) Option {
+	return func(c *Client) {
+		c.transport.ClientCertFile = certFile
+		c.transport.ClientKeyFile = keyFile
+	}
+}
+
+// 許可するTLSの最小バージョン（tls.VersionTLS13など）を指定するオプション
+func WithMinTLSVersion(version uint16) Option {
+	return func(c *Client) {
+		c.transport.MinTLSVersion = version
+	}
+}
+
+// SNIと証明書の検証に使うサーバー名を指定するオプション
+// IPアドレスや別名で接続する場合に、証明書のホスト名を指定する
+func WithServerName(name string) Option {
+	return func(c *Client) {
+		c.transport.ServerName = name
+	}
+}
+
+// 共有するTransportの数の上限
+// 設定（証明書のファイルやDialerなど）の組み合わせが増えてもTransportが増え続けないようにする
+const maxSharedTransports = 16
+
+// 設定ごとに共有するTransport（最近使った順）
+var (
+	transportsMu sync.Mutex
+	transports   = map[TransportConfig]*list.Element{}
+	transportLRU = list.New()
+)
+
+type sharedEntry struct {
+	config    TransportConfig
+	transport http.RoundTripper
+}
+
+// 設定に対応するTransportを返す
+// ハンドラごとにNewClientを呼んでも接続を使い回せるよう、同じ設定では同じTransportを返す
+// 上限を超えた場合は最も長く使われていないTransportを外し、アイドル接続を閉じる
+func sharedTransport(config TransportConfig) (http.RoundTripper, error) {
+	transportsMu.Lock()
+	defer transportsMu.Unlock()
+	if e, ok := transports[config]; ok {
+		transportLRU.MoveToFront(e)
+		return e.Value.(*sharedEntry).transport, nil
+	}
+	t, err := NewTransport(config)
+	if err != nil {
+		return nil, err
+	}
+	transports[config] = transportLRU.PushFront(&sharedEntry{config: config, transport: t})
+	for transportLRU.Len() > maxSharedTransports {
+		oldest := transportLRU.Remove(transportLRU.Back()).(*sharedEntry)
+		delete(transports, oldest.config)
+		// 使用中の接続は閉じないため、外したTransportを使っているクライアントもそのまま使える
+		if c, ok := oldest.transport.(interface{ CloseIdleConnections() }); ok {
+			c.CloseIdleConnections()
+		}
+	}
+	return t, nil
+}
+
+// 設定からTransportを生成する
+// 証明書のファイルを読み込めない場合はエラーを返す
+func NewTransport(config TransportConfig) (http.RoundTripper, error) {
+	dialer := &net.Dialer{
+		Timeout:   config.DialTimeout,
+		KeepAlive: config.KeepAlive,
+	}
+	dial := dialer.DialContext
+	if config.Dialer != nil {
+		dial = config.Dialer.dialContext(dialer)
+	}
+	if config.H2C {
+		return &http2.Transport{
+			AllowHTTP: true,
+			// h2cはTLSを使わずに接続する
+			DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+				return dial(ctx, network, addr)
+			},
+		}, nil
+	}
+
+	tlsConfig, err := config.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+	proxy, err := config.proxy()
+	if err != nil {
+		return nil, err
+	}
+	return &http.Transport{
+		Proxy:                 proxy,
+		DialContext:           dial,
+		MaxIdleConns:          config.MaxIdleConns,
+		MaxIdleConnsPerHost:   config.MaxIdleConnsPerHost,
+		IdleConnTimeout:       config.IdleConnTimeout,
+		TLSHandshakeTimeout:   config.TLSHandshakeTimeout,
+		ResponseHeaderTimeout: config.ResponseHeaderTimeout,
+		ExpectContinueTimeout: 1 * time.Second,
+		DisableKeepAlives:     config.DisableKeepAlives,
+		ForceAttemptHTTP2:     config.HTTP2,
+		TLSClientConfig:       tlsConfig,
+	}, nil
+}
+
+func (config TransportConfig) tlsConfig() (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion: config.MinTLSVersion,
+		ServerName: config.ServerName,
+	}
+	if config.RootCAFile != "" {
+		pool, err := certs.LoadCertPool(config.RootCAFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if config.ClientCertFile != "" || config.ClientKeyFile != "" {
+		reloader, err := certs.NewReloader(config.ClientCertFile, config.ClientKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.GetClientCertificate = reloader.GetClientCertificate
+	}
+	return tlsConfig, nil
+}
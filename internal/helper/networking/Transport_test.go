@@ -0,0 +1,184 @@
+package networking
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+// 新しく確立された接続の数を数えるサーバー
+func newCountingServer(tb testing.TB, h http.Handler) (*httptest.Server, *int64) {
+	tb.Helper()
+	var conns int64
+	ts := httptest.NewUnstartedServer(h)
+	ts.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt64(&conns, 1)
+		}
+	}
+	ts.Start()
+	tb.Cleanup(ts.Close)
+	return ts, &conns
+}
+
+func okHandler(w http.ResponseWriter, _ *http.Request) {
+	_, _ = w.Write([]byte("ok"))
+}
+
+// リクエストしてボディを読み切る（読み切らないと接続を使い回せない）
+func doGet(tb testing.TB, c *Client) {
+	tb.Helper()
+	res, err := c.NewRequestAndDo(context.Background(), http.MethodGet, c.BaseURL, nil, nil, nil)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	_, _ = io.Copy(io.Discard, res.Body)
+	res.Body.Close()
+}
+
+func TestSharedTransport(t *testing.T) {
+	t.Run("正常ケース：同じ設定のクライアントはTransportを共有する", func(t *testing.T) {
+		c1, _ := NewClient("http://mock:80")
+		c2, _ := NewClient("http://mock:80")
+		assert.True(t, c1.Client != c2.Client)
+		assert.True(t, c1.Client.Transport == c2.Client.Transport)
+	})
+	t.Run("正常ケース：設定が異なる場合は別のTransport", func(t *testing.T) {
+		c1, _ := NewClient("http://mock:80")
+		c2, _ := NewClient("http://mock:80", WithMaxIdleConnsPerHost(8))
+		assert.True(t, c1.Client.Transport != c2.Client.Transport)
+	})
+	t.Run("正常ケース：上限を超えると最も長く使われていないTransportを外す", func(t *testing.T) {
+		recent, _ := NewClient("http://mock:80", WithIdleConnTimeout(time.Hour))
+		old, _ := NewClient("http://mock:80", WithIdleConnTimeout(2*time.Hour))
+		for i := 0; i < maxSharedTransports; i++ {
+			_, _ = NewClient("http://mock:80", WithIdleConnTimeout(time.Minute+time.Duration(i)))
+			// 使い続けているTransportは外さない
+			_, _ = NewClient("http://mock:80", WithIdleConnTimeout(time.Hour))
+		}
+
+		c1, _ := NewClient("http://mock:80", WithIdleConnTimeout(time.Hour))
+		assert.True(t, recent.Client.Transport == c1.Client.Transport)
+		c2, _ := NewClient("http://mock:80", WithIdleConnTimeout(2*time.Hour))
+		assert.True(t, old.Client.Transport != c2.Client.Transport)
+		assert.Equal(t, maxSharedTransports, transportLRU.Len())
+		assert.Len(t, transports, maxSharedTransports)
+	})
+	t.Run("正常ケース：WithHTTPClientのTransportは変更しない", func(t *testing.T) {
+		httpClient := &http.Client{}
+		c, _ := NewClient("http://mock:80", WithHTTPClient(httpClient), WithDialTimeout(time.Second))
+		assert.Nil(t, c.Client.Transport)
+	})
+}
+
+func TestNewTransport(t *testing.T) {
+	t.Run("正常ケース：オプションの設定を反映する", func(t *testing.T) {
+		c, _ := NewClient("http://mock:80",
+			WithMaxIdleConnsPerHost(200),
+			WithIdleConnTimeout(time.Minute),
+			WithTLSHandshakeTimeout(2*time.Second),
+			WithResponseHeaderTimeout(3*time.Second),
+			WithHTTP2(false),
+		)
+		tr, ok := c.Client.Transport.(*http.Transport)
+		if !assert.True(t, ok) {
+			return
+		}
+		assert.Equal(t, 200, tr.MaxIdleConnsPerHost)
+		// ホストごとの上限より小さくならない
+		assert.Equal(t, 200, tr.MaxIdleConns)
+		assert.Equal(t, time.Minute, tr.IdleConnTimeout)
+		assert.Equal(t, 2*time.Second, tr.TLSHandshakeTimeout)
+		assert.Equal(t, 3*time.Second, tr.ResponseHeaderTimeout)
+		assert.False(t, tr.ForceAttemptHTTP2)
+	})
+	t.Run("正常ケース：h2c", func(t *testing.T) {
+		tr, err := NewTransport(TransportConfig{H2C: true})
+		assert.NoError(t, err)
+		_, ok := tr.(*http2.Transport)
+		assert.True(t, ok)
+	})
+}
+
+func TestConnectionReuse(t *testing.T) {
+	success := map[string]struct {
+		options   []Option
+		wantConns int64
+	}{
+		"正常ケース：接続を使い回す":   {wantConns: 1},
+		"正常ケース：接続を使い回さない": {options: []Option{WithDisableKeepAlives()}, wantConns: 10},
+	}
+	for tn, tc := range success {
+		t.Run(tn, func(t *testing.T) {
+			ts, conns := newCountingServer(t, http.HandlerFunc(okHandler))
+
+			// ハンドラと同じく、リクエストごとにクライアントを生成する
+			for i := 0; i < 10; i++ {
+				c, err := NewClient(ts.URL, tc.options...)
+				assert.NoError(t, err)
+				doGet(t, c)
+			}
+
+			assert.Equal(t, tc.wantConns, atomic.LoadInt64(conns))
+		})
+	}
+}
+
+func TestH2C(t *testing.T) {
+	ts, conns := newCountingServer(t, h2c.NewHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Proto", r.Proto)
+		okHandler(w, r)
+	}), &http2.Server{}))
+
+	for i := 0; i < 5; i++ {
+		c, err := NewClient(ts.URL, WithH2C())
+		assert.NoError(t, err)
+		res, err := c.NewRequestAndDo(context.Background(), http.MethodGet, c.BaseURL, nil, nil, nil)
+		if !assert.NoError(t, err) {
+			return
+		}
+		_, _ = io.Copy(io.Discard, res.Body)
+		res.Body.Close()
+		assert.Equal(t, "HTTP/2.0", res.Header.Get("X-Proto"))
+	}
+	// 1つの接続を多重化して使う
+	assert.Equal(t, int64(1), atomic.LoadInt64(conns))
+}
+
+// 並行してリクエストした場合の接続数を比較する
+// go test -bench=. -run=^$ ./internal/helper/networking/
+func BenchmarkConnectionReuse(b *testing.B) {
+	benchmarks := map[string][]Option{
+		// 既定のhttp.Transport（ホストごとに保持するアイドル接続は2つまで）
+		"default transport": {WithHTTPClient(&http.Client{Transport: http.DefaultTransport.(*http.Transport).Clone()})},
+		"shared transport":  nil,
+		"no keep-alive":     {WithDisableKeepAlives()},
+		"h2c":               {WithH2C()},
+	}
+	for name, options := range benchmarks {
+		b.Run(name, func(b *testing.B) {
+			ts, conns := newCountingServer(b, h2c.NewHandler(http.HandlerFunc(okHandler), &http2.Server{}))
+			b.SetParallelism(16)
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					c, err := NewClient(ts.URL, options...)
+					if err != nil {
+						b.Fatal(err)
+					}
+					doGet(b, c)
+				}
+			})
+			b.ReportMetric(float64(atomic.LoadInt64(conns)), "conns")
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package openapi
+
+import (
+	"bufio"
+	"bytes"
+	"net"
+	"net/http"
+	"testing"
+)
+
+// テストで呼び出すハンドラのリクエストとレスポンスを仕様書と突き合わせる
+// routeには仕様書のパス（/users/{id}など）を指定する
+//
+// 仕様書と一致しないリクエストは、ハンドラが4xx・5xxで拒否していれば正しい挙動とみなす
+// 受け付けてしまった場合とレスポンスが一致しない場合はテストを失敗させる
+func Check(t testing.TB, route string, h http.HandlerFunc) http.HandlerFunc {
+	t.Helper()
+	d, err := Default()
+	if err != nil {
+		t.Fatalf("failed to load OpenAPI document: %v", err)
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		reqErr := d.ValidateRequest(route, r)
+
+		cw := &captureWriter{ResponseWriter: w}
+		h(cw, r)
+
+		status := cw.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		if reqErr != nil && status < http.StatusBadRequest {
+			t.Errorf("request does not match the OpenAPI document but was accepted with %d: %v", status, reqErr)
+		}
+		// 書き出しに失敗したレスポンスはクライアントに届かないため検証しない
+		if cw.writeErr != nil {
+			return
+		}
+		if err := d.ValidateResponse(route, r, status, w.Header(), cw.body.Bytes()); err != nil {
+			t.Errorf("response does not match the OpenAPI document: %v", err)
+		}
+	}
+}
+
+// レスポンスを書き出しつつ、ステータスとボディを保持する
+type captureWriter struct {
+	http.ResponseWriter
+	status   int
+	body     bytes.Buffer
+	writeErr error
+}
+
+func (w *captureWriter) WriteHeader(status int) {
+	// 1xxは中間レスポンスのため記録しない
+	if w.status == 0 && status >= http.StatusOK {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *captureWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	w.body.Write(b)
+	n, err := w.ResponseWriter.Write(b)
+	if err != nil && w.writeErr == nil {
+		w.writeErr = err
+	}
+	return n, err
+}
+
+func (w *captureWriter) Flush() {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	_ = http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// WebSocketなどで接続を引き継いだ場合は101として扱う
+func (w *captureWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, brw, err := http.NewResponseController(w.ResponseWriter).Hijack()
+	if err == nil && w.status == 0 {
+		w.status = http.StatusSwitchingProtocols
+	}
+	return conn, brw, err
+}
+
+func (w *captureWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
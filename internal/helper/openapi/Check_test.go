@@ -0,0 +1,90 @@
+package openapi
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// 検証の失敗を記録するtesting.TB
+type recordingT struct {
+	testing.TB
+	errors []string
+}
+
+func (t *recordingT) Helper() {}
+
+func (t *recordingT) Errorf(format string, args ...any) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+func TestCheck(t *testing.T) {
+	cases := map[string]struct {
+		target     string
+		handler    http.HandlerFunc
+		wantErrors []string
+	}{
+		"正常ケース：仕様通り": {
+			target: "/echo?multi=1&a=1&a=2",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = w.Write([]byte(`{"a":["1","2"]}`))
+			},
+		},
+		"正常ケース：不正なリクエストを拒否": {
+			target: "/echo?%",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusBadRequest)
+			},
+		},
+		"異常ケース：不正なリクエストを受け付けた": {
+			target: "/echo?%",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = w.Write([]byte(`{}`))
+			},
+			wantErrors: []string{"request does not match"},
+		},
+		"異常ケース：レスポンスが仕様と異なる": {
+			target: "/echo",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = w.Write([]byte(`{"a":1}`))
+			},
+			wantErrors: []string{"response does not match"},
+		},
+		"異常ケース：Content-Typeの設定漏れ": {
+			target: "/echo",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte(`{}`))
+			},
+			wantErrors: []string{`Content-Type "text/plain" is not documented`},
+		},
+		"異常ケース：定義されていないステータス": {
+			target: "/echo",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusTeapot)
+			},
+			wantErrors: []string{"status 418 is not documented"},
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			rt := &recordingT{TB: t}
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, tc.target, nil)
+			Check(rt, "/echo", tc.handler)(w, r)
+
+			assert.Len(t, rt.errors, len(tc.wantErrors), rt.errors)
+			for i, want := range tc.wantErrors {
+				if i < len(rt.errors) {
+					assert.Contains(t, rt.errors[i], want)
+				}
+			}
+		})
+	}
+}
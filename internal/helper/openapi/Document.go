@@ -0,0 +1,259 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// OpenAPI 3の仕様書
+// 検証に使用する項目のみを読み込む
+type Document struct {
+	OpenAPI    string              `json:"openapi"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components Components          `json:"components"`
+}
+
+// パスごとの操作（キーは小文字のHTTPメソッド）
+type PathItem map[string]*Operation
+
+// 操作の定義
+type Operation struct {
+	Summary     string               `json:"summary"`
+	Parameters  []*Parameter         `json:"parameters"`
+	RequestBody *RequestBody         `json:"requestBody"`
+	Responses   map[string]*Response `json:"responses"`
+}
+
+// パラメータの定義
+type Parameter struct {
+	Ref      string  `json:"$ref"`
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required"`
+	Schema   *Schema `json:"schema"`
+}
+
+// リクエストボディの定義
+type RequestBody struct {
+	Ref      string                `json:"$ref"`
+	Required bool                  `json:"required"`
+	Content  map[string]*MediaType `json:"content"`
+}
+
+// レスポンスの定義
+type Response struct {
+	Ref         string                `json:"$ref"`
+	Description string                `json:"description"`
+	Headers     map[string]*Header    `json:"headers"`
+	Content     map[string]*MediaType `json:"content"`
+}
+
+// レスポンスヘッダーの定義
+type Header struct {
+	Required bool    `json:"required"`
+	Schema   *Schema `json:"schema"`
+}
+
+// メディアタイプごとのスキーマ
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// 共通の定義
+type Components struct {
+	Parameters    map[string]*Parameter   `json:"parameters"`
+	RequestBodies map[string]*RequestBody `json:"requestBodies"`
+	Responses     map[string]*Response    `json:"responses"`
+	Schemas       map[string]*Schema      `json:"schemas"`
+}
+
+// 仕様書を読み込む
+// 参照先が存在しない$refがある場合はエラーを返す
+func Parse(b []byte) (*Document, error) {
+	d := &Document{}
+	if err := json.Unmarshal(b, d); err != nil {
+		return nil, fmt.Errorf("openapi: %w", err)
+	}
+	if !strings.HasPrefix(d.OpenAPI, "3.") {
+		return nil, fmt.Errorf("openapi: unsupported version %q", d.OpenAPI)
+	}
+	if err := d.checkRefs(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// 操作を取得する
+// HEADが定義されていない場合はGETの定義を使う
+func (d *Document) Operation(route, method string) (*Operation, bool) {
+	item, ok := d.Paths[route]
+	if !ok {
+		return nil, false
+	}
+	op, ok := item[strings.ToLower(method)]
+	if !ok && method == http.MethodHead {
+		op, ok = item["get"]
+	}
+	return op, ok
+}
+
+func (d *Document) parameter(p *Parameter) (*Parameter, error) {
+	if p.Ref == "" {
+		return p, nil
+	}
+	name, err := refName(p.Ref, "parameters")
+	if err != nil {
+		return nil, err
+	}
+	if resolved, ok := d.Components.Parameters[name]; ok {
+		return d.parameter(resolved)
+	}
+	return nil, fmt.Errorf("openapi: unresolved reference %q", p.Ref)
+}
+
+func (d *Document) requestBody(b *RequestBody) (*RequestBody, error) {
+	if b.Ref == "" {
+		return b, nil
+	}
+	name, err := refName(b.Ref, "requestBodies")
+	if err != nil {
+		return nil, err
+	}
+	if resolved, ok := d.Components.RequestBodies[name]; ok {
+		return d.requestBody(resolved)
+	}
+	return nil, fmt.Errorf("openapi: unresolved reference %q", b.Ref)
+}
+
+func (d *Document) response(r *Response) (*Response, error) {
+	if r.Ref == "" {
+		return r, nil
+	}
+	name, err := refName(r.Ref, "responses")
+	if err != nil {
+		return nil, err
+	}
+	if resolved, ok := d.Components.Responses[name]; ok {
+		return d.response(resolved)
+	}
+	return nil, fmt.Errorf("openapi: unresolved reference %q", r.Ref)
+}
+
+func (d *Document) schema(s *Schema) (*Schema, error) {
+	if s.Ref == "" {
+		return s, nil
+	}
+	name, err := refName(s.Ref, "schemas")
+	if err != nil {
+		return nil, err
+	}
+	if resolved, ok := d.Components.Schemas[name]; ok {
+		return d.schema(resolved)
+	}
+	return nil, fmt.Errorf("openapi: unresolved reference %q", s.Ref)
+}
+
+// #/components/{kind}/{name} 形式の参照から名前を取り出す
+func refName(ref, kind string) (string, error) {
+	prefix := "#/components/" + kind + "/"
+	if !strings.HasPrefix(ref, prefix) {
+		return "", fmt.Errorf("openapi: unsupported reference %q", ref)
+	}
+	return strings.TrimPrefix(ref, prefix), nil
+}
+
+// 全ての$refが解決できるか確認する
+func (d *Document) checkRefs() error {
+	var err error
+	for _, item := range d.Paths {
+		for _, op := range item {
+			for _, p := range op.Parameters {
+				if err = d.checkParameter(p); err != nil {
+					return err
+				}
+			}
+			if op.RequestBody != nil {
+				var b *RequestBody
+				if b, err = d.requestBody(op.RequestBody); err != nil {
+					return err
+				}
+				if err = d.checkContent(b.Content); err != nil {
+					return err
+				}
+			}
+			for _, r := range op.Responses {
+				if err = d.checkResponse(r); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	for _, s := range d.Components.Schemas {
+		if err = d.checkSchema(s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *Document) checkParameter(p *Parameter) error {
+	p, err := d.parameter(p)
+	if err != nil {
+		return err
+	}
+	switch p.In {
+	case "query", "header", "path":
+	default:
+		return fmt.Errorf("openapi: unsupported parameter location %q for %q", p.In, p.Name)
+	}
+	return d.checkSchema(p.Schema)
+}
+
+func (d *Document) checkResponse(r *Response) error {
+	r, err := d.response(r)
+	if err != nil {
+		return err
+	}
+	for _, h := range r.Headers {
+		if err = d.checkSchema(h.Schema); err != nil {
+			return err
+		}
+	}
+	return d.checkContent(r.Content)
+}
+
+func (d *Document) checkContent(content map[string]*MediaType) error {
+	for _, mt := range content {
+		if err := d.checkSchema(mt.Schema); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *Document) checkSchema(s *Schema) error {
+	if s == nil {
+		return nil
+	}
+	if s.Ref != "" {
+		_, err := d.schema(s)
+		return err
+	}
+	children := append([]*Schema{s.Items}, s.AnyOf...)
+	children = append(children, s.OneOf...)
+	children = append(children, s.AllOf...)
+	for _, p := range s.Properties {
+		children = append(children, p)
+	}
+	if s.AdditionalProperties != nil {
+		children = append(children, s.AdditionalProperties.Schema)
+	}
+	for _, c := range children {
+		if err := d.checkSchema(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
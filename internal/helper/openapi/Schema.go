@@ -0,0 +1,249 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// JSON Schemaのうち、仕様書で使用する項目
+type Schema struct {
+	Ref                  string             `json:"$ref"`
+	Type                 string             `json:"type"`
+	Format               string             `json:"format"`
+	Nullable             bool               `json:"nullable"`
+	Enum                 []any              `json:"enum"`
+	Properties           map[string]*Schema `json:"properties"`
+	Required             []string           `json:"required"`
+	AdditionalProperties *Additional        `json:"additionalProperties"`
+	Items                *Schema            `json:"items"`
+	MinItems             *int               `json:"minItems"`
+	MaxItems             *int               `json:"maxItems"`
+	AnyOf                []*Schema          `json:"anyOf"`
+	OneOf                []*Schema          `json:"oneOf"`
+	AllOf                []*Schema          `json:"allOf"`
+	Minimum              *float64           `json:"minimum"`
+	Maximum              *float64           `json:"maximum"`
+	MinLength            *int               `json:"minLength"`
+	MaxLength            *int               `json:"maxLength"`
+	Pattern              string             `json:"pattern"`
+}
+
+// additionalPropertiesの定義
+// 真偽値またはスキーマを指定できる
+type Additional struct {
+	Allowed bool
+	Schema  *Schema
+}
+
+func (a *Additional) UnmarshalJSON(b []byte) error {
+	if err := json.Unmarshal(b, &a.Allowed); err == nil {
+		return nil
+	}
+	a.Allowed = true
+	a.Schema = &Schema{}
+	return json.Unmarshal(b, a.Schema)
+}
+
+// スキーマに一致しない値
+type SchemaError struct {
+	// JSON Pointer形式の位置
+	Path    string
+	Message string
+}
+
+func (e *SchemaError) Error() string {
+	path := e.Path
+	if path == "" {
+		path = "/"
+	}
+	return path + ": " + e.Message
+}
+
+// JSONとしてデコードした値を検証する
+// 数値はjson.Numberでデコードしておくこと
+func (d *Document) ValidateValue(s *Schema, v any) []error {
+	var errs []error
+	d.validate(s, v, "", &errs)
+	return errs
+}
+
+func (d *Document) validate(s *Schema, v any, path string, errs *[]error) {
+	if s == nil {
+		return
+	}
+	s, err := d.schema(s)
+	if err != nil {
+		*errs = append(*errs, err)
+		return
+	}
+	fail := func(format string, args ...any) {
+		*errs = append(*errs, &SchemaError{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if v == nil {
+		if s.Nullable || (s.Type == "" && len(s.AnyOf) == 0 && len(s.OneOf) == 0 && len(s.AllOf) == 0) {
+			return
+		}
+		fail("must not be null")
+		return
+	}
+
+	for _, c := range s.AllOf {
+		d.validate(c, v, path, errs)
+	}
+	if len(s.AnyOf) > 0 && d.countMatches(s.AnyOf, v, path) == 0 {
+		fail("must match at least one schema in anyOf")
+	}
+	if len(s.OneOf) > 0 {
+		if n := d.countMatches(s.OneOf, v, path); n != 1 {
+			fail("must match exactly one schema in oneOf, but matched %d", n)
+		}
+	}
+
+	if len(s.Enum) > 0 && !inEnum(s.Enum, v) {
+		fail("must be one of %v", s.Enum)
+	}
+
+	switch s.Type {
+	case "":
+	case "object":
+		obj, ok := v.(map[string]any)
+		if !ok {
+			fail("must be an object")
+			return
+		}
+		d.validateObject(s, obj, path, errs)
+	case "array":
+		arr, ok := v.([]any)
+		if !ok {
+			fail("must be an array")
+			return
+		}
+		if s.MinItems != nil && len(arr) < *s.MinItems {
+			fail("must have at least %d items", *s.MinItems)
+		}
+		if s.MaxItems != nil && len(arr) > *s.MaxItems {
+			fail("must have at most %d items", *s.MaxItems)
+		}
+		for i, item := range arr {
+			d.validate(s.Items, item, path+"/"+strconv.Itoa(i), errs)
+		}
+	case "string":
+		str, ok := v.(string)
+		if !ok {
+			fail("must be a string")
+			return
+		}
+		n := utf8.RuneCountInString(str)
+		if s.MinLength != nil && n < *s.MinLength {
+			fail("must be at least %d characters", *s.MinLength)
+		}
+		if s.MaxLength != nil && n > *s.MaxLength {
+			fail("must be at most %d characters", *s.MaxLength)
+		}
+		if s.Pattern != "" {
+			re, err := regexp.Compile(s.Pattern)
+			if err != nil {
+				fail("invalid pattern %q: %v", s.Pattern, err)
+			} else if !re.MatchString(str) {
+				fail("must match pattern %q", s.Pattern)
+			}
+		}
+	case "integer", "number":
+		num, ok := v.(json.Number)
+		if !ok {
+			fail("must be a %s", s.Type)
+			return
+		}
+		f, err := num.Float64()
+		if err != nil {
+			fail("must be a %s", s.Type)
+			return
+		}
+		if s.Type == "integer" && f != math.Trunc(f) {
+			fail("must be an integer")
+		}
+		if s.Minimum != nil && f < *s.Minimum {
+			fail("must be greater than or equal to %v", *s.Minimum)
+		}
+		if s.Maximum != nil && f > *s.Maximum {
+			fail("must be less than or equal to %v", *s.Maximum)
+		}
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			fail("must be a boolean")
+		}
+	default:
+		fail("unsupported schema type %q", s.Type)
+	}
+}
+
+func (d *Document) validateObject(s *Schema, obj map[string]any, path string, errs *[]error) {
+	for _, name := range s.Required {
+		if _, ok := obj[name]; !ok {
+			*errs = append(*errs, &SchemaError{Path: path, Message: fmt.Sprintf("missing required property %q", name)})
+		}
+	}
+
+	// エラーの順序を安定させるため、キーを並び替えて検証する
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		child := path + "/" + escapePointer(k)
+		if p, ok := s.Properties[k]; ok {
+			d.validate(p, obj[k], child, errs)
+			continue
+		}
+		if s.AdditionalProperties == nil {
+			continue
+		}
+		if !s.AdditionalProperties.Allowed {
+			*errs = append(*errs, &SchemaError{Path: path, Message: fmt.Sprintf("unknown property %q", k)})
+			continue
+		}
+		d.validate(s.AdditionalProperties.Schema, obj[k], child, errs)
+	}
+}
+
+// 一致するスキーマの数を数える
+func (d *Document) countMatches(schemas []*Schema, v any, path string) int {
+	n := 0
+	for _, c := range schemas {
+		var errs []error
+		d.validate(c, v, path, &errs)
+		if len(errs) == 0 {
+			n++
+		}
+	}
+	return n
+}
+
+func inEnum(enum []any, v any) bool {
+	for _, e := range enum {
+		// 仕様書の数値はfloat64、検証する値はjson.Numberのため揃えて比較する
+		if num, ok := v.(json.Number); ok {
+			if f, ok := e.(float64); ok && num.String() == strconv.FormatFloat(f, 'f', -1, 64) {
+				return true
+			}
+			continue
+		}
+		if reflect.DeepEqual(e, v) {
+			return true
+		}
+	}
+	return false
+}
+
+func escapePointer(s string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(s, "~", "~0"), "/", "~1")
+}
@@ -0,0 +1,358 @@
+package openapi
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/dip-dev/go-tutorial/api"
+)
+
+// 仕様書と一致しないリクエスト・レスポンス
+type ValidationError struct {
+	Route  string
+	Method string
+	Errors []error
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		msgs = append(msgs, err.Error())
+	}
+	return fmt.Sprintf("openapi: %s %s: %s", e.Method, e.Route, strings.Join(msgs, "; "))
+}
+
+func (e *ValidationError) Unwrap() []error {
+	return e.Errors
+}
+
+var (
+	defaultDoc     *Document
+	defaultDocErr  error
+	defaultDocOnce sync.Once
+)
+
+// リポジトリの仕様書（api/openapi.json）を読み込む
+func Default() (*Document, error) {
+	defaultDocOnce.Do(func() {
+		defaultDoc, defaultDocErr = Parse(api.OpenAPI)
+	})
+	return defaultDoc, defaultDocErr
+}
+
+// リクエストを検証する
+// ボディは読み込んだ後、同じ内容で読み直せるように差し替える
+func (d *Document) ValidateRequest(route string, r *http.Request) error {
+	op, ok := d.Operation(route, r.Method)
+	if !ok {
+		return &ValidationError{Route: route, Method: r.Method, Errors: []error{errors.New("operation is not documented")}}
+	}
+
+	var errs []error
+	query, err := url.ParseQuery(r.URL.RawQuery)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("invalid query: %w", err))
+	}
+	pathParams := matchPath(route, r.URL.Path)
+
+	for _, p := range op.Parameters {
+		p, err := d.parameter(p)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		var values []string
+		switch p.In {
+		case "query":
+			values = query[p.Name]
+		case "header":
+			values = r.Header.Values(p.Name)
+		case "path":
+			// 呼び出し時のパスがルートと一致しない場合（ハンドラを直接呼ぶテストなど）は検証しない
+			if pathParams == nil {
+				continue
+			}
+			if v, ok := pathParams[p.Name]; ok {
+				values = []string{v}
+			}
+		}
+		errs = append(errs, d.validateParameter(p, values)...)
+	}
+
+	if op.RequestBody != nil {
+		errs = append(errs, d.validateRequestBody(op.RequestBody, r)...)
+	}
+
+	if len(errs) > 0 {
+		return &ValidationError{Route: route, Method: r.Method, Errors: errs}
+	}
+	return nil
+}
+
+func (d *Document) validateParameter(p *Parameter, values []string) []error {
+	if len(values) == 0 {
+		if p.Required {
+			return []error{fmt.Errorf("%s parameter %q is required", p.In, p.Name)}
+		}
+		return nil
+	}
+	s := p.Schema
+	if s != nil {
+		var err error
+		if s, err = d.schema(s); err != nil {
+			return []error{err}
+		}
+	}
+
+	var errs []error
+	check := func(s *Schema, v any) {
+		for _, err := range d.ValidateValue(s, v) {
+			errs = append(errs, fmt.Errorf("%s parameter %q: %w", p.In, p.Name, err))
+		}
+	}
+	if s != nil && s.Type == "array" {
+		items := make([]any, 0, len(values))
+		for _, v := range values {
+			items = append(items, d.coerce(s.Items, v))
+		}
+		check(s, items)
+		return errs
+	}
+	for _, v := range values {
+		check(s, d.coerce(s, v))
+	}
+	return errs
+}
+
+// 文字列のパラメータをスキーマの型に変換する
+// 変換できない場合は文字列のまま返し、型の不一致として検出させる
+func (d *Document) coerce(s *Schema, v string) any {
+	if s == nil {
+		return v
+	}
+	s, err := d.schema(s)
+	if err != nil {
+		return v
+	}
+	switch s.Type {
+	case "integer", "number":
+		if _, err := strconv.ParseFloat(v, 64); err == nil {
+			return json.Number(v)
+		}
+	case "boolean":
+		if b, err := strconv.ParseBool(v); err == nil {
+			return b
+		}
+	}
+	return v
+}
+
+func (d *Document) validateRequestBody(b *RequestBody, r *http.Request) []error {
+	b, err := d.requestBody(b)
+	if err != nil {
+		return []error{err}
+	}
+	var body []byte
+	if r.Body != nil {
+		if body, err = io.ReadAll(r.Body); err != nil {
+			return []error{fmt.Errorf("failed to read request body: %w", err)}
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	if len(body) == 0 {
+		if b.Required {
+			return []error{errors.New("request body is required")}
+		}
+		return nil
+	}
+	return d.validateContent("request body", b.Content, r.Header.Get("Content-Type"), body)
+}
+
+// レスポンスを検証する
+func (d *Document) ValidateResponse(route string, r *http.Request, status int, header http.Header, body []byte) error {
+	op, ok := d.Operation(route, r.Method)
+	if !ok {
+		// 定義されていないメソッドは405を返していれば仕様通り
+		if status == http.StatusMethodNotAllowed {
+			return nil
+		}
+		return &ValidationError{Route: route, Method: r.Method, Errors: []error{errors.New("operation is not documented")}}
+	}
+
+	res, ok := op.Responses[strconv.Itoa(status)]
+	if !ok {
+		res, ok = op.Responses[strconv.Itoa(status/100)+"XX"]
+	}
+	if !ok {
+		res, ok = op.Responses["default"]
+	}
+	if !ok {
+		return &ValidationError{Route: route, Method: r.Method, Errors: []error{fmt.Errorf("status %d is not documented", status)}}
+	}
+	res, err := d.response(res)
+	if err != nil {
+		return &ValidationError{Route: route, Method: r.Method, Errors: []error{err}}
+	}
+
+	var errs []error
+	for name, h := range res.Headers {
+		if h.Required && header.Get(name) == "" {
+			errs = append(errs, fmt.Errorf("response header %q is required", name))
+		}
+	}
+	contentType := header.Get("Content-Type")
+	if contentType == "" && len(body) > 0 {
+		// サーバーはContent-Typeが未設定の場合にボディから判定する
+		contentType = http.DetectContentType(body)
+	}
+	// ボディがない場合とHEADの場合は検証しない
+	if len(res.Content) > 0 && contentType != "" && r.Method != http.MethodHead {
+		name := fmt.Sprintf("response %d", status)
+		mt, mediaType, err := matchContent(name, res.Content, contentType)
+		switch {
+		case err != nil:
+			errs = append(errs, err)
+		case hasTrailerValues(header):
+			// トレーラーで失敗を通知したレスポンスはボディが途中で終わるため、Content-Typeのみを検証する
+		default:
+			errs = append(errs, d.validateBody(name, mt, mediaType, body)...)
+		}
+	}
+
+	if len(errs) > 0 {
+		return &ValidationError{Route: route, Method: r.Method, Errors: errs}
+	}
+	return nil
+}
+
+// 宣言したトレーラーに値が設定されているか
+func hasTrailerValues(header http.Header) bool {
+	for _, v := range header.Values("Trailer") {
+		for _, name := range strings.Split(v, ",") {
+			if header.Get(strings.TrimSpace(name)) != "" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Content-Typeに対応するスキーマでボディを検証する
+func (d *Document) validateContent(name string, content map[string]*MediaType, contentType string, body []byte) []error {
+	mt, mediaType, err := matchContent(name, content, contentType)
+	if err != nil {
+		return []error{err}
+	}
+	return d.validateBody(name, mt, mediaType, body)
+}
+
+// Content-Typeに対応する定義を取得する
+func matchContent(name string, content map[string]*MediaType, contentType string) (*MediaType, string, error) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, "", fmt.Errorf("%s: invalid Content-Type %q", name, contentType)
+	}
+	mt, ok := matchMediaType(content, mediaType)
+	if !ok {
+		return nil, "", fmt.Errorf("%s: Content-Type %q is not documented", name, mediaType)
+	}
+	return mt, mediaType, nil
+}
+
+// スキーマでボディを検証する
+// JSONとNDJSON以外は検証しない
+func (d *Document) validateBody(name string, mt *MediaType, mediaType string, body []byte) []error {
+	if mt.Schema == nil {
+		return nil
+	}
+
+	var errs []error
+	switch {
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		v, err := decodeJSON(body)
+		if err != nil {
+			return []error{fmt.Errorf("%s: invalid JSON: %w", name, err)}
+		}
+		for _, err := range d.ValidateValue(mt.Schema, v) {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		}
+	case mediaType == "application/x-ndjson":
+		sc := bufio.NewScanner(bytes.NewReader(body))
+		for line := 1; sc.Scan(); line++ {
+			if len(bytes.TrimSpace(sc.Bytes())) == 0 {
+				continue
+			}
+			v, err := decodeJSON(sc.Bytes())
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: line %d: invalid JSON: %w", name, line, err))
+				continue
+			}
+			for _, err := range d.ValidateValue(mt.Schema, v) {
+				errs = append(errs, fmt.Errorf("%s: line %d: %w", name, line, err))
+			}
+		}
+	}
+	return errs
+}
+
+// 完全一致、type/*、*/*の順に対応するメディアタイプを探す
+func matchMediaType(content map[string]*MediaType, mediaType string) (*MediaType, bool) {
+	if mt, ok := content[mediaType]; ok {
+		return mt, true
+	}
+	if i := strings.Index(mediaType, "/"); i >= 0 {
+		if mt, ok := content[mediaType[:i]+"/*"]; ok {
+			return mt, true
+		}
+	}
+	mt, ok := content["*/*"]
+	return mt, ok
+}
+
+func decodeJSON(b []byte) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	if dec.More() {
+		return nil, errors.New("unexpected data after JSON value")
+	}
+	return v, nil
+}
+
+// /users/{id} 形式のルートとパスを照合してパスパラメータを取り出す
+// 一致しない場合はnilを返す
+func matchPath(route, path string) map[string]string {
+	rs := strings.Split(strings.Trim(route, "/"), "/")
+	ps := strings.Split(strings.Trim(path, "/"), "/")
+	if len(rs) != len(ps) {
+		return nil
+	}
+	params := map[string]string{}
+	for i, seg := range rs {
+		if strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "}") {
+			v, err := url.PathUnescape(ps[i])
+			if err != nil {
+				return nil
+			}
+			params[seg[1:len(seg)-1]] = v
+			continue
+		}
+		if seg != ps[i] {
+			return nil
+		}
+	}
+	return params
+}
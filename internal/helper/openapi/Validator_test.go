@@ -0,0 +1,251 @@
+package openapi
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testSpec = `{
+  "openapi": "3.0.3",
+  "paths": {
+    "/items": {
+      "get": {
+        "parameters": [
+          {"name": "id", "in": "query", "required": true, "schema": {"type": "integer", "minimum": 1}},
+          {"name": "tag", "in": "query", "schema": {"type": "array", "items": {"type": "string", "enum": ["a", "b"]}}},
+          {"name": "X-Trace", "in": "header", "schema": {"type": "string", "minLength": 2}}
+        ],
+        "responses": {
+          "200": {
+            "description": "ok",
+            "headers": {"Vary": {"required": true, "schema": {"type": "string"}}},
+            "content": {
+              "application/json": {"schema": {"type": "array", "items": {"$ref": "#/components/schemas/Item"}}},
+              "application/x-ndjson": {"schema": {"$ref": "#/components/schemas/Item"}}
+            }
+          },
+          "4XX": {"description": "error", "content": {"text/plain": {"schema": {"type": "string"}}}}
+        }
+      },
+      "post": {
+        "requestBody": {
+          "required": true,
+          "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Item"}}}
+        },
+        "responses": {
+          "201": {"description": "created"}
+        }
+      }
+    },
+    "/items/{id}": {
+      "get": {
+        "parameters": [
+          {"name": "id", "in": "path", "required": true, "schema": {"type": "integer"}}
+        ],
+        "responses": {"default": {"description": "any"}}
+      }
+    }
+  },
+  "components": {
+    "schemas": {
+      "Item": {
+        "type": "object",
+        "required": ["id"],
+        "additionalProperties": false,
+        "properties": {
+          "id": {"type": "integer"},
+          "name": {"type": "string", "nullable": true, "maxLength": 3},
+          "kind": {"oneOf": [{"type": "string"}, {"type": "integer"}]}
+        }
+      }
+    }
+  }
+}`
+
+func TestParse(t *testing.T) {
+	t.Run("正常ケース：リポジトリの仕様書", func(t *testing.T) {
+		d, err := Default()
+		assert.NoError(t, err)
+		for _, route := range []string{"/echo", "/users", "/entries", "/openapi.json"} {
+			_, ok := d.Operation(route, http.MethodGet)
+			assert.True(t, ok, route)
+		}
+	})
+
+	fail := map[string]string{
+		"異常ケース：JSONではない":       `{`,
+		"異常ケース：OpenAPI 3ではない":  `{"swagger": "2.0"}`,
+		"異常ケース：参照先が存在しない":      `{"openapi": "3.0.0", "paths": {"/": {"get": {"responses": {"200": {"$ref": "#/components/responses/None"}}}}}}`,
+		"異常ケース：スキーマの参照先が存在しない": `{"openapi": "3.0.0", "components": {"schemas": {"A": {"items": {"$ref": "#/components/schemas/B"}}}}}`,
+		"異常ケース：未対応のパラメータの位置":   `{"openapi": "3.0.0", "paths": {"/": {"get": {"parameters": [{"name": "a", "in": "cookie"}]}}}}`,
+	}
+	for tn, tc := range fail {
+		t.Run(tn, func(t *testing.T) {
+			_, err := Parse([]byte(tc))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestValidateRequest(t *testing.T) {
+	d, err := Parse([]byte(testSpec))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	success := map[string]struct {
+		method string
+		target string
+		header map[string]string
+		body   string
+	}{
+		"正常ケース：必須パラメータのみ": {method: http.MethodGet, target: "/items?id=1"},
+		"正常ケース：配列のパラメータ":  {method: http.MethodGet, target: "/items?id=1&tag=a&tag=b"},
+		"正常ケース：ヘッダー":      {method: http.MethodGet, target: "/items?id=1", header: map[string]string{"X-Trace": "abc"}},
+		"正常ケース：パスパラメータ":   {method: http.MethodGet, target: "/items/10"},
+		"正常ケース：ルートと異なるパス": {method: http.MethodGet, target: "/?id=1"},
+		"正常ケース：リクエストボディ":  {method: http.MethodPost, target: "/items", header: map[string]string{"Content-Type": "application/json"}, body: `{"id":1,"name":null,"kind":"x"}`},
+	}
+	for tn, tc := range success {
+		t.Run(tn, func(t *testing.T) {
+			r := httptest.NewRequest(tc.method, tc.target, strings.NewReader(tc.body))
+			for k, v := range tc.header {
+				r.Header.Set(k, v)
+			}
+			route := "/items"
+			if strings.HasPrefix(tc.target, "/items/") {
+				route = "/items/{id}"
+			}
+			assert.NoError(t, d.ValidateRequest(route, r))
+		})
+	}
+
+	fail := map[string]struct {
+		method  string
+		route   string
+		target  string
+		header  map[string]string
+		body    string
+		wantErr string
+	}{
+		"異常ケース：必須パラメータがない":       {method: http.MethodGet, target: "/items", wantErr: `query parameter "id" is required`},
+		"異常ケース：整数ではない":           {method: http.MethodGet, target: "/items?id=a", wantErr: "must be a integer"},
+		"異常ケース：最小値未満":            {method: http.MethodGet, target: "/items?id=0", wantErr: "greater than or equal to 1"},
+		"異常ケース：列挙値以外":            {method: http.MethodGet, target: "/items?id=1&tag=c", wantErr: "/0: must be one of"},
+		"異常ケース：不正なクエリ":           {method: http.MethodGet, target: "/items?id=1&%", wantErr: "invalid query"},
+		"異常ケース：ヘッダーが短い":          {method: http.MethodGet, target: "/items?id=1", header: map[string]string{"X-Trace": "a"}, wantErr: "at least 2 characters"},
+		"異常ケース：パスパラメータが整数ではない":   {method: http.MethodGet, route: "/items/{id}", target: "/items/abc", wantErr: `path parameter "id"`},
+		"異常ケース：定義されていないメソッド":     {method: http.MethodDelete, target: "/items", wantErr: "operation is not documented"},
+		"異常ケース：リクエストボディがない":      {method: http.MethodPost, target: "/items", wantErr: "request body is required"},
+		"異常ケース：Content-Typeが定義外": {method: http.MethodPost, target: "/items", header: map[string]string{"Content-Type": "text/plain"}, body: "a", wantErr: `Content-Type "text/plain" is not documented`},
+		"異常ケース：必須項目がない":          {method: http.MethodPost, target: "/items", header: map[string]string{"Content-Type": "application/json"}, body: `{}`, wantErr: `missing required property "id"`},
+		"異常ケース：未定義の項目":           {method: http.MethodPost, target: "/items", header: map[string]string{"Content-Type": "application/json"}, body: `{"id":1,"Name":"a"}`, wantErr: `unknown property "Name"`},
+		"異常ケース：文字数超過":            {method: http.MethodPost, target: "/items", header: map[string]string{"Content-Type": "application/json"}, body: `{"id":1,"name":"太郎です"}`, wantErr: "/name: must be at most 3 characters"},
+		"異常ケース：oneOfに一致しない":      {method: http.MethodPost, target: "/items", header: map[string]string{"Content-Type": "application/json"}, body: `{"id":1,"kind":true}`, wantErr: "matched 0"},
+		"異常ケース：JSONではない":         {method: http.MethodPost, target: "/items", header: map[string]string{"Content-Type": "application/json"}, body: `{`, wantErr: "invalid JSON"},
+	}
+	for tn, tc := range fail {
+		t.Run(tn, func(t *testing.T) {
+			r := httptest.NewRequest(tc.method, tc.target, strings.NewReader(tc.body))
+			for k, v := range tc.header {
+				r.Header.Set(k, v)
+			}
+			route := tc.route
+			if route == "" {
+				route = "/items"
+			}
+			err := d.ValidateRequest(route, r)
+			if assert.Error(t, err) {
+				assert.Contains(t, err.Error(), tc.wantErr)
+			}
+		})
+	}
+
+	t.Run("正常ケース：検証後もボディを読める", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodPost, "/items", strings.NewReader(`{"id":1}`))
+		r.Header.Set("Content-Type", "application/json")
+		assert.NoError(t, d.ValidateRequest("/items", r))
+		buf := new(strings.Builder)
+		_, _ = io.Copy(buf, r.Body)
+		assert.Equal(t, `{"id":1}`, buf.String())
+	})
+}
+
+func TestValidateResponse(t *testing.T) {
+	d, err := Parse([]byte(testSpec))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	success := map[string]struct {
+		method string
+		route  string
+		status int
+		header http.Header
+		body   string
+	}{
+		"正常ケース：JSON":             {status: 200, header: http.Header{"Content-Type": {"application/json; charset=utf-8"}, "Vary": {"Accept"}}, body: `[{"id":1},{"id":2,"name":"abc"}]`},
+		"正常ケース：NDJSON":           {status: 200, header: http.Header{"Content-Type": {"application/x-ndjson"}, "Vary": {"Accept"}}, body: "{\"id\":1}\n{\"id\":2}\n"},
+		"正常ケース：範囲指定のステータス":       {status: 404, header: http.Header{"Content-Type": {"text/plain; charset=utf-8"}}, body: "not found\n"},
+		"正常ケース：Content-Typeの判定":  {status: 400, header: http.Header{}, body: "bad request"},
+		"正常ケース：定義されていないメソッドの405": {method: http.MethodPut, status: 405},
+		"正常ケース：default":          {route: "/items/{id}", status: 503},
+		"正常ケース：ボディの定義がない":        {method: http.MethodPost, status: 201, body: "created"},
+		"正常ケース：HEAD":             {method: http.MethodHead, route: "/items/{id}", status: 200},
+	}
+	for tn, tc := range success {
+		t.Run(tn, func(t *testing.T) {
+			method, route := tc.method, tc.route
+			if method == "" {
+				method = http.MethodGet
+			}
+			if route == "" {
+				route = "/items"
+			}
+			r := httptest.NewRequest(method, "/items", nil)
+			assert.NoError(t, d.ValidateResponse(route, r, tc.status, tc.header, []byte(tc.body)))
+		})
+	}
+
+	fail := map[string]struct {
+		method  string
+		status  int
+		header  http.Header
+		body    string
+		wantErr string
+	}{
+		"異常ケース：定義されていないステータス":    {status: 500, wantErr: "status 500 is not documented"},
+		"異常ケース：必須のヘッダーがない":       {status: 200, header: http.Header{"Content-Type": {"application/json"}}, body: `[]`, wantErr: `response header "Vary" is required`},
+		"異常ケース：Content-Typeが定義外": {status: 200, header: http.Header{"Content-Type": {"text/csv"}, "Vary": {"Accept"}}, body: "a,b", wantErr: `Content-Type "text/csv" is not documented`},
+		"異常ケース：スキーマに一致しない":       {status: 200, header: http.Header{"Content-Type": {"application/json"}, "Vary": {"Accept"}}, body: `[{"ID":1}]`, wantErr: `/0: missing required property "id"`},
+		"異常ケース：NDJSONの行が一致しない":   {status: 200, header: http.Header{"Content-Type": {"application/x-ndjson"}, "Vary": {"Accept"}}, body: "{\"id\":1}\n{\"id\":\"2\"}\n", wantErr: "line 2: /id: must be a integer"},
+		"異常ケース：JSONの後に余分なデータ":    {status: 200, header: http.Header{"Content-Type": {"application/json"}, "Vary": {"Accept"}}, body: `[] []`, wantErr: "unexpected data"},
+		"異常ケース：定義されていないメソッド":     {method: http.MethodDelete, status: 200, wantErr: "operation is not documented"},
+	}
+	for tn, tc := range fail {
+		t.Run(tn, func(t *testing.T) {
+			method := tc.method
+			if method == "" {
+				method = http.MethodGet
+			}
+			r := httptest.NewRequest(method, "/items", nil)
+			err := d.ValidateResponse("/items", r, tc.status, tc.header, []byte(tc.body))
+			if assert.Error(t, err) {
+				assert.Contains(t, err.Error(), tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestMatchPath(t *testing.T) {
+	assert.Equal(t, map[string]string{"id": "1"}, matchPath("/users/{id}", "/users/1"))
+	assert.Equal(t, map[string]string{"id": "dip 太郎"}, matchPath("/users/{id}", "/users/dip%20%E5%A4%AA%E9%83%8E"))
+	assert.Equal(t, map[string]string{}, matchPath("/users", "/users/"))
+	assert.Nil(t, matchPath("/users/{id}", "/users"))
+	assert.Nil(t, matchPath("/users/{id}", "/entries/1"))
+}
@@ -0,0 +1,100 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// 中継するルートの設定
+type Config struct {
+	Routes []Route `json:"routes"`
+}
+
+// 1つのルートの設定
+type Route struct {
+	// 受け付けるパス（/で終わる場合は配下のパスも受け付ける）
+	Prefix string `json:"prefix"`
+	// 中継先の外部APIのパス（接頭辞以降のパスを連結する）
+	Upstream string `json:"upstream"`
+	// 外部APIへ付与するkeyヘッダー（空の場合はリクエストの値をそのまま使う）
+	Key string `json:"key"`
+	// 受け付けるメソッド（空の場合は全てのメソッド）
+	Methods []string `json:"methods"`
+	// 外部APIの応答を待つ時間（空の場合は既定値）
+	Timeout Duration `json:"timeout"`
+}
+
+// "5s"のような文字列で指定する時間
+type Duration time.Duration
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return errors.New(`duration must be a string such as "5s"`)
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// 設定ファイル（JSON）を読み込む
+func LoadConfig(path string) (*Config, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	var c Config
+	if err := dec.Decode(&c); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if err := c.validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &c, nil
+}
+
+func (c *Config) validate() error {
+	prefixes := map[string]bool{}
+	for i, route := range c.Routes {
+		if err := route.validate(); err != nil {
+			return fmt.Errorf("routes[%d]: %w", i, err)
+		}
+		if prefixes[route.Prefix] {
+			return fmt.Errorf("routes[%d]: duplicate prefix %q", i, route.Prefix)
+		}
+		prefixes[route.Prefix] = true
+	}
+	return nil
+}
+
+func (r Route) validate() error {
+	if !strings.HasPrefix(r.Prefix, "/") {
+		return fmt.Errorf("prefix %q must start with /", r.Prefix)
+	}
+	if !strings.HasPrefix(r.Upstream, "/") {
+		return fmt.Errorf("upstream %q must start with /", r.Upstream)
+	}
+	for _, method := range r.Methods {
+		if method == "" || method != strings.ToUpper(method) {
+			return fmt.Errorf("method %q must be upper case", method)
+		}
+	}
+	if r.Timeout < 0 {
+		return errors.New("timeout must not be negative")
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package proxy
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeConfig(t *testing.T, body string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "proxy.json")
+	assert.NoError(t, os.WriteFile(path, []byte(body), 0o600))
+	return path
+}
+
+func TestLoadConfig(t *testing.T) {
+	t.Run("正常ケース：設定ファイルを読み込む", func(t *testing.T) {
+		path := writeConfig(t, `{"routes":[{"prefix":"/jobs/","upstream":"/entries","key":"dip","methods":["GET"],"timeout":"1.5s"}]}`)
+
+		c, err := LoadConfig(path)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []Route{{
+			Prefix:   "/jobs/",
+			Upstream: "/entries",
+			Key:      "dip",
+			Methods:  []string{"GET"},
+			Timeout:  Duration(1500 * time.Millisecond),
+		}}, c.Routes)
+	})
+
+	fail := map[string]struct {
+		body string
+	}{
+		"異常ケース：JSONが不正":     {body: `{"routes":`},
+		"異常ケース：未知の項目":       {body: `{"routes":[{"prefix":"/a","upstream":"/b","path":"/c"}]}`},
+		"異常ケース：接頭辞が/で始まらない": {body: `{"routes":[{"prefix":"a","upstream":"/b"}]}`},
+		"異常ケース：中継先が/で始まらない": {body: `{"routes":[{"prefix":"/a","upstream":"b"}]}`},
+		"異常ケース：メソッドが小文字":    {body: `{"routes":[{"prefix":"/a","upstream":"/b","methods":["get"]}]}`},
+		"異常ケース：時間の形式が不正":    {body: `{"routes":[{"prefix":"/a","upstream":"/b","timeout":5}]}`},
+		"異常ケース：時間が負の値":      {body: `{"routes":[{"prefix":"/a","upstream":"/b","timeout":"-1s"}]}`},
+		"異常ケース：接頭辞が重複している":  {body: `{"routes":[{"prefix":"/a","upstream":"/b"},{"prefix":"/a","upstream":"/c"}]}`},
+	}
+	for tn, tc := range fail {
+		t.Run(tn, func(t *testing.T) {
+			_, err := LoadConfig(writeConfig(t, tc.body))
+			assert.Error(t, err)
+		})
+	}
+	t.Run("異常ケース：ファイルが存在しない", func(t *testing.T) {
+		_, err := LoadConfig(filepath.Join(t.TempDir(), "missing.json"))
+		assert.ErrorIs(t, err, os.ErrNotExist)
+	})
+}
@@ -0,0 +1,169 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dip-dev/go-tutorial/internal/helper/networking"
+)
+
+// 外部APIの応答を待つ時間の既定値
+const defaultTimeout = 30 * time.Second
+
+// 設定したルートへのリクエストを外部APIへそのまま中継するハンドラ
+type Handler struct {
+	client   *networking.Client
+	route    Route
+	response *ResponseWriter
+}
+
+// ハンドラの初期化処理
+// responseがnilの場合は既定の設定でレスポンスを中継する
+func NewHandler(client *networking.Client, route Route, response *ResponseWriter) (*Handler, error) {
+	if err := route.validate(); err != nil {
+		return nil, err
+	}
+	if response == nil {
+		response = NewResponseWriter()
+	}
+	methods := append([]string(nil), route.Methods...)
+	sort.Strings(methods)
+	route.Methods = methods
+	return &Handler{client: client, route: route, response: response}, nil
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !h.allows(r.Method) {
+		w.Header().Set("Allow", strings.Join(h.route.Methods, ", "))
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	timeout := time.Duration(h.route.Timeout)
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	defer cancel()
+
+	// ボディは読み込みながら送る
+	// 長さが分かっている場合はContent-Lengthを付けて送る（不明な場合のみchunked）
+	var body io.Reader = http.NoBody
+	if r.ContentLength != 0 && r.Body != nil && r.Body != http.NoBody {
+		body = r.Body
+	}
+	// 複数の値を持つヘッダーもそのまま渡すため、リクエストはここで作成する
+	// （NewRequestAndDoは同じ名前のヘッダーを1つの値で上書きする）
+	req, err := http.NewRequestWithContext(ctx, r.Method, h.upstreamURL(r.URL.Path).String(), body)
+	if err != nil {
+		http.Error(w, "Bad Gateway", http.StatusBadGateway)
+		return
+	}
+	if body != http.NoBody {
+		req.ContentLength = r.ContentLength
+	}
+
+	// ヘッダーの設定
+	hop := hopByHop(r.Header)
+	for k, vs := range r.Header {
+		if !hop[k] {
+			req.Header[k] = vs
+		}
+	}
+	if h.route.Key != "" {
+		req.Header.Set("Key", h.route.Key)
+	}
+	// クエリパラメータの設定
+	if q := r.URL.Query(); len(q) > 0 {
+		req.URL.RawQuery = q.Encode()
+	}
+
+	// 外部APIへリクエスト
+	res, err := h.client.Client.Do(req)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			http.Error(w, "Gateway Timeout", http.StatusGatewayTimeout)
+			return
+		}
+		http.Error(w, "Bad Gateway", http.StatusBadGateway)
+		return
+	}
+	defer res.Body.Close()
+
+	if loc := res.Header.Get("Location"); loc != "" {
+		res.Header.Set("Location", h.rewriteLocation(r, loc))
+	}
+	// ヘッダー・ステータスコード・ボディをコピー
+	// ステータスコードを送った後のため、失敗した場合は接続を切断してクライアントに途中で切れたことを伝える
+	if err := h.response.WriteResponse(w, res); err != nil {
+		log.Printf("proxy %s: failed to copy response: %+v", h.route.Prefix, err)
+		panic(http.ErrAbortHandler)
+	}
+}
+
+func (h *Handler) allows(method string) bool {
+	if len(h.route.Methods) == 0 {
+		return true
+	}
+	i := sort.SearchStrings(h.route.Methods, method)
+	return i < len(h.route.Methods) && h.route.Methods[i] == method
+}
+
+// 接頭辞以降のパスを外部APIのパスに連結する
+// ..で外部APIのパスより上の階層を指定できないよう、先に正規化する
+func (h *Handler) upstreamURL(requestPath string) *url.URL {
+	rest := strings.TrimPrefix(requestPath, strings.TrimSuffix(h.route.Prefix, "/"))
+	u := h.client.BaseURL.JoinPath(h.route.Upstream)
+	if cleaned := path.Clean("/" + rest); cleaned != "/" {
+		u = u.JoinPath(cleaned)
+	}
+	if strings.HasSuffix(rest, "/") && !strings.HasSuffix(u.Path, "/") {
+		u.Path += "/"
+	}
+	return u
+}
+
+// 外部APIのパスを指すLocationヘッダーを、このサーバーのパスに書き換える
+// 外部API以外を指す場合はそのまま返す
+func (h *Handler) rewriteLocation(r *http.Request, loc string) string {
+	u, err := url.Parse(loc)
+	if err != nil || !strings.HasPrefix(u.Path, "/") {
+		return loc
+	}
+	base := h.client.BaseURL
+	if u.Host != "" && (u.Host != base.Host || (u.Scheme != "" && u.Scheme != base.Scheme)) {
+		return loc
+	}
+
+	// ベースURLにパスがない場合、JoinPathの結果は/で始まらない
+	upstream := strings.TrimSuffix("/"+strings.TrimPrefix(base.JoinPath(h.route.Upstream).Path, "/"), "/")
+	rest, ok := strings.CutPrefix(u.Path, upstream)
+	if !ok || (rest != "" && !strings.HasPrefix(rest, "/")) {
+		return loc
+	}
+
+	// バージョンの接頭辞（/v1など）はルーターで取り除かれているため、元のパスから復元する
+	external := r.URL.Path
+	if orig, err := url.ParseRequestURI(r.RequestURI); err == nil && orig.Path != "" {
+		external = orig.Path
+	}
+	versionPrefix := strings.TrimSuffix(external, r.URL.Path)
+
+	out := &url.URL{
+		Path:     versionPrefix + strings.TrimSuffix(h.route.Prefix, "/") + rest,
+		RawQuery: u.RawQuery,
+		Fragment: u.Fragment,
+	}
+	if out.Path == "" {
+		out.Path = "/"
+	}
+	return out.String()
+}
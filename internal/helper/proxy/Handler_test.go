@@ -0,0 +1,284 @@
+package proxy
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/dip-dev/go-tutorial/internal/helper/networking"
+	"github.com/dip-dev/go-tutorial/internal/helper/test"
+)
+
+// 受け取ったリクエストの内容
+type upstreamRequest struct {
+	Method string      `json:"method"`
+	Path   string      `json:"path"`
+	Query  string      `json:"query"`
+	Header http.Header `json:"header"`
+	Body   string      `json:"body"`
+	// 受け取ったボディの長さ（-1は不明）とTransfer-Encoding
+	ContentLength    int64    `json:"content_length"`
+	TransferEncoding []string `json:"transfer_encoding"`
+}
+
+// 受け取ったリクエストをJSONで返す外部API
+func newUpstream(t *testing.T) *httptest.Server {
+	t.Helper()
+	var ts *httptest.Server
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/slow":
+			select {
+			case <-r.Context().Done():
+			case <-time.After(time.Second):
+			}
+			return
+		case "/api/created":
+			w.Header().Set("Location", ts.URL+"/api/items/1?view=full")
+			w.WriteHeader(http.StatusCreated)
+			return
+		}
+		b, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(upstreamRequest{
+			Method: r.Method,
+			Path:   r.URL.Path,
+			Query:  r.URL.RawQuery,
+			Header: r.Header,
+			Body:   string(b),
+
+			ContentLength:    r.ContentLength,
+			TransferEncoding: r.TransferEncoding,
+		})
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func newTestHandler(t *testing.T, baseURL string, route Route) *Handler {
+	t.Helper()
+	c, err := networking.NewClient(baseURL)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	h, err := NewHandler(c, route, nil)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	return h
+}
+
+func TestHandler(t *testing.T) {
+	ts := newUpstream(t)
+	route := Route{Prefix: "/jobs/", Upstream: "/api", Key: "dip", Methods: []string{"POST", "GET"}}
+
+	success := map[string]struct {
+		method   string
+		target   string
+		body     string
+		header   http.Header
+		wantPath string
+		wantQ    string
+	}{
+		"正常ケース：接頭辞以降のパスを連結": {
+			method:   http.MethodGet,
+			target:   "/jobs/123",
+			wantPath: "/api/123",
+		},
+		"正常ケース：接頭辞のみ": {
+			method:   http.MethodGet,
+			target:   "/jobs/",
+			wantPath: "/api/",
+		},
+		"正常ケース：クエリパラメータを渡す": {
+			method:   http.MethodGet,
+			target:   "/jobs/search?q=go&q=dip",
+			wantPath: "/api/search",
+			wantQ:    "q=go&q=dip",
+		},
+		"正常ケース：ボディを渡す": {
+			method:   http.MethodPost,
+			target:   "/jobs/",
+			body:     `{"name":"dip"}`,
+			header:   http.Header{"Content-Type": {"application/json"}},
+			wantPath: "/api/",
+		},
+	}
+	for tn, tc := range success {
+		t.Run(tn, func(t *testing.T) {
+			h := newTestHandler(t, ts.URL, route)
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(tc.method, "http://localhost"+tc.target, strings.NewReader(tc.body))
+			for k, vs := range tc.header {
+				r.Header[k] = vs
+			}
+
+			h.ServeHTTP(w, r)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			var got upstreamRequest
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+			assert.Equal(t, tc.method, got.Method)
+			assert.Equal(t, tc.wantPath, got.Path)
+			assert.Equal(t, tc.wantQ, got.Query)
+			assert.Equal(t, tc.body, got.Body)
+			// 長さが分かっているボディはchunkedにしない
+			assert.Equal(t, int64(len(tc.body)), got.ContentLength)
+			assert.Empty(t, got.TransferEncoding)
+			assert.Equal(t, "dip", got.Header.Get("Key"))
+			assert.Equal(t, "1.1 go-tutorial", w.Header().Get("Via"))
+		})
+	}
+
+	t.Run("正常ケース：ヘッダーを渡す", func(t *testing.T) {
+		h := newTestHandler(t, ts.URL, route)
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "http://localhost/jobs/1", nil)
+		r.Header.Set("Key", "client")
+		r.Header.Add("Cookie", "a=1")
+		r.Header.Add("Cookie", "b=2")
+		r.Header.Set("Connection", "X-Hop")
+		r.Header.Set("X-Hop", "1")
+
+		h.ServeHTTP(w, r)
+
+		var got upstreamRequest
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+		// 設定のkeyで上書きする
+		assert.Equal(t, []string{"dip"}, got.Header.Values("Key"))
+		assert.Equal(t, []string{"a=1", "b=2"}, got.Header.Values("Cookie"))
+		assert.Empty(t, got.Header.Get("X-Hop"))
+	})
+	t.Run("正常ケース：keyの設定がない場合はリクエストの値を使う", func(t *testing.T) {
+		h := newTestHandler(t, ts.URL, Route{Prefix: "/jobs/", Upstream: "/api"})
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodDelete, "http://localhost/jobs/1", nil)
+		r.Header.Set("Key", "client")
+
+		h.ServeHTTP(w, r)
+
+		var got upstreamRequest
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+		assert.Equal(t, http.MethodDelete, got.Method)
+		assert.Equal(t, "client", got.Header.Get("Key"))
+	})
+	t.Run("正常ケース：Locationヘッダーを書き換える", func(t *testing.T) {
+		h := newTestHandler(t, ts.URL, route)
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "http://localhost/v1/jobs/created", nil)
+		// バージョンの接頭辞はルーターで取り除かれる
+		r.URL.Path = "/jobs/created"
+
+		h.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.Equal(t, "/v1/jobs/items/1?view=full", w.Header().Get("Location"))
+	})
+	t.Run("正常ケース：長さが不明なボディはchunkedで送る", func(t *testing.T) {
+		h := newTestHandler(t, ts.URL, route)
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "http://localhost/jobs/", strings.NewReader(`{"name":"dip"}`))
+		r.ContentLength = -1
+
+		h.ServeHTTP(w, r)
+
+		var got upstreamRequest
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+		assert.Equal(t, `{"name":"dip"}`, got.Body)
+		assert.Equal(t, int64(-1), got.ContentLength)
+		assert.Equal(t, []string{"chunked"}, got.TransferEncoding)
+	})
+	t.Run("異常ケース：レスポンスを書き込めない場合は接続を切断する", func(t *testing.T) {
+		h := newTestHandler(t, ts.URL, route)
+		r := httptest.NewRequest(http.MethodGet, "http://localhost/jobs/1", nil)
+		errW := &test.ErrorResponseWriter{}
+
+		assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+			h.ServeHTTP(errW, r)
+		})
+		// ステータスコードは上書きしない
+		assert.Equal(t, http.StatusOK, errW.Code())
+	})
+	t.Run("異常ケース：外部APIの応答が制限時間を超える", func(t *testing.T) {
+		h := newTestHandler(t, ts.URL, Route{Prefix: "/jobs/", Upstream: "/api", Timeout: Duration(10 * time.Millisecond)})
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "http://localhost/jobs/slow", nil)
+
+		h.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusGatewayTimeout, w.Code)
+	})
+	t.Run("異常ケース：外部APIへ接続できない", func(t *testing.T) {
+		h := newTestHandler(t, "http://127.0.0.1:1", route)
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "http://localhost/jobs/1", nil)
+
+		h.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusBadGateway, w.Code)
+	})
+	t.Run("異常ケース：メソッドが一致しない", func(t *testing.T) {
+		h := newTestHandler(t, ts.URL, route)
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPut, "http://localhost/jobs/1", nil)
+
+		h.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+		assert.Equal(t, "GET, POST", w.Header().Get("Allow"))
+	})
+	t.Run("異常ケース：設定が不正", func(t *testing.T) {
+		c, _ := networking.NewClient(ts.URL)
+		_, err := NewHandler(c, Route{Prefix: "jobs", Upstream: "/api"}, nil)
+		assert.Error(t, err)
+	})
+}
+
+func TestUpstreamURL(t *testing.T) {
+	c, _ := networking.NewClient("http://mock-api/base")
+	success := map[string]struct {
+		route Route
+		path  string
+		want  string
+	}{
+		"正常ケース：配下のパス":       {route: Route{Prefix: "/jobs/", Upstream: "/entries"}, path: "/jobs/1/detail", want: "http://mock-api/base/entries/1/detail"},
+		"正常ケース：完全一致のルート":    {route: Route{Prefix: "/all-users", Upstream: "/users"}, path: "/all-users", want: "http://mock-api/base/users"},
+		"正常ケース：末尾の/を残す":     {route: Route{Prefix: "/jobs/", Upstream: "/entries"}, path: "/jobs/1/", want: "http://mock-api/base/entries/1/"},
+		"正常ケース：上の階層は指定できない": {route: Route{Prefix: "/jobs/", Upstream: "/entries"}, path: "/jobs/../../admin", want: "http://mock-api/base/entries/admin"},
+	}
+	for tn, tc := range success {
+		t.Run(tn, func(t *testing.T) {
+			h, err := NewHandler(c, tc.route, nil)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.want, h.upstreamURL(tc.path).String())
+		})
+	}
+}
+
+func TestRewriteLocation(t *testing.T) {
+	c, _ := networking.NewClient("http://mock-api")
+	h, _ := NewHandler(c, Route{Prefix: "/jobs/", Upstream: "/entries"}, nil)
+	r := httptest.NewRequest(http.MethodPost, "http://localhost/jobs/", nil)
+
+	success := map[string]struct {
+		loc  string
+		want string
+	}{
+		"正常ケース：外部APIの絶対URL":   {loc: "http://mock-api/entries/1", want: "/jobs/1"},
+		"正常ケース：外部APIのパス":      {loc: "/entries?user_id=1", want: "/jobs?user_id=1"},
+		"正常ケース：別のホストはそのまま":    {loc: "http://example.com/entries/1", want: "http://example.com/entries/1"},
+		"正常ケース：中継先以外のパスはそのまま": {loc: "/entries-old/1", want: "/entries-old/1"},
+		"正常ケース：相対パスはそのまま":     {loc: "1", want: "1"},
+	}
+	for tn, tc := range success {
+		t.Run(tn, func(t *testing.T) {
+			assert.Equal(t, tc.want, h.rewriteLocation(r, tc.loc))
+		})
+	}
+}
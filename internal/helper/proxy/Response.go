@@ -0,0 +1,144 @@
+package proxy
+
+import (
+	"io"
+	"net/http"
+	"net/textproto"
+	"strconv"
+	"strings"
+)
+
+// Viaヘッダーに付与する既定の名前
+const defaultPseudonym = "go-tutorial"
+
+// ホップバイホップヘッダー（RFC 9110 7.6.1）
+// 接続ごとのヘッダーのため、中継先へは転送しない
+var hopHeaders = []string{
+	"Connection",
+	"Proxy-Connection",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+// 外部APIのレスポンスをクライアントへ中継するWriter
+type ResponseWriter struct {
+	allow     map[string]bool
+	deny      map[string]bool
+	pseudonym string
+}
+
+// ResponseWriterのオプション
+type Option func(p *ResponseWriter)
+
+// 転送するヘッダーを指定したものだけに絞るオプション
+func WithAllowedHeaders(names ...string) Option {
+	return func(p *ResponseWriter) {
+		p.allow = headerSet(p.allow, names)
+	}
+}
+
+// 転送しないヘッダーを指定するオプション
+// 許可リストより優先する
+func WithDeniedHeaders(names ...string) Option {
+	return func(p *ResponseWriter) {
+		p.deny = headerSet(p.deny, names)
+	}
+}
+
+// Viaヘッダーに付与する名前を指定するオプション
+// 空文字を指定するとViaヘッダーを付与しない
+func WithPseudonym(pseudonym string) Option {
+	return func(p *ResponseWriter) {
+		p.pseudonym = pseudonym
+	}
+}
+
+// ResponseWriterの初期化処理
+func NewResponseWriter(options ...Option) *ResponseWriter {
+	p := &ResponseWriter{pseudonym: defaultPseudonym}
+	for _, option := range options {
+		option(p)
+	}
+	return p
+}
+
+func headerSet(set map[string]bool, names []string) map[string]bool {
+	if set == nil {
+		set = map[string]bool{}
+	}
+	for _, name := range names {
+		set[textproto.CanonicalMIMEHeaderKey(name)] = true
+	}
+	return set
+}
+
+// レスポンスのヘッダー・ステータスコード・ボディをクライアントへ書き込む
+// Content-Lengthは外部APIのヘッダーではなく、実際に読み込むボディの長さから設定する
+func (p *ResponseWriter) WriteResponse(w http.ResponseWriter, res *http.Response) error {
+	p.CopyHeader(w.Header(), res)
+	if res.ContentLength >= 0 && !res.Uncompressed {
+		w.Header().Set("Content-Length", strconv.FormatInt(res.ContentLength, 10))
+	}
+	w.WriteHeader(res.StatusCode)
+	_, err := io.Copy(w, res.Body)
+	return err
+}
+
+// 転送できるヘッダーをコピーし、Viaヘッダーを追加する
+// 複数の値を持つヘッダー（Set-Cookieなど）は全ての値をコピーする
+// ボディを書き換える場合があるため、Content-Lengthはコピーしない
+func (p *ResponseWriter) CopyHeader(dst http.Header, res *http.Response) {
+	hop := hopByHop(res.Header)
+	for k, vs := range res.Header {
+		if hop[k] || k == "Content-Length" || !p.forwards(k) {
+			continue
+		}
+		for _, v := range vs {
+			dst.Add(k, v)
+		}
+	}
+	if p.pseudonym != "" {
+		dst.Add("Via", receivedProtocol(res)+" "+p.pseudonym)
+	}
+}
+
+// 転送しないホップバイホップヘッダーの一覧
+// Connectionヘッダーで指定されたヘッダーもホップバイホップとして扱う
+func hopByHop(h http.Header) map[string]bool {
+	hop := map[string]bool{}
+	for _, name := range hopHeaders {
+		hop[name] = true
+	}
+	for _, v := range h.Values("Connection") {
+		for _, name := range strings.Split(v, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				hop[textproto.CanonicalMIMEHeaderKey(name)] = true
+			}
+		}
+	}
+	return hop
+}
+
+// 許可リスト・拒否リストに従って転送するか
+func (p *ResponseWriter) forwards(name string) bool {
+	if p.deny[name] {
+		return false
+	}
+	return len(p.allow) == 0 || p.allow[name]
+}
+
+// Viaヘッダーに記載するプロトコルのバージョン（HTTP/1.1は"1.1"、HTTP/2は"2"）
+func receivedProtocol(res *http.Response) string {
+	if res.ProtoMajor >= 2 {
+		return strconv.Itoa(res.ProtoMajor)
+	}
+	if res.ProtoMajor == 0 {
+		return "1.1"
+	}
+	return strconv.Itoa(res.ProtoMajor) + "." + strconv.Itoa(res.ProtoMinor)
+}
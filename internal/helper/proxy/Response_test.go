@@ -0,0 +1,149 @@
+package proxy
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/dip-dev/go-tutorial/internal/helper/test"
+)
+
+func newResponse(header http.Header, body string) *http.Response {
+	return &http.Response{
+		StatusCode:    http.StatusCreated,
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		ContentLength: int64(len(body)),
+		Body:          io.NopCloser(strings.NewReader(body)),
+	}
+}
+
+func TestCopyHeader(t *testing.T) {
+	upstream := http.Header{
+		"Content-Type":      {"application/json"},
+		"Content-Length":    {"999"},
+		"Set-Cookie":        {"a=1", "b=2"},
+		"Connection":        {"keep-alive, X-Internal"},
+		"Keep-Alive":        {"timeout=5"},
+		"Transfer-Encoding": {"chunked"},
+		"X-Internal":        {"secret"},
+		"Server":            {"mock-api"},
+		"Via":               {"1.0 upstream"},
+	}
+
+	success := map[string]struct {
+		options  []Option
+		protoMaj int
+		want     http.Header
+	}{
+		"正常ケース：ホップバイホップヘッダーを除く": {
+			want: http.Header{
+				"Content-Type": {"application/json"},
+				"Set-Cookie":   {"a=1", "b=2"},
+				"Server":       {"mock-api"},
+				"Via":          {"1.0 upstream", "1.1 go-tutorial"},
+			},
+		},
+		"正常ケース：許可リスト": {
+			options: []Option{WithAllowedHeaders("content-type", "set-cookie", "keep-alive")},
+			want: http.Header{
+				"Content-Type": {"application/json"},
+				"Set-Cookie":   {"a=1", "b=2"},
+				"Via":          {"1.1 go-tutorial"},
+			},
+		},
+		"正常ケース：拒否リストは許可リストより優先": {
+			options: []Option{WithAllowedHeaders("Content-Type", "Server"), WithDeniedHeaders("server")},
+			want: http.Header{
+				"Content-Type": {"application/json"},
+				"Via":          {"1.1 go-tutorial"},
+			},
+		},
+		"正常ケース：Viaを付与しない": {
+			options: []Option{WithPseudonym(""), WithDeniedHeaders("Set-Cookie", "Server")},
+			want: http.Header{
+				"Content-Type": {"application/json"},
+				"Via":          {"1.0 upstream"},
+			},
+		},
+		"正常ケース：HTTP/2": {
+			options:  []Option{WithAllowedHeaders("Content-Type")},
+			protoMaj: 2,
+			want: http.Header{
+				"Content-Type": {"application/json"},
+				"Via":          {"2 go-tutorial"},
+			},
+		},
+	}
+	for tn, tc := range success {
+		t.Run(tn, func(t *testing.T) {
+			res := newResponse(upstream, "")
+			if tc.protoMaj != 0 {
+				res.ProtoMajor, res.ProtoMinor = tc.protoMaj, 0
+			}
+			dst := http.Header{}
+
+			NewResponseWriter(tc.options...).CopyHeader(dst, res)
+
+			assert.Equal(t, tc.want, dst)
+		})
+	}
+}
+
+func TestWriteResponse(t *testing.T) {
+	t.Run("正常ケース：ボディの長さからContent-Lengthを設定", func(t *testing.T) {
+		res := newResponse(http.Header{"Content-Length": {"999"}, "Content-Type": {"text/plain"}}, "hello")
+		w := httptest.NewRecorder()
+
+		assert.NoError(t, NewResponseWriter().WriteResponse(w, res))
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.Equal(t, "5", w.Header().Get("Content-Length"))
+		assert.Equal(t, "hello", w.Body.String())
+	})
+	t.Run("正常ケース：長さが不明な場合はContent-Lengthを設定しない", func(t *testing.T) {
+		res := newResponse(http.Header{"Content-Length": {"999"}}, "hello")
+		res.ContentLength = -1
+		w := httptest.NewRecorder()
+
+		assert.NoError(t, NewResponseWriter().WriteResponse(w, res))
+
+		assert.Empty(t, w.Header().Get("Content-Length"))
+		assert.Equal(t, "hello", w.Body.String())
+	})
+	t.Run("正常ケース：展開済みのボディはContent-Lengthを設定しない", func(t *testing.T) {
+		res := newResponse(http.Header{}, "hello")
+		res.ContentLength = 3
+		res.Uncompressed = true
+		w := httptest.NewRecorder()
+
+		assert.NoError(t, NewResponseWriter().WriteResponse(w, res))
+
+		assert.Empty(t, w.Header().Get("Content-Length"))
+	})
+	t.Run("異常ケース：ボディの書き込みに失敗", func(t *testing.T) {
+		res := newResponse(http.Header{}, "hello")
+		w := &test.ErrorResponseWriter{}
+
+		assert.Error(t, NewResponseWriter().WriteResponse(w, res))
+	})
+	t.Run("異常ケース：ボディの読み込みに失敗", func(t *testing.T) {
+		res := newResponse(http.Header{}, "")
+		res.Body = io.NopCloser(&errReader{})
+		w := httptest.NewRecorder()
+
+		assert.Error(t, NewResponseWriter().WriteResponse(w, res))
+	})
+}
+
+type errReader struct{}
+
+func (errReader) Read([]byte) (int, error) {
+	return 0, errors.New("read error")
+}
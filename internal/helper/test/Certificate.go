@@ -0,0 +1,128 @@
+package test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// テスト用の認証局
+type CertificateAuthority struct {
+	// CA証明書（PEM）のファイル
+	CertFile string
+
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	dir  string
+}
+
+// 発行した証明書と秘密鍵のファイル
+type CertificateFiles struct {
+	CertFile string
+	KeyFile  string
+}
+
+// テスト用の認証局を作成し、CA証明書を一時ディレクトリに書き出す
+func NewCertificateAuthority(tb testing.TB) *CertificateAuthority {
+	tb.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          serialNumber(tb),
+		Subject:               pkix.Name{CommonName: "go-tutorial test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	dir := tb.TempDir()
+	ca := &CertificateAuthority{CertFile: filepath.Join(dir, "ca.pem"), cert: cert, key: key, dir: dir}
+	writePEM(tb, ca.CertFile, "CERTIFICATE", der)
+	return ca
+}
+
+// サーバー証明書を発行する
+// hostsにはDNS名かIPアドレスを指定する
+func (ca *CertificateAuthority) IssueServer(tb testing.TB, name string, hosts ...string) CertificateFiles {
+	tb.Helper()
+	return ca.issue(tb, name, x509.ExtKeyUsageServerAuth, hosts)
+}
+
+// mTLSのクライアント証明書を発行する
+func (ca *CertificateAuthority) IssueClient(tb testing.TB, name string) CertificateFiles {
+	tb.Helper()
+	return ca.issue(tb, name, x509.ExtKeyUsageClientAuth, nil)
+}
+
+func (ca *CertificateAuthority) issue(tb testing.TB, name string, usage x509.ExtKeyUsage, hosts []string) CertificateFiles {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: serialNumber(tb),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	for _, h := range hosts {
+		if ip := net.ParseIP(h); ip != nil {
+			tmpl.IPAddresses = append(tmpl.IPAddresses, ip)
+		} else {
+			tmpl.DNSNames = append(tmpl.DNSNames, h)
+		}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	files := CertificateFiles{
+		CertFile: filepath.Join(ca.dir, name+".pem"),
+		KeyFile:  filepath.Join(ca.dir, name+"-key.pem"),
+	}
+	writePEM(tb, files.CertFile, "CERTIFICATE", der)
+	writePEM(tb, files.KeyFile, "PRIVATE KEY", keyDER)
+	return files
+}
+
+func serialNumber(tb testing.TB) *big.Int {
+	n, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 62))
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return n
+}
+
+func writePEM(tb testing.TB, path, typ string, der []byte) {
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der}), 0o600); err != nil {
+		tb.Fatal(err)
+	}
+}
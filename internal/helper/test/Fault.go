@@ -0,0 +1,258 @@
+package test
+
+import (
+	"bytes"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ハンドラに注入する障害
+// nextを呼ばずにレスポンスを返すことも、nextのレスポンスを加工することもできる
+type Fault func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc)
+
+// 障害を起こさずにそのまま処理する
+func Pass() Fault {
+	return func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		next(w, r)
+	}
+}
+
+// 指定時間待ってから処理する
+// クライアントが先に切断した場合は何も返さない
+func Latency(d time.Duration) Fault {
+	return func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		select {
+		case <-time.After(d):
+			next(w, r)
+		case <-r.Context().Done():
+		}
+	}
+}
+
+// 指定したステータスコードのエラーを返す
+func Status(code int) Fault {
+	return func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		http.Error(w, http.StatusText(code), code)
+	}
+}
+
+// レスポンスを返さずにTCPの接続をリセットする
+func Reset() Fault {
+	return func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		conn, _, err := http.NewResponseController(w).Hijack()
+		if err != nil {
+			panic(http.ErrAbortHandler)
+		}
+		if tcp, ok := conn.(*net.TCPConn); ok {
+			// SO_LINGERを0にするとCloseでRSTが送られる
+			_ = tcp.SetLinger(0)
+		}
+		_ = conn.Close()
+	}
+}
+
+// Content-Lengthは本来の長さのまま、ボディを先頭nバイトで切断する
+func Truncate(n int) Fault {
+	return func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		res := record(next, r)
+		body := res.body.Bytes()
+		if n < len(body) {
+			body = body[:n]
+		}
+		copyHeader(w.Header(), res.header)
+		w.Header().Set("Content-Length", strconv.Itoa(res.body.Len()))
+		w.WriteHeader(res.status)
+		_, _ = w.Write(body)
+		_ = http.NewResponseController(w).Flush()
+		// 残りを送らずに接続を切る
+		panic(http.ErrAbortHandler)
+	}
+}
+
+// 不正なJSONを正常なレスポンスとして返す
+func MalformedJSON() Fault {
+	return func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`[{"name":"dip 太郎",`))
+	}
+}
+
+// ボディをchunkバイトずつ、intervalの間隔で少しずつ返す
+func SlowDrip(chunk int, interval time.Duration) Fault {
+	if chunk <= 0 {
+		chunk = 1
+	}
+	return func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		res := record(next, r)
+		copyHeader(w.Header(), res.header)
+		w.Header().Del("Content-Length")
+		w.WriteHeader(res.status)
+
+		rc := http.NewResponseController(w)
+		body := res.body.Bytes()
+		for len(body) > 0 {
+			n := chunk
+			if n > len(body) {
+				n = len(body)
+			}
+			if _, err := w.Write(body[:n]); err != nil {
+				return
+			}
+			if err := rc.Flush(); err != nil {
+				return
+			}
+			body = body[n:]
+			if len(body) == 0 {
+				return
+			}
+			select {
+			case <-time.After(interval):
+			case <-r.Context().Done():
+				return
+			}
+		}
+	}
+}
+
+// rateの確率でfaultを起こす
+// 同じseedであれば同じ順序で障害が起きる
+func Random(seed int64, rate float64, fault Fault) Fault {
+	var mu sync.Mutex
+	rng := rand.New(rand.NewSource(seed))
+	return func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		mu.Lock()
+		hit := rng.Float64() < rate
+		mu.Unlock()
+		if hit {
+			fault(w, r, next)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// 複数の障害を順に重ねる（先頭が外側）
+func Chain(faults ...Fault) Fault {
+	return func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		h := next
+		for i := len(faults) - 1; i >= 0; i-- {
+			f, inner := faults[i], h
+			h = func(w http.ResponseWriter, r *http.Request) {
+				f(w, r, inner)
+			}
+		}
+		h(w, r)
+	}
+}
+
+// 同じ障害をn回繰り返す
+func Times(n int, fault Fault) []Fault {
+	faults := make([]Fault, n)
+	for i := range faults {
+		faults[i] = fault
+	}
+	return faults
+}
+
+// リクエストの順番ごとに起こす障害を決めたシナリオ
+type Scenario struct {
+	mu       sync.Mutex
+	steps    []Fault
+	fallback Fault
+	count    int
+}
+
+// n番目のリクエストにsteps[n-1]の障害を起こすシナリオ
+// stepsを使い切った後は正常に処理する
+func Sequence(steps ...Fault) *Scenario {
+	return &Scenario{steps: steps, fallback: Pass()}
+}
+
+// 全てのリクエストに同じ障害を起こすシナリオ
+func Always(fault Fault) *Scenario {
+	return &Scenario{fallback: fault}
+}
+
+// stepsを使い切った後の障害を設定する
+func (s *Scenario) Then(fault Fault) *Scenario {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fallback = fault
+	return s
+}
+
+// これまでに受け付けたリクエスト数
+func (s *Scenario) Requests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.count
+}
+
+func (s *Scenario) next() Fault {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := s.count
+	s.count++
+	switch {
+	case i >= len(s.steps):
+		return s.fallback
+	case s.steps[i] == nil:
+		return Pass()
+	}
+	return s.steps[i]
+}
+
+// ハンドラにシナリオに沿った障害を注入する
+func Inject(h Handler, s *Scenario) Handler {
+	next := h.Handler
+	return Handler{
+		Path: h.Path,
+		Handler: func(w http.ResponseWriter, r *http.Request) {
+			s.next()(w, r, next)
+		},
+	}
+}
+
+// nextのレスポンスをバッファに記録する
+type recordedResponse struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (rr *recordedResponse) Header() http.Header {
+	return rr.header
+}
+
+func (rr *recordedResponse) Write(b []byte) (int, error) {
+	if rr.status == 0 {
+		rr.status = http.StatusOK
+	}
+	return rr.body.Write(b)
+}
+
+func (rr *recordedResponse) WriteHeader(statusCode int) {
+	if rr.status == 0 {
+		rr.status = statusCode
+	}
+}
+
+func record(next http.HandlerFunc, r *http.Request) *recordedResponse {
+	rr := &recordedResponse{header: http.Header{}}
+	next(rr, r)
+	if rr.status == 0 {
+		rr.status = http.StatusOK
+	}
+	return rr
+}
+
+func copyHeader(dst, src http.Header) {
+	for k, vs := range src {
+		dst[k] = append([]string(nil), vs...)
+	}
+}
@@ -0,0 +1,144 @@
+package test
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// 障害を注入したmock-apiの/usersを起動する
+func startFaultServer(s *Scenario) *httptest.Server {
+	m := NewMockAPI(DefaultMockData())
+	m.Key = ""
+	return httptest.NewServer(Route(Inject(Handler{Path: "/users", Handler: m.ServeHTTP}, s)))
+}
+
+func getUsers(c *http.Client, url string) (int, []MockUser, error) {
+	res, err := c.Get(url + "/users")
+	if err != nil {
+		return 0, nil, err
+	}
+	defer res.Body.Close()
+	var users []MockUser
+	if res.StatusCode != http.StatusOK {
+		return res.StatusCode, nil, nil
+	}
+	err = json.NewDecoder(res.Body).Decode(&users)
+	return res.StatusCode, users, err
+}
+
+func TestFault(t *testing.T) {
+	t.Run("正常ケース：順番に応じた障害（リトライの確認）", func(t *testing.T) {
+		s := Sequence(append(Times(2, Status(http.StatusServiceUnavailable)), Pass(), Status(http.StatusInternalServerError))...)
+		ts := startFaultServer(s)
+		defer ts.Close()
+
+		var statuses []int
+		for i := 0; i < 5; i++ {
+			status, _, err := getUsers(ts.Client(), ts.URL)
+			assert.NoError(t, err)
+			statuses = append(statuses, status)
+		}
+		assert.Equal(t, []int{503, 503, 200, 500, 200}, statuses)
+		assert.Equal(t, 5, s.Requests())
+	})
+	t.Run("正常ケース：使い切った後の障害", func(t *testing.T) {
+		s := Sequence(nil).Then(Status(http.StatusBadGateway))
+		ts := startFaultServer(s)
+		defer ts.Close()
+
+		status, users, err := getUsers(ts.Client(), ts.URL)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, status)
+		assert.Len(t, users, 3)
+		status, _, err = getUsers(ts.Client(), ts.URL)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusBadGateway, status)
+	})
+	t.Run("正常ケース：遅延によるタイムアウト", func(t *testing.T) {
+		ts := startFaultServer(Sequence(Latency(time.Second)))
+		defer ts.Close()
+
+		c := ts.Client()
+		c.Timeout = 50 * time.Millisecond
+		_, _, err := getUsers(c, ts.URL)
+		assert.Error(t, err)
+
+		// 2回目は遅延しない
+		status, _, err := getUsers(c, ts.URL)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, status)
+	})
+	t.Run("正常ケース：接続のリセット", func(t *testing.T) {
+		ts := startFaultServer(Always(Reset()))
+		defer ts.Close()
+
+		_, _, err := getUsers(ts.Client(), ts.URL)
+		assert.Error(t, err)
+	})
+	t.Run("正常ケース：ボディの切断", func(t *testing.T) {
+		ts := startFaultServer(Always(Truncate(10)))
+		defer ts.Close()
+
+		res, err := ts.Client().Get(ts.URL + "/users")
+		if !assert.NoError(t, err) {
+			return
+		}
+		defer res.Body.Close()
+		body, err := io.ReadAll(res.Body)
+		assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+		assert.Len(t, body, 10)
+	})
+	t.Run("正常ケース：不正なJSON", func(t *testing.T) {
+		ts := startFaultServer(Always(MalformedJSON()))
+		defer ts.Close()
+
+		status, _, err := getUsers(ts.Client(), ts.URL)
+		assert.Equal(t, http.StatusOK, status)
+		var syntaxErr *json.SyntaxError
+		assert.True(t, errors.As(err, &syntaxErr) || errors.Is(err, io.ErrUnexpectedEOF))
+	})
+	t.Run("正常ケース：少しずつ返す", func(t *testing.T) {
+		ts := startFaultServer(Always(SlowDrip(64, 20*time.Millisecond)))
+		defer ts.Close()
+
+		start := time.Now()
+		status, users, err := getUsers(ts.Client(), ts.URL)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, status)
+		assert.Len(t, users, 3)
+		assert.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond)
+	})
+	t.Run("正常ケース：乱数による障害は同じシードで再現する", func(t *testing.T) {
+		run := func() []int {
+			ts := startFaultServer(Always(Random(42, 0.5, Status(http.StatusServiceUnavailable))))
+			defer ts.Close()
+			var statuses []int
+			for i := 0; i < 20; i++ {
+				status, _, _ := getUsers(ts.Client(), ts.URL)
+				statuses = append(statuses, status)
+			}
+			return statuses
+		}
+		first := run()
+		assert.Equal(t, first, run())
+		assert.Contains(t, first, http.StatusOK)
+		assert.Contains(t, first, http.StatusServiceUnavailable)
+	})
+	t.Run("正常ケース：障害の重ね合わせ", func(t *testing.T) {
+		ts := startFaultServer(Always(Chain(Latency(10*time.Millisecond), Status(http.StatusGatewayTimeout))))
+		defer ts.Close()
+
+		start := time.Now()
+		status, _, err := getUsers(ts.Client(), ts.URL)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusGatewayTimeout, status)
+		assert.GreaterOrEqual(t, time.Since(start), 10*time.Millisecond)
+	})
+}
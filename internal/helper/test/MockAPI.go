@@ -0,0 +1,526 @@
+package test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/dip-dev/go-tutorial/internal/helper/etag"
+)
+
+// mock-apiが要求するkeyヘッダーの既定値
+const DefaultMockAPIKey = "dip"
+
+// mock-apiのユーザー情報
+type MockUser struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+	Age  int    `json:"age"`
+}
+
+// mock-apiの案件情報
+type MockEntry struct {
+	Name   string `json:"name"`
+	UserID int    `json:"user_id"`
+	Salary int    `json:"salary"`
+}
+
+// mock-apiの初期データ
+type MockData struct {
+	Users   []MockUser  `json:"users"`
+	Entries []MockEntry `json:"entries"`
+}
+
+// 各chapterのREADMEに記載されているデータ
+func DefaultMockData() MockData {
+	return MockData{
+		Users: []MockUser{
+			{ID: 123456, Name: "dip 太郎", Age: 25},
+			{ID: 234567, Name: "dip 次郎", Age: 24},
+			{ID: 345678, Name: "dip 花子", Age: 25},
+		},
+		Entries: []MockEntry{
+			{Name: "案件情報1", UserID: 123456, Salary: 123456},
+			{Name: "案件情報2", UserID: 234567, Salary: 123456},
+		},
+	}
+}
+
+// JSONから初期データを読み込む
+func LoadMockData(r io.Reader) (MockData, error) {
+	var data MockData
+	if err := json.NewDecoder(r).Decode(&data); err != nil {
+		return MockData{}, err
+	}
+	return data, nil
+}
+
+// mock-apiの/users・/entriesをメモリ上で再現するサーバー
+// テストではhttptest.NewServerに渡して使う
+type MockAPI struct {
+	// keyヘッダーに要求する値（空の場合はチェックしない）
+	Key string
+
+	mu      sync.RWMutex
+	users   []MockUser
+	entries []MockEntry
+	nextID  int
+	mux     *http.ServeMux
+}
+
+// 初期データを投入したmock-apiを作成する
+func NewMockAPI(data MockData) *MockAPI {
+	m := &MockAPI{
+		Key: DefaultMockAPIKey,
+		mux: http.NewServeMux(),
+	}
+	m.Seed(data)
+	m.mux.HandleFunc("/users", m.handleUsers)
+	m.mux.HandleFunc("/users/", m.handleUser)
+	m.mux.HandleFunc("/entries", m.handleEntries)
+	return m
+}
+
+// データを初期データで置き換える
+func (m *MockAPI) Seed(data MockData) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.users = append([]MockUser{}, data.Users...)
+	m.entries = append([]MockEntry{}, data.Entries...)
+	m.nextID = 1
+	for _, u := range m.users {
+		if u.ID >= m.nextID {
+			m.nextID = u.ID + 1
+		}
+	}
+}
+
+// 現在のユーザー情報
+func (m *MockAPI) Users() []MockUser {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return append([]MockUser{}, m.users...)
+}
+
+// 現在の案件情報
+func (m *MockAPI) Entries() []MockEntry {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return append([]MockEntry{}, m.entries...)
+}
+
+// test.Routeと組み合わせるためのハンドラ
+func (m *MockAPI) Handlers() []Handler {
+	return []Handler{
+		{Path: "/users", Handler: m.handleUsers},
+		{Path: "/users/", Handler: m.handleUser},
+		{Path: "/entries", Handler: m.handleEntries},
+	}
+}
+
+func (m *MockAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m.mux.ServeHTTP(w, r)
+}
+
+// keyヘッダーをチェックする
+func (m *MockAPI) authorize(w http.ResponseWriter, r *http.Request) bool {
+	if m.Key != "" && r.Header.Get("key") != m.Key {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return false
+	}
+	return true
+}
+
+func (m *MockAPI) handleUsers(w http.ResponseWriter, r *http.Request) {
+	if !m.authorize(w, r) {
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		m.getUsers(w, r)
+	case http.MethodPost:
+		m.createUser(w, r)
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// GET /users
+// name・id・ageで絞り込む（複数指定した場合はいずれかに一致）
+func (m *MockAPI) getUsers(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	ids, ok := atoiAll(query["id"])
+	if !ok {
+		http.Error(w, "Invalid parameters", http.StatusBadRequest)
+		return
+	}
+	ages, ok := atoiAll(query["age"])
+	if !ok {
+		http.Error(w, "Invalid parameters", http.StatusBadRequest)
+		return
+	}
+	names := query["name"]
+
+	m.mu.RLock()
+	users := []MockUser{}
+	for _, u := range m.users {
+		if matchString(names, u.Name) && matchInt(ids, u.ID) && matchInt(ages, u.Age) {
+			users = append(users, u)
+		}
+	}
+	m.mu.RUnlock()
+
+	writeMockJSON(w, http.StatusOK, users)
+}
+
+// POST /users
+// form-urlencoded・JSONのどちらでも受け付ける
+func (m *MockAPI) createUser(w http.ResponseWriter, r *http.Request) {
+	var name, age string
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case "application/json":
+		var body map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, "Invalid body", http.StatusBadRequest)
+			return
+		}
+		name = stringValue(body["name"])
+		age = stringValue(body["age"])
+	default:
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, "Invalid body", http.StatusBadRequest)
+			return
+		}
+		name = r.PostForm.Get("name")
+		age = r.PostForm.Get("age")
+	}
+
+	if name == "" {
+		http.Error(w, "name is required", http.StatusBadRequest)
+		return
+	}
+	n, err := strconv.Atoi(age)
+	if err != nil {
+		http.Error(w, "age is not a number", http.StatusBadRequest)
+		return
+	}
+
+	m.mu.Lock()
+	u := MockUser{ID: m.nextID, Name: name, Age: n}
+	m.nextID++
+	m.users = append(m.users, u)
+	m.mu.Unlock()
+
+	writeMockJSON(w, http.StatusOK, u)
+}
+
+// /users/{id}
+// 更新・削除はIf-Matchが指定された場合、ETagが一致する時だけ処理する
+func (m *MockAPI) handleUser(w http.ResponseWriter, r *http.Request) {
+	if !m.authorize(w, r) {
+		return
+	}
+	id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/users/"))
+	if err != nil {
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	}
+
+	// 条件の判定から更新までを不可分にする
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	i := m.indexOfUser(id)
+	if i < 0 {
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	}
+	tag := MockUserETag(m.users[i])
+
+	switch r.Method {
+	case http.MethodGet:
+		if etag.IfNoneMatch(r.Header.Get("If-None-Match"), tag) {
+			w.Header().Set("ETag", tag)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		m.writeUser(w, m.users[i])
+	case http.MethodPut, http.MethodPatch, http.MethodDelete:
+		if ifMatch := r.Header.Get("If-Match"); ifMatch != "" && !etag.IfMatch(ifMatch, tag) {
+			http.Error(w, "Precondition Failed", http.StatusPreconditionFailed)
+			return
+		}
+		if r.Method == http.MethodDelete {
+			m.users = append(m.users[:i], m.users[i+1:]...)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		var u MockUser
+		var status int
+		if r.Method == http.MethodPut {
+			u, status, err = replaceUser(r)
+		} else {
+			u, status, err = patchUser(r, m.users[i])
+		}
+		if err != nil {
+			http.Error(w, err.Error(), status)
+			return
+		}
+		// IDは変更できない
+		if u.ID != 0 && u.ID != id {
+			http.Error(w, "id cannot be changed", http.StatusConflict)
+			return
+		}
+		u.ID = id
+		m.users[i] = u
+		m.writeUser(w, u)
+	default:
+		w.Header().Set("Allow", "GET, PUT, PATCH, DELETE")
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// PUT /users/{id}
+// 登録と同じくform-urlencoded・JSONのどちらでも受け付ける
+func replaceUser(r *http.Request) (MockUser, int, error) {
+	var id, name, age string
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case "application/json":
+		var body map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			return MockUser{}, http.StatusBadRequest, errors.New("Invalid body")
+		}
+		id = stringValue(body["id"])
+		name = stringValue(body["name"])
+		age = stringValue(body["age"])
+	default:
+		if err := r.ParseForm(); err != nil {
+			return MockUser{}, http.StatusBadRequest, errors.New("Invalid body")
+		}
+		id = r.PostForm.Get("id")
+		name = r.PostForm.Get("name")
+		age = r.PostForm.Get("age")
+	}
+	return newMockUser(id, name, age)
+}
+
+// PATCH /users/{id}
+// JSON Merge Patch（RFC 7396）で部分的に更新する
+func patchUser(r *http.Request, current MockUser) (MockUser, int, error) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "application/merge-patch+json" && mediaType != "application/json" {
+		return MockUser{}, http.StatusUnsupportedMediaType, errors.New("Unsupported Media Type")
+	}
+	var patch any
+	if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+		return MockUser{}, http.StatusBadRequest, errors.New("Invalid body")
+	}
+	if _, ok := patch.(map[string]any); !ok {
+		return MockUser{}, http.StatusBadRequest, errors.New("Invalid body")
+	}
+
+	// 現在の値をJSONの形式に変換してから適用する
+	b, _ := json.Marshal(current)
+	var target any
+	_ = json.Unmarshal(b, &target)
+	merged, _ := mergePatch(target, patch).(map[string]any)
+	return newMockUser(stringValue(merged["id"]), stringValue(merged["name"]), stringValue(merged["age"]))
+}
+
+// 文字列の値からユーザー情報を作る
+// IDが空の場合は0にする
+func newMockUser(id, name, age string) (MockUser, int, error) {
+	if name == "" {
+		return MockUser{}, http.StatusBadRequest, errors.New("name is required")
+	}
+	n, err := strconv.Atoi(age)
+	if err != nil {
+		return MockUser{}, http.StatusBadRequest, errors.New("age is not a number")
+	}
+	u := MockUser{Name: name, Age: n}
+	if id != "" {
+		if u.ID, err = strconv.Atoi(id); err != nil {
+			return MockUser{}, http.StatusBadRequest, errors.New("id is not a number")
+		}
+	}
+	return u, http.StatusOK, nil
+}
+
+// RFC 7396のMerge Patchを適用する
+func mergePatch(target, patch any) any {
+	p, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+	t, ok := target.(map[string]any)
+	if !ok {
+		t = map[string]any{}
+	}
+	for k, v := range p {
+		if v == nil {
+			delete(t, k)
+			continue
+		}
+		t[k] = mergePatch(t[k], v)
+	}
+	return t
+}
+
+func (m *MockAPI) indexOfUser(id int) int {
+	for i, u := range m.users {
+		if u.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func (m *MockAPI) writeUser(w http.ResponseWriter, u MockUser) {
+	w.Header().Set("ETag", MockUserETag(u))
+	writeMockJSON(w, http.StatusOK, u)
+}
+
+// ユーザー情報のETag（内容から計算する強いETag）
+func MockUserETag(u MockUser) string {
+	b, _ := json.Marshal(u)
+	h := fnv.New64a()
+	_, _ = h.Write(b)
+	return fmt.Sprintf(`"%016x"`, h.Sum64())
+}
+
+func (m *MockAPI) handleEntries(w http.ResponseWriter, r *http.Request) {
+	if !m.authorize(w, r) {
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		m.getEntries(w, r)
+	case http.MethodPost:
+		m.createEntry(w, r)
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// GET /entries
+// userID・minSalary・maxSalaryで絞り込む（指定が無い場合は全件）
+func (m *MockAPI) getEntries(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	ids, ok := atoiAll(query["userID"])
+	if !ok {
+		http.Error(w, "Invalid parameters", http.StatusBadRequest)
+		return
+	}
+	minSalary, ok := atoiAll(query["minSalary"])
+	if !ok || len(minSalary) > 1 {
+		http.Error(w, "Invalid parameters", http.StatusBadRequest)
+		return
+	}
+	maxSalary, ok := atoiAll(query["maxSalary"])
+	if !ok || len(maxSalary) > 1 {
+		http.Error(w, "Invalid parameters", http.StatusBadRequest)
+		return
+	}
+
+	m.mu.RLock()
+	entries := []MockEntry{}
+	for _, e := range m.entries {
+		if len(minSalary) > 0 && e.Salary < minSalary[0] || len(maxSalary) > 0 && e.Salary > maxSalary[0] {
+			continue
+		}
+		if matchInt(ids, e.UserID) {
+			entries = append(entries, e)
+		}
+	}
+	m.mu.RUnlock()
+
+	writeMockJSON(w, http.StatusOK, entries)
+}
+
+// POST /entries
+// JSONのみ受け付け、存在するユーザーの案件情報だけを登録する
+func (m *MockAPI) createEntry(w http.ResponseWriter, r *http.Request) {
+	var e MockEntry
+	if err := json.NewDecoder(r.Body).Decode(&e); err != nil {
+		http.Error(w, "Invalid body", http.StatusBadRequest)
+		return
+	}
+	if e.Name == "" {
+		http.Error(w, "name is required", http.StatusBadRequest)
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.indexOfUser(e.UserID) < 0 {
+		http.Error(w, "user is not found", http.StatusNotFound)
+		return
+	}
+	m.entries = append(m.entries, e)
+
+	writeMockJSON(w, http.StatusCreated, e)
+}
+
+func writeMockJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func atoiAll(vs []string) ([]int, bool) {
+	ns := make([]int, 0, len(vs))
+	for _, v := range vs {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, false
+		}
+		ns = append(ns, n)
+	}
+	sort.Ints(ns)
+	return ns, true
+}
+
+func matchInt(want []int, v int) bool {
+	if len(want) == 0 {
+		return true
+	}
+	i := sort.SearchInts(want, v)
+	return i < len(want) && want[i] == v
+}
+
+func matchString(want []string, v string) bool {
+	if len(want) == 0 {
+		return true
+	}
+	for _, w := range want {
+		if w == v {
+			return true
+		}
+	}
+	return false
+}
+
+// JSONの値をフォームと同じ文字列として扱う
+func stringValue(v any) string {
+	switch t := v.(type) {
+	case string:
+		return t
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64)
+	}
+	return ""
+}
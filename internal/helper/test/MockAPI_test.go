@@ -0,0 +1,398 @@
+package test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMockAPI(t *testing.T) {
+	success := map[string]struct {
+		method      string
+		path        string
+		contentType string
+		body        string
+		wantStatus  int
+		want        string
+	}{
+		"正常ケース：ユーザーを年齢で絞り込む": {
+			method: http.MethodGet,
+			path:   "/users?age=25",
+			want:   `[{"id":123456,"name":"dip 太郎","age":25},{"id":345678,"name":"dip 花子","age":25}]`,
+		},
+		"正常ケース：ユーザーを名前で絞り込む": {
+			method: http.MethodGet,
+			path:   "/users?" + url.Values{"name": {"dip 次郎"}}.Encode(),
+			want:   `[{"id":234567,"name":"dip 次郎","age":24}]`,
+		},
+		"正常ケース：該当するユーザーなし": {
+			method: http.MethodGet,
+			path:   "/users?id=1",
+			want:   `[]`,
+		},
+		"正常ケース：フォームでユーザーを登録": {
+			method:      http.MethodPost,
+			path:        "/users",
+			contentType: "application/x-www-form-urlencoded",
+			body:        url.Values{"name": {"dip 四郎"}, "age": {"30"}}.Encode(),
+			want:        `{"id":345679,"name":"dip 四郎","age":30}`,
+		},
+		"正常ケース：JSONでユーザーを登録": {
+			method:      http.MethodPost,
+			path:        "/users",
+			contentType: "application/json",
+			body:        `{"name":"dip 五郎","age":"31"}`,
+			want:        `{"id":345679,"name":"dip 五郎","age":31}`,
+		},
+		"正常ケース：案件情報をユーザーIDで絞り込む": {
+			method: http.MethodGet,
+			path:   "/entries?userID=234567",
+			want:   `[{"name":"案件情報2","user_id":234567,"salary":123456}]`,
+		},
+		"正常ケース：案件情報を給与で絞り込む": {
+			method: http.MethodGet,
+			path:   "/entries?minSalary=100000&maxSalary=123456&userID=123456",
+			want:   `[{"name":"案件情報1","user_id":123456,"salary":123456}]`,
+		},
+		"正常ケース：給与の条件に一致しない": {
+			method: http.MethodGet,
+			path:   "/entries?minSalary=123457",
+			want:   `[]`,
+		},
+		"正常ケース：案件情報を登録": {
+			method:      http.MethodPost,
+			path:        "/entries",
+			contentType: "application/json",
+			body:        `{"name":"案件情報3","user_id":345678,"salary":500000}`,
+			wantStatus:  http.StatusCreated,
+			want:        `{"name":"案件情報3","user_id":345678,"salary":500000}`,
+		},
+		"正常ケース：案件情報を全件取得": {
+			method: http.MethodGet,
+			path:   "/entries",
+			want:   `[{"name":"案件情報1","user_id":123456,"salary":123456},{"name":"案件情報2","user_id":234567,"salary":123456}]`,
+		},
+	}
+	fail := map[string]struct {
+		method      string
+		path        string
+		key         string
+		contentType string
+		body        string
+		wantStatus  int
+	}{
+		"異常ケース：keyヘッダーなし": {
+			method:     http.MethodGet,
+			path:       "/users",
+			wantStatus: http.StatusUnauthorized,
+		},
+		"異常ケース：keyヘッダーが不正": {
+			method:     http.MethodGet,
+			path:       "/entries",
+			key:        "invalid",
+			wantStatus: http.StatusUnauthorized,
+		},
+		"異常ケース：年齢が数値ではない": {
+			method:     http.MethodGet,
+			path:       "/users?age=abc",
+			key:        DefaultMockAPIKey,
+			wantStatus: http.StatusBadRequest,
+		},
+		"異常ケース：ユーザーIDが数値ではない": {
+			method:     http.MethodGet,
+			path:       "/entries?userID=abc",
+			key:        DefaultMockAPIKey,
+			wantStatus: http.StatusBadRequest,
+		},
+		"異常ケース：給与が数値ではない": {
+			method:     http.MethodGet,
+			path:       "/entries?minSalary=abc",
+			key:        DefaultMockAPIKey,
+			wantStatus: http.StatusBadRequest,
+		},
+		"異常ケース：登録時に名前なし": {
+			method:      http.MethodPost,
+			path:        "/users",
+			key:         DefaultMockAPIKey,
+			contentType: "application/x-www-form-urlencoded",
+			body:        "age=20",
+			wantStatus:  http.StatusBadRequest,
+		},
+		"異常ケース：登録時に年齢が数値ではない": {
+			method:      http.MethodPost,
+			path:        "/users",
+			key:         DefaultMockAPIKey,
+			contentType: "application/json",
+			body:        `{"name":"dip 四郎","age":true}`,
+			wantStatus:  http.StatusBadRequest,
+		},
+		"異常ケース：不正なJSON": {
+			method:      http.MethodPost,
+			path:        "/users",
+			key:         DefaultMockAPIKey,
+			contentType: "application/json",
+			body:        `{`,
+			wantStatus:  http.StatusBadRequest,
+		},
+		"異常ケース：存在しないユーザーの案件情報を登録": {
+			method:      http.MethodPost,
+			path:        "/entries",
+			key:         DefaultMockAPIKey,
+			contentType: "application/json",
+			body:        `{"name":"案件情報3","user_id":1,"salary":500000}`,
+			wantStatus:  http.StatusNotFound,
+		},
+		"異常ケース：対応していないメソッド": {
+			method:     http.MethodDelete,
+			path:       "/entries",
+			key:        DefaultMockAPIKey,
+			wantStatus: http.StatusMethodNotAllowed,
+		},
+	}
+
+	for tn, tc := range success {
+		t.Run(tn, func(t *testing.T) {
+			ts := httptest.NewServer(NewMockAPI(DefaultMockData()))
+			defer ts.Close()
+
+			req, _ := http.NewRequest(tc.method, ts.URL+tc.path, strings.NewReader(tc.body))
+			req.Header.Set("key", DefaultMockAPIKey)
+			req.Header.Set("Content-Type", tc.contentType)
+			res, err := http.DefaultClient.Do(req)
+			if !assert.NoError(t, err) {
+				return
+			}
+			defer res.Body.Close()
+
+			wantStatus := tc.wantStatus
+			if wantStatus == 0 {
+				wantStatus = http.StatusOK
+			}
+			var got json.RawMessage
+			assert.NoError(t, json.NewDecoder(res.Body).Decode(&got))
+			assert.Equal(t, wantStatus, res.StatusCode)
+			assert.JSONEq(t, tc.want, string(got))
+		})
+	}
+	for tn, tc := range fail {
+		t.Run(tn, func(t *testing.T) {
+			ts := httptest.NewServer(NewMockAPI(DefaultMockData()))
+			defer ts.Close()
+
+			req, _ := http.NewRequest(tc.method, ts.URL+tc.path, strings.NewReader(tc.body))
+			req.Header.Set("key", tc.key)
+			req.Header.Set("Content-Type", tc.contentType)
+			res, err := http.DefaultClient.Do(req)
+			if !assert.NoError(t, err) {
+				return
+			}
+			defer res.Body.Close()
+			assert.Equal(t, tc.wantStatus, res.StatusCode)
+		})
+	}
+	t.Run("正常ケース：登録したユーザーを取得できる", func(t *testing.T) {
+		m := NewMockAPI(MockData{})
+		m.Key = ""
+		ts := httptest.NewServer(Route(m.Handlers()...))
+		defer ts.Close()
+
+		res, err := http.PostForm(ts.URL+"/users", url.Values{"name": {"dip 太郎"}, "age": {"25"}})
+		if !assert.NoError(t, err) {
+			return
+		}
+		res.Body.Close()
+		assert.Equal(t, []MockUser{{ID: 1, Name: "dip 太郎", Age: 25}}, m.Users())
+		assert.Empty(t, m.Entries())
+	})
+}
+
+func TestMockAPIUser(t *testing.T) {
+	taro := MockUser{ID: 123456, Name: "dip 太郎", Age: 25}
+	success := map[string]struct {
+		method      string
+		path        string
+		contentType string
+		ifMatch     string
+		body        string
+		wantStatus  int
+		want        string
+	}{
+		"正常ケース：ユーザーを取得": {
+			method:     http.MethodGet,
+			path:       "/users/123456",
+			wantStatus: http.StatusOK,
+			want:       `{"id":123456,"name":"dip 太郎","age":25}`,
+		},
+		"正常ケース：ユーザーを置き換える": {
+			method:      http.MethodPut,
+			path:        "/users/123456",
+			contentType: "application/json",
+			ifMatch:     MockUserETag(taro),
+			body:        `{"name":"dip 太郎","age":26}`,
+			wantStatus:  http.StatusOK,
+			want:        `{"id":123456,"name":"dip 太郎","age":26}`,
+		},
+		"正常ケース：Merge Patchで年齢だけ更新": {
+			method:      http.MethodPatch,
+			path:        "/users/123456",
+			contentType: "application/merge-patch+json",
+			ifMatch:     "*",
+			body:        `{"age":30}`,
+			wantStatus:  http.StatusOK,
+			want:        `{"id":123456,"name":"dip 太郎","age":30}`,
+		},
+		"正常ケース：ユーザーを削除": {
+			method:     http.MethodDelete,
+			path:       "/users/123456",
+			wantStatus: http.StatusNoContent,
+		},
+	}
+	fail := map[string]struct {
+		method      string
+		path        string
+		contentType string
+		ifMatch     string
+		body        string
+		wantStatus  int
+	}{
+		"異常ケース：存在しないユーザー": {
+			method:     http.MethodGet,
+			path:       "/users/1",
+			wantStatus: http.StatusNotFound,
+		},
+		"異常ケース：IDが数値ではない": {
+			method:     http.MethodGet,
+			path:       "/users/abc",
+			wantStatus: http.StatusNotFound,
+		},
+		"異常ケース：ETagが一致しない": {
+			method:      http.MethodPut,
+			path:        "/users/123456",
+			contentType: "application/json",
+			ifMatch:     `"stale"`,
+			body:        `{"name":"dip 太郎","age":26}`,
+			wantStatus:  http.StatusPreconditionFailed,
+		},
+		"異常ケース：弱いETagは一致しない": {
+			method:     http.MethodDelete,
+			path:       "/users/123456",
+			ifMatch:    "W/" + MockUserETag(taro),
+			wantStatus: http.StatusPreconditionFailed,
+		},
+		"異常ケース：IDを変更": {
+			method:      http.MethodPatch,
+			path:        "/users/123456",
+			contentType: "application/merge-patch+json",
+			body:        `{"id":1}`,
+			wantStatus:  http.StatusConflict,
+		},
+		"異常ケース：必須の項目を削除": {
+			method:      http.MethodPatch,
+			path:        "/users/123456",
+			contentType: "application/merge-patch+json",
+			body:        `{"name":null}`,
+			wantStatus:  http.StatusBadRequest,
+		},
+		"異常ケース：Merge Patchではない": {
+			method:      http.MethodPatch,
+			path:        "/users/123456",
+			contentType: "text/plain",
+			body:        `{"age":30}`,
+			wantStatus:  http.StatusUnsupportedMediaType,
+		},
+		"異常ケース：置き換え時に年齢なし": {
+			method:      http.MethodPut,
+			path:        "/users/123456",
+			contentType: "application/json",
+			body:        `{"name":"dip 太郎"}`,
+			wantStatus:  http.StatusBadRequest,
+		},
+	}
+
+	for tn, tc := range success {
+		t.Run(tn, func(t *testing.T) {
+			ts := httptest.NewServer(NewMockAPI(DefaultMockData()))
+			defer ts.Close()
+
+			req, _ := http.NewRequest(tc.method, ts.URL+tc.path, strings.NewReader(tc.body))
+			req.Header.Set("key", DefaultMockAPIKey)
+			req.Header.Set("Content-Type", tc.contentType)
+			if tc.ifMatch != "" {
+				req.Header.Set("If-Match", tc.ifMatch)
+			}
+			res, err := http.DefaultClient.Do(req)
+			if !assert.NoError(t, err) {
+				return
+			}
+			defer res.Body.Close()
+
+			assert.Equal(t, tc.wantStatus, res.StatusCode)
+			if tc.want == "" {
+				return
+			}
+			var got json.RawMessage
+			assert.NoError(t, json.NewDecoder(res.Body).Decode(&got))
+			assert.JSONEq(t, tc.want, string(got))
+			var u MockUser
+			assert.NoError(t, json.Unmarshal(got, &u))
+			assert.Equal(t, MockUserETag(u), res.Header.Get("ETag"))
+		})
+	}
+	for tn, tc := range fail {
+		t.Run(tn, func(t *testing.T) {
+			m := NewMockAPI(DefaultMockData())
+			ts := httptest.NewServer(m)
+			defer ts.Close()
+
+			req, _ := http.NewRequest(tc.method, ts.URL+tc.path, strings.NewReader(tc.body))
+			req.Header.Set("key", DefaultMockAPIKey)
+			req.Header.Set("Content-Type", tc.contentType)
+			if tc.ifMatch != "" {
+				req.Header.Set("If-Match", tc.ifMatch)
+			}
+			res, err := http.DefaultClient.Do(req)
+			if !assert.NoError(t, err) {
+				return
+			}
+			defer res.Body.Close()
+			assert.Equal(t, tc.wantStatus, res.StatusCode)
+			// 失敗した場合はデータを変更しない
+			assert.Equal(t, DefaultMockData().Users, m.Users())
+		})
+	}
+	t.Run("正常ケース：ETagが一致する場合は304", func(t *testing.T) {
+		ts := httptest.NewServer(NewMockAPI(DefaultMockData()))
+		defer ts.Close()
+
+		req, _ := http.NewRequest(http.MethodGet, ts.URL+"/users/123456", nil)
+		req.Header.Set("key", DefaultMockAPIKey)
+		req.Header.Set("If-None-Match", "W/"+MockUserETag(taro))
+		res, err := http.DefaultClient.Do(req)
+		if !assert.NoError(t, err) {
+			return
+		}
+		defer res.Body.Close()
+		assert.Equal(t, http.StatusNotModified, res.StatusCode)
+		assert.Equal(t, MockUserETag(taro), res.Header.Get("ETag"))
+	})
+}
+
+func TestLoadMockData(t *testing.T) {
+	t.Run("正常ケース：JSONから読み込む", func(t *testing.T) {
+		data, err := LoadMockData(strings.NewReader(`{"users":[{"id":1,"name":"dip","age":20}],"entries":[{"name":"案件","user_id":1,"salary":100}]}`))
+		assert.NoError(t, err)
+		assert.Equal(t, MockData{
+			Users:   []MockUser{{ID: 1, Name: "dip", Age: 20}},
+			Entries: []MockEntry{{Name: "案件", UserID: 1, Salary: 100}},
+		}, data)
+	})
+	t.Run("異常ケース：不正なJSON", func(t *testing.T) {
+		_, err := LoadMockData(strings.NewReader(`{`))
+		assert.Error(t, err)
+	})
+}
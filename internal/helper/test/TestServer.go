@@ -17,4 +17,12 @@ func Route(handlers ...Handler) *http.ServeMux {
 	}
 
 	return m
-}
\ No newline at end of file
+}
+
+// 自分自身へリダイレクトし続けるハンドラ
+// クライアントがリダイレクトの上限に達するため、外部APIのリクエスト失敗を再現できる
+func RedirectHandler(redirectURL string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, redirectURL, http.StatusFound)
+	}
+}
@@ -0,0 +1,209 @@
+package versioning
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// バージョンを指定するリクエストヘッダー
+const AcceptVersionHeader = "Accept-Version"
+
+// 処理したバージョンを返すレスポンスヘッダー
+const VersionHeader = "API-Version"
+
+// APIのバージョン
+type Version struct {
+	// パスの接頭辞とヘッダーで指定する名前（v1など）
+	Name string
+	// 非推奨になった日時（ゼロ値の場合は非推奨ではない）
+	Deprecation time.Time
+	// 提供を終了する日時
+	Sunset time.Time
+	// 移行先のドキュメントなど
+	Link string
+}
+
+// 非推奨のバージョンか
+func (v Version) Deprecated() bool {
+	return !v.Deprecation.IsZero()
+}
+
+// バージョンごとにハンドラを登録するルーター
+// /v1/usersのようにパスの接頭辞、またはAccept-Versionヘッダーでバージョンを選択し、
+// どちらもない場合は既定のバージョンで処理する
+type Router struct {
+	defaultVersion string
+	versions       map[string]*route
+}
+
+type route struct {
+	version Version
+	mux     *http.ServeMux
+}
+
+// ルーターの初期化処理
+func NewRouter(defaultVersion string, versions ...Version) (*Router, error) {
+	rt := &Router{defaultVersion: defaultVersion, versions: map[string]*route{}}
+	for _, v := range versions {
+		if v.Name == "" || strings.Contains(v.Name, "/") {
+			return nil, fmt.Errorf("versioning: invalid version name %q", v.Name)
+		}
+		if _, ok := rt.versions[v.Name]; ok {
+			return nil, fmt.Errorf("versioning: duplicate version %q", v.Name)
+		}
+		rt.versions[v.Name] = &route{version: v, mux: http.NewServeMux()}
+	}
+	if _, ok := rt.versions[defaultVersion]; !ok {
+		return nil, fmt.Errorf("versioning: default version %q is not registered", defaultVersion)
+	}
+	return rt, nil
+}
+
+// 指定したバージョンにハンドラを登録する
+// patternにはバージョンの接頭辞を含めない
+// バージョンごとに異なるルートのため、非推奨のバージョンではDeprecation・Sunsetヘッダーを付ける
+func (rt *Router) Handle(version, pattern string, h http.Handler) {
+	r := rt.route(version)
+	r.mux.Handle(pattern, deprecationHandler(r.version, h))
+}
+
+// 指定したバージョンにハンドラ関数を登録する
+func (rt *Router) HandleFunc(version, pattern string, h http.HandlerFunc) {
+	rt.Handle(version, pattern, h)
+}
+
+// 全てのバージョンに同じハンドラを登録する
+// バージョンによって変わらないルートのため、Deprecation・Sunsetヘッダーは付けない
+func (rt *Router) HandleAll(pattern string, h http.Handler) {
+	for _, name := range rt.Versions() {
+		rt.route(name).mux.Handle(pattern, h)
+	}
+}
+
+func (rt *Router) route(version string) *route {
+	r, ok := rt.versions[version]
+	if !ok {
+		panic(fmt.Sprintf("versioning: unknown version %q", version))
+	}
+	return r
+}
+
+// 登録されているバージョンの名前
+func (rt *Router) Versions() []string {
+	names := make([]string, 0, len(rt.versions))
+	for name := range rt.versions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (rt *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// パスの接頭辞を優先する
+	if name, rest, ok := rt.splitPrefix(r.URL.Path); ok {
+		// ServeMuxのリダイレクト（末尾のスラッシュの補完など）に接頭辞を戻す
+		rt.serve(&prefixWriter{ResponseWriter: w, prefix: "/" + name}, stripPrefix(r, rest), rt.versions[name])
+		return
+	}
+
+	// バージョンによって結果が変わるため、キャッシュを分ける
+	w.Header().Add("Vary", AcceptVersionHeader)
+	name := rt.defaultVersion
+	if v := strings.TrimSpace(r.Header.Get(AcceptVersionHeader)); v != "" {
+		if _, ok := rt.versions[v]; !ok {
+			http.Error(w, fmt.Sprintf("unsupported version %q: supported versions are %s", v, strings.Join(rt.Versions(), ", ")), http.StatusBadRequest)
+			return
+		}
+		name = v
+	}
+	rt.serve(w, r, rt.versions[name])
+}
+
+func (rt *Router) serve(w http.ResponseWriter, r *http.Request, rv *route) {
+	w.Header().Set(VersionHeader, rv.version.Name)
+	rv.mux.ServeHTTP(w, r)
+}
+
+// 非推奨のバージョンの場合、Deprecation・Sunsetヘッダーを付けるハンドラ
+func deprecationHandler(v Version, next http.Handler) http.Handler {
+	if !v.Deprecated() {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h := w.Header()
+		// RFC 9745の形式（@UNIX時間）
+		h.Set("Deprecation", "@"+strconv.FormatInt(v.Deprecation.Unix(), 10))
+		if !v.Sunset.IsZero() {
+			h.Set("Sunset", v.Sunset.UTC().Format(http.TimeFormat))
+		}
+		if v.Link != "" {
+			h.Add("Link", "<"+v.Link+`>; rel="deprecation"`)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// /v1/users を v1 と /users に分ける
+func (rt *Router) splitPrefix(path string) (string, string, bool) {
+	trimmed := strings.TrimPrefix(path, "/")
+	name, rest, found := strings.Cut(trimmed, "/")
+	if _, ok := rt.versions[name]; !ok {
+		return "", "", false
+	}
+	if !found {
+		return name, "/", true
+	}
+	return name, "/" + rest, true
+}
+
+// パスからバージョンの接頭辞を取り除いたリクエストを作る
+func stripPrefix(r *http.Request, path string) *http.Request {
+	r2 := new(http.Request)
+	*r2 = *r
+	r2.URL = new(url.URL)
+	*r2.URL = *r.URL
+	r2.URL.Path = path
+	r2.URL.RawPath = ""
+	return r2
+}
+
+// 接頭辞を取り除いたパスで作られたリダイレクト先に接頭辞を戻すResponseWriter
+type prefixWriter struct {
+	http.ResponseWriter
+	prefix      string
+	wroteHeader bool
+}
+
+func (w *prefixWriter) WriteHeader(status int) {
+	if !w.wroteHeader && status >= http.StatusMultipleChoices && status < http.StatusBadRequest {
+		h := w.Header()
+		// 同じサーバー内の絶対パスのみ書き換える（//hostのようなURLや、ハンドラが接頭辞を付けたものは除く）
+		loc := h.Get("Location")
+		if strings.HasPrefix(loc, "/") && !strings.HasPrefix(loc, "//") && !w.hasPrefix(loc) {
+			h.Set("Location", w.prefix+loc)
+		}
+	}
+	if status >= http.StatusOK {
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *prefixWriter) hasPrefix(loc string) bool {
+	rest, ok := strings.CutPrefix(loc, w.prefix)
+	return ok && (rest == "" || strings.ContainsAny(rest[:1], "/?#"))
+}
+
+func (w *prefixWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *prefixWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,221 @@
+package versioning
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestRouter(t *testing.T) *Router {
+	rt, err := NewRouter("v2",
+		Version{
+			Name:        "v1",
+			Deprecation: time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC),
+			Sunset:      time.Date(2027, time.April, 1, 0, 0, 0, 0, time.UTC),
+			Link:        "https://example.com/migration",
+		},
+		Version{Name: "v2"},
+	)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	handler := func(name string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(name + " " + r.URL.Path))
+		}
+	}
+	rt.HandleFunc("v1", "/entries", handler("v1"))
+	rt.HandleFunc("v2", "/entries", handler("v2"))
+	rt.HandleAll("/users", handler("all"))
+	rt.HandleAll("/docs/", handler("all"))
+	rt.HandleAll("/moved", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, r.RequestURI+"/", http.StatusFound)
+	}))
+	return rt
+}
+
+func TestRouter(t *testing.T) {
+	rt := newTestRouter(t)
+
+	success := map[string]struct {
+		target         string
+		acceptVersion  string
+		wantBody       string
+		wantVersion    string
+		wantDeprecated bool
+		wantVary       bool
+	}{
+		"正常ケース：接頭辞でv1を指定": {
+			target:         "/v1/entries?user_id=1",
+			wantBody:       "v1 /entries",
+			wantVersion:    "v1",
+			wantDeprecated: true,
+		},
+		"正常ケース：接頭辞でv2を指定": {
+			target:      "/v2/entries",
+			wantBody:    "v2 /entries",
+			wantVersion: "v2",
+		},
+		"正常ケース：接頭辞なしは既定のバージョン": {
+			target:      "/entries",
+			wantBody:    "v2 /entries",
+			wantVersion: "v2",
+			wantVary:    true,
+		},
+		"正常ケース：ヘッダーでv1を指定": {
+			target:         "/entries",
+			acceptVersion:  "v1",
+			wantBody:       "v1 /entries",
+			wantVersion:    "v1",
+			wantDeprecated: true,
+			wantVary:       true,
+		},
+		"正常ケース：接頭辞がヘッダーより優先": {
+			target:        "/v2/entries",
+			acceptVersion: "v1",
+			wantBody:      "v2 /entries",
+			wantVersion:   "v2",
+		},
+		"正常ケース：全てのバージョンに登録したルートは非推奨にしない": {
+			target:      "/v1/users",
+			wantBody:    "all /users",
+			wantVersion: "v1",
+		},
+	}
+	for tn, tc := range success {
+		t.Run(tn, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, tc.target, nil)
+			if tc.acceptVersion != "" {
+				r.Header.Set(AcceptVersionHeader, tc.acceptVersion)
+			}
+			rt.ServeHTTP(w, r)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, tc.wantBody, w.Body.String())
+			assert.Equal(t, tc.wantVersion, w.Header().Get(VersionHeader))
+			if tc.wantDeprecated {
+				assert.Equal(t, "@1792368000", w.Header().Get("Deprecation"))
+				assert.Equal(t, "Thu, 01 Apr 2027 00:00:00 GMT", w.Header().Get("Sunset"))
+				assert.Equal(t, `<https://example.com/migration>; rel="deprecation"`, w.Header().Get("Link"))
+			} else {
+				assert.Empty(t, w.Header().Get("Deprecation"))
+				assert.Empty(t, w.Header().Get("Sunset"))
+			}
+			if tc.wantVary {
+				assert.Equal(t, AcceptVersionHeader, w.Header().Get("Vary"))
+			} else {
+				assert.Empty(t, w.Header().Get("Vary"))
+			}
+		})
+	}
+
+	fail := map[string]struct {
+		target        string
+		acceptVersion string
+		wantStatus    int
+	}{
+		"異常ケース：存在しないバージョンのヘッダー": {
+			target:        "/entries",
+			acceptVersion: "v3",
+			wantStatus:    http.StatusBadRequest,
+		},
+		"異常ケース：存在しないバージョンの接頭辞": {
+			target:     "/v3/entries",
+			wantStatus: http.StatusNotFound,
+		},
+		"異常ケース：登録されていないパス": {
+			target:     "/v1/echo",
+			wantStatus: http.StatusNotFound,
+		},
+	}
+	for tn, tc := range fail {
+		t.Run(tn, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, tc.target, nil)
+			if tc.acceptVersion != "" {
+				r.Header.Set(AcceptVersionHeader, tc.acceptVersion)
+			}
+			rt.ServeHTTP(w, r)
+
+			assert.Equal(t, tc.wantStatus, w.Code)
+		})
+	}
+}
+
+func TestRouterRedirect(t *testing.T) {
+	rt := newTestRouter(t)
+
+	success := map[string]struct {
+		target       string
+		wantStatus   int
+		wantLocation string
+	}{
+		"正常ケース：接頭辞付きのパスは接頭辞を残してリダイレクト": {
+			target:       "/v1/docs",
+			wantStatus:   http.StatusMovedPermanently,
+			wantLocation: "/v1/docs/",
+		},
+		"正常ケース：クエリも残す": {
+			target:       "/v2/docs?page=2",
+			wantStatus:   http.StatusMovedPermanently,
+			wantLocation: "/v2/docs/?page=2",
+		},
+		"正常ケース：接頭辞なしのパスはそのままリダイレクト": {
+			target:       "/docs",
+			wantStatus:   http.StatusMovedPermanently,
+			wantLocation: "/docs/",
+		},
+		"正常ケース：ハンドラが接頭辞を付けたリダイレクト先は書き換えない": {
+			target:       "/v1/moved",
+			wantStatus:   http.StatusFound,
+			wantLocation: "/v1/moved/",
+		},
+	}
+	for tn, tc := range success {
+		t.Run(tn, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, tc.target, nil)
+			rt.ServeHTTP(w, r)
+
+			assert.Equal(t, tc.wantStatus, w.Code)
+			assert.Equal(t, tc.wantLocation, w.Header().Get("Location"))
+		})
+	}
+}
+
+func TestNewRouter(t *testing.T) {
+	fail := map[string]struct {
+		defaultVersion string
+		versions       []Version
+	}{
+		"異常ケース：既定のバージョンが無い": {
+			defaultVersion: "v3",
+			versions:       []Version{{Name: "v1"}},
+		},
+		"異常ケース：バージョンが重複": {
+			defaultVersion: "v1",
+			versions:       []Version{{Name: "v1"}, {Name: "v1"}},
+		},
+		"異常ケース：バージョン名が不正": {
+			defaultVersion: "v1",
+			versions:       []Version{{Name: "v1"}, {Name: "v/2"}},
+		},
+	}
+	for tn, tc := range fail {
+		t.Run(tn, func(t *testing.T) {
+			_, err := NewRouter(tc.defaultVersion, tc.versions...)
+			assert.Error(t, err)
+		})
+	}
+
+	t.Run("異常ケース：存在しないバージョンへの登録", func(t *testing.T) {
+		rt, _ := NewRouter("v1", Version{Name: "v1"})
+		assert.Panics(t, func() {
+			rt.HandleFunc("v2", "/entries", func(http.ResponseWriter, *http.Request) {})
+		})
+	})
+}
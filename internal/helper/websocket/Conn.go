@@ -0,0 +1,404 @@
+package websocket
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// フレームのオペコード (RFC 6455 5.2)
+const (
+	OpContinuation = 0x0
+	OpText         = 0x1
+	OpBinary       = 0x2
+	OpClose        = 0x8
+	OpPing         = 0x9
+	OpPong         = 0xA
+)
+
+// クローズコード (RFC 6455 7.4.1)
+const (
+	CloseNormalClosure      = 1000
+	CloseGoingAway          = 1001
+	CloseProtocolError      = 1002
+	CloseUnsupportedData    = 1003
+	CloseNoStatusReceived   = 1005
+	CloseAbnormalClosure    = 1006
+	CloseInvalidPayloadData = 1007
+	ClosePolicyViolation    = 1008
+	CloseMessageTooBig      = 1009
+	CloseInternalServerErr  = 1011
+)
+
+// タイムアウトの解除に使う値
+var noDeadline time.Time
+
+// 受信するメッセージサイズの既定の上限
+const DefaultMaxMessageSize = 1 << 20
+
+// 制御フレームのペイロードの上限
+const maxControlPayload = 125
+
+// クローズフレーム送信後に相手のクローズフレームを待つ時間
+const closeTimeout = time.Second
+
+// 接続が閉じられたときのエラー
+type CloseError struct {
+	Code int
+	Text string
+}
+
+func (e *CloseError) Error() string {
+	return fmt.Sprintf("websocket: close %d %s", e.Code, e.Text)
+}
+
+// クローズフレーム送信後に書き込もうとした場合のエラー
+var ErrCloseSent = errors.New("websocket: close sent")
+
+// WebSocketの接続
+type Conn struct {
+	conn     net.Conn
+	br       *bufio.Reader
+	isServer bool
+
+	writeMu   sync.Mutex
+	closeSent bool
+	closeOnce sync.Once
+
+	// 受信するメッセージサイズの上限
+	MaxMessageSize int64
+	// Pingを受信したときの処理（既定ではPongを返す）
+	PingHandler func(data []byte) error
+	// Pongを受信したときの処理
+	PongHandler func(data []byte) error
+}
+
+func newConn(conn net.Conn, br *bufio.Reader, isServer bool) *Conn {
+	if br == nil {
+		br = bufio.NewReader(conn)
+	}
+	c := &Conn{
+		conn:           conn,
+		br:             br,
+		isServer:       isServer,
+		MaxMessageSize: DefaultMaxMessageSize,
+	}
+	c.PingHandler = func(data []byte) error {
+		err := c.WriteControl(OpPong, data)
+		if errors.Is(err, ErrCloseSent) {
+			return nil
+		}
+		return err
+	}
+	return c
+}
+
+// 接続先のアドレス
+func (c *Conn) RemoteAddr() net.Addr {
+	return c.conn.RemoteAddr()
+}
+
+// 読み込みのタイムアウトを設定する
+func (c *Conn) SetReadDeadline(t time.Time) error {
+	return c.conn.SetReadDeadline(t)
+}
+
+// フレームのヘッダー
+type frameHeader struct {
+	fin    bool
+	opcode byte
+	masked bool
+	length int64
+	mask   [4]byte
+}
+
+func isControl(opcode byte) bool {
+	return opcode&0x8 != 0
+}
+
+// メッセージを1件受信する
+// Ping・Pong・Closeの制御フレームはこの中で処理する
+func (c *Conn) ReadMessage() (opcode int, data []byte, err error) {
+	var msgOp byte
+	var msg []byte
+	for {
+		h, err := c.readHeader()
+		if err != nil {
+			return 0, nil, err
+		}
+		payload, err := c.readPayload(h)
+		if err != nil {
+			return 0, nil, err
+		}
+
+		switch h.opcode {
+		case OpPing:
+			if c.PingHandler != nil {
+				if err = c.PingHandler(payload); err != nil {
+					return 0, nil, err
+				}
+			}
+			continue
+		case OpPong:
+			if c.PongHandler != nil {
+				if err = c.PongHandler(payload); err != nil {
+					return 0, nil, err
+				}
+			}
+			continue
+		case OpClose:
+			return 0, nil, c.handleClose(payload)
+		case OpText, OpBinary:
+			if msgOp != 0 {
+				return 0, nil, c.fail(CloseProtocolError, "new message before previous message completed")
+			}
+			msgOp = h.opcode
+		case OpContinuation:
+			if msgOp == 0 {
+				return 0, nil, c.fail(CloseProtocolError, "continuation frame without message")
+			}
+		default:
+			return 0, nil, c.fail(CloseProtocolError, "unknown opcode")
+		}
+
+		if int64(len(msg))+int64(len(payload)) > c.MaxMessageSize {
+			return 0, nil, c.fail(CloseMessageTooBig, "message too big")
+		}
+		msg = append(msg, payload...)
+		if !h.fin {
+			continue
+		}
+		if msgOp == OpText && !utf8.Valid(msg) {
+			return 0, nil, c.fail(CloseInvalidPayloadData, "invalid utf-8")
+		}
+		if msg == nil {
+			msg = []byte{}
+		}
+		return int(msgOp), msg, nil
+	}
+}
+
+func (c *Conn) readHeader() (frameHeader, error) {
+	var h frameHeader
+	var b [8]byte
+	if _, err := io.ReadFull(c.br, b[:2]); err != nil {
+		return h, err
+	}
+	h.fin = b[0]&0x80 != 0
+	if b[0]&0x70 != 0 {
+		// 拡張はネゴシエーションしていないためRSVビットは常に0
+		return h, c.fail(CloseProtocolError, "reserved bits set")
+	}
+	h.opcode = b[0] & 0x0F
+	h.masked = b[1]&0x80 != 0
+	length := int64(b[1] & 0x7F)
+
+	switch length {
+	case 126:
+		if _, err := io.ReadFull(c.br, b[:2]); err != nil {
+			return h, err
+		}
+		length = int64(binary.BigEndian.Uint16(b[:2]))
+	case 127:
+		if _, err := io.ReadFull(c.br, b[:8]); err != nil {
+			return h, err
+		}
+		u := binary.BigEndian.Uint64(b[:8])
+		if u>>63 != 0 {
+			return h, c.fail(CloseProtocolError, "invalid payload length")
+		}
+		length = int64(u)
+	}
+	h.length = length
+
+	if isControl(h.opcode) && (!h.fin || h.length > maxControlPayload) {
+		return h, c.fail(CloseProtocolError, "invalid control frame")
+	}
+	// クライアントからのフレームは必ずマスクされる (RFC 6455 5.1)
+	if h.masked != c.isServer {
+		return h, c.fail(CloseProtocolError, "invalid masking")
+	}
+	if h.length > c.MaxMessageSize {
+		return h, c.fail(CloseMessageTooBig, "message too big")
+	}
+	if h.masked {
+		if _, err := io.ReadFull(c.br, h.mask[:]); err != nil {
+			return h, err
+		}
+	}
+	return h, nil
+}
+
+func (c *Conn) readPayload(h frameHeader) ([]byte, error) {
+	payload := make([]byte, h.length)
+	if _, err := io.ReadFull(c.br, payload); err != nil {
+		return nil, err
+	}
+	if h.masked {
+		maskBytes(h.mask, payload)
+	}
+	return payload, nil
+}
+
+func maskBytes(key [4]byte, b []byte) {
+	for i := range b {
+		b[i] ^= key[i%4]
+	}
+}
+
+// 相手からのクローズフレームに応答して接続を閉じる
+func (c *Conn) handleClose(payload []byte) error {
+	code := CloseNoStatusReceived
+	text := ""
+	switch {
+	case len(payload) == 1:
+		return c.fail(CloseProtocolError, "invalid close payload")
+	case len(payload) >= 2:
+		code = int(binary.BigEndian.Uint16(payload[:2]))
+		text = string(payload[2:])
+		if !validCloseCode(code) {
+			return c.fail(CloseProtocolError, "invalid close code")
+		}
+		if !utf8.ValidString(text) {
+			return c.fail(CloseInvalidPayloadData, "invalid utf-8")
+		}
+	}
+	reply := code
+	if reply == CloseNoStatusReceived {
+		reply = CloseNormalClosure
+	}
+	_ = c.writeFrame(OpClose, closePayload(reply, ""), true)
+	c.closeConn()
+	return &CloseError{Code: code, Text: text}
+}
+
+// 送信してよいクローズコードか (RFC 6455 7.4)
+func validCloseCode(code int) bool {
+	switch {
+	case code >= 1000 && code <= 1003, code >= 1007 && code <= 1011:
+		return true
+	case code >= 3000 && code <= 4999:
+		return true
+	}
+	return false
+}
+
+// プロトコル違反時にクローズフレームを送って接続を閉じる
+func (c *Conn) fail(code int, text string) error {
+	_ = c.writeFrame(OpClose, closePayload(code, text), true)
+	c.closeConn()
+	return &CloseError{Code: code, Text: text}
+}
+
+func closePayload(code int, text string) []byte {
+	payload := make([]byte, 2+len(text))
+	binary.BigEndian.PutUint16(payload, uint16(code))
+	copy(payload[2:], text)
+	if len(payload) > maxControlPayload {
+		payload = payload[:maxControlPayload]
+	}
+	return payload
+}
+
+// データメッセージを送信する
+func (c *Conn) WriteMessage(opcode int, data []byte) error {
+	if opcode != OpText && opcode != OpBinary {
+		return fmt.Errorf("websocket: invalid message opcode %d", opcode)
+	}
+	return c.writeFrame(byte(opcode), data, false)
+}
+
+// 制御フレームを送信する
+func (c *Conn) WriteControl(opcode int, data []byte) error {
+	if opcode != OpPing && opcode != OpPong {
+		return fmt.Errorf("websocket: invalid control opcode %d", opcode)
+	}
+	if len(data) > maxControlPayload {
+		return errors.New("websocket: control frame payload too large")
+	}
+	return c.writeFrame(byte(opcode), data, false)
+}
+
+func (c *Conn) writeFrame(opcode byte, payload []byte, closing bool) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	if c.closeSent {
+		return ErrCloseSent
+	}
+	if closing {
+		c.closeSent = true
+	}
+
+	buf := make([]byte, 0, 14+len(payload))
+	buf = append(buf, 0x80|opcode)
+
+	var maskBit byte
+	if !c.isServer {
+		maskBit = 0x80
+	}
+	switch n := len(payload); {
+	case n <= 125:
+		buf = append(buf, maskBit|byte(n))
+	case n <= 0xFFFF:
+		buf = append(buf, maskBit|126, byte(n>>8), byte(n))
+	default:
+		buf = append(buf, maskBit|127)
+		buf = binary.BigEndian.AppendUint64(buf, uint64(n))
+	}
+
+	if c.isServer {
+		buf = append(buf, payload...)
+	} else {
+		var key [4]byte
+		if _, err := rand.Read(key[:]); err != nil {
+			return err
+		}
+		buf = append(buf, key[:]...)
+		start := len(buf)
+		buf = append(buf, payload...)
+		maskBytes(key, buf[start:])
+	}
+
+	_, err := c.conn.Write(buf)
+	return err
+}
+
+// クローズフレームを送信し、相手の応答を待ってから接続を閉じる
+func (c *Conn) Close(code int, text string) error {
+	err := c.writeFrame(OpClose, closePayload(code, text), true)
+	if err != nil {
+		c.closeConn()
+		if errors.Is(err, ErrCloseSent) {
+			return nil
+		}
+		return err
+	}
+	// 相手のクローズフレームを待つ（受信中の場合はそちらで処理される）
+	_ = c.conn.SetReadDeadline(time.Now().Add(closeTimeout))
+	c.closeConnAfter(closeTimeout)
+	return nil
+}
+
+// クローズフレームを送らずに接続を閉じる
+// 既に閉じている場合は何もしないため、ハンドラの終了時にdeferで呼び出して接続を確実に解放する
+func (c *Conn) CloseNow() {
+	c.closeConn()
+}
+
+func (c *Conn) closeConnAfter(d time.Duration) {
+	time.AfterFunc(d, c.closeConn)
+}
+
+func (c *Conn) closeConn() {
+	c.closeOnce.Do(func() {
+		_ = c.conn.Close()
+	})
+}
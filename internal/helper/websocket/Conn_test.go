@@ -0,0 +1,274 @@
+package websocket
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// 受信したメッセージをそのまま返すハンドラ
+func echoHandler(w http.ResponseWriter, r *http.Request) {
+	c, err := Upgrade(w, r)
+	if err != nil {
+		return
+	}
+	c.MaxMessageSize = 1024
+	for {
+		op, data, err := c.ReadMessage()
+		if err != nil {
+			return
+		}
+		if err = c.WriteMessage(op, data); err != nil {
+			return
+		}
+	}
+}
+
+func dialEcho(t *testing.T) (*Conn, func()) {
+	ts := httptest.NewServer(http.HandlerFunc(echoHandler))
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	c, _, err := Dial(ctx, "ws"+strings.TrimPrefix(ts.URL, "http"), nil)
+	if err != nil {
+		ts.Close()
+		t.Fatalf("dial: %v", err)
+	}
+	_ = c.SetReadDeadline(time.Now().Add(5 * time.Second))
+	return c, func() {
+		c.closeConn()
+		ts.Close()
+	}
+}
+
+// 任意のフレームを送るための生の接続
+func dialRaw(t *testing.T) (net.Conn, *bufio.Reader, func()) {
+	ts := httptest.NewServer(http.HandlerFunc(echoHandler))
+	c, _, err := Dial(context.Background(), ts.URL, nil)
+	if err != nil {
+		ts.Close()
+		t.Fatalf("dial: %v", err)
+	}
+	_ = c.conn.SetDeadline(time.Now().Add(5 * time.Second))
+	return c.conn, c.br, func() {
+		c.conn.Close()
+		ts.Close()
+	}
+}
+
+// マスクしたフレームを組み立てる
+func maskedFrame(b0 byte, payload []byte) []byte {
+	key := [4]byte{1, 2, 3, 4}
+	frame := []byte{b0, 0x80 | byte(len(payload))}
+	frame = append(frame, key[:]...)
+	masked := append([]byte{}, payload...)
+	maskBytes(key, masked)
+	return append(frame, masked...)
+}
+
+func readServerFrame(t *testing.T, br *bufio.Reader) (byte, []byte) {
+	c := &Conn{br: br, isServer: false, MaxMessageSize: DefaultMaxMessageSize}
+	h, err := c.readHeader()
+	if err != nil {
+		t.Fatalf("read header: %v", err)
+	}
+	payload, err := c.readPayload(h)
+	if err != nil {
+		t.Fatalf("read payload: %v", err)
+	}
+	return h.opcode, payload
+}
+
+func TestUpgrade(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(echoHandler))
+	defer ts.Close()
+
+	fail := map[string]struct {
+		method     string
+		header     map[string]string
+		wantStatus int
+	}{
+		"異常ケース：GETメソッドではない": {
+			method:     http.MethodPost,
+			wantStatus: http.StatusMethodNotAllowed,
+		},
+		"異常ケース：Upgradeヘッダーなし": {
+			method:     http.MethodGet,
+			header:     map[string]string{"Connection": "Upgrade", "Sec-WebSocket-Version": "13", "Sec-WebSocket-Key": "dGhlIHNhbXBsZSBub25jZQ=="},
+			wantStatus: http.StatusBadRequest,
+		},
+		"異常ケース：バージョンが異なる": {
+			method:     http.MethodGet,
+			header:     map[string]string{"Connection": "keep-alive, Upgrade", "Upgrade": "websocket", "Sec-WebSocket-Version": "8", "Sec-WebSocket-Key": "dGhlIHNhbXBsZSBub25jZQ=="},
+			wantStatus: http.StatusUpgradeRequired,
+		},
+		"異常ケース：キーが不正": {
+			method:     http.MethodGet,
+			header:     map[string]string{"Connection": "Upgrade", "Upgrade": "websocket", "Sec-WebSocket-Version": "13", "Sec-WebSocket-Key": "short"},
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+	for tn, tc := range fail {
+		t.Run(tn, func(t *testing.T) {
+			req, _ := http.NewRequest(tc.method, ts.URL, nil)
+			for k, v := range tc.header {
+				req.Header.Set(k, v)
+			}
+			res, err := http.DefaultClient.Do(req)
+			if !assert.NoError(t, err) {
+				return
+			}
+			defer res.Body.Close()
+			assert.Equal(t, tc.wantStatus, res.StatusCode)
+		})
+	}
+	t.Run("正常ケース：Sec-WebSocket-Acceptの計算", func(t *testing.T) {
+		// RFC 6455 1.3の例
+		assert.Equal(t, "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=", acceptKey("dGhlIHNhbXBsZSBub25jZQ=="))
+	})
+}
+
+func TestConn(t *testing.T) {
+	t.Run("正常ケース：テキスト・バイナリのエコー", func(t *testing.T) {
+		c, done := dialEcho(t)
+		defer done()
+
+		messages := map[int][]byte{
+			OpText:   []byte("こんにちは"),
+			OpBinary: {0x00, 0x01, 0xff},
+		}
+		for op, data := range messages {
+			assert.NoError(t, c.WriteMessage(op, data))
+			gotOp, got, err := c.ReadMessage()
+			assert.NoError(t, err)
+			assert.Equal(t, op, gotOp)
+			assert.Equal(t, data, got)
+		}
+	})
+	t.Run("正常ケース：126バイト以上のメッセージ", func(t *testing.T) {
+		c, done := dialEcho(t)
+		defer done()
+
+		data := []byte(strings.Repeat("a", 1000))
+		assert.NoError(t, c.WriteMessage(OpText, data))
+		_, got, err := c.ReadMessage()
+		assert.NoError(t, err)
+		assert.Equal(t, data, got)
+	})
+	t.Run("正常ケース：Pingに対してPongを返す", func(t *testing.T) {
+		c, done := dialEcho(t)
+		defer done()
+
+		pong := make(chan string, 1)
+		c.PongHandler = func(data []byte) error {
+			pong <- string(data)
+			return nil
+		}
+		assert.NoError(t, c.WriteControl(OpPing, []byte("hello")))
+		assert.NoError(t, c.WriteMessage(OpText, []byte("after ping")))
+		_, got, err := c.ReadMessage()
+		assert.NoError(t, err)
+		assert.Equal(t, "after ping", string(got))
+		assert.Equal(t, "hello", <-pong)
+	})
+	t.Run("正常ケース：クローズハンドシェイク", func(t *testing.T) {
+		c, done := dialEcho(t)
+		defer done()
+
+		assert.NoError(t, c.Close(CloseNormalClosure, "bye"))
+		_, _, err := c.ReadMessage()
+		var cerr *CloseError
+		if assert.ErrorAs(t, err, &cerr) {
+			assert.Equal(t, CloseNormalClosure, cerr.Code)
+		}
+		assert.ErrorIs(t, c.WriteMessage(OpText, []byte("x")), ErrCloseSent)
+	})
+	t.Run("正常ケース：分割されたメッセージ", func(t *testing.T) {
+		conn, br, done := dialRaw(t)
+		defer done()
+
+		// 最初のフレームの後にPingを挟む
+		_, err := conn.Write(maskedFrame(OpText, []byte("こん")))
+		assert.NoError(t, err)
+		_, err = conn.Write(maskedFrame(0x80|OpPing, []byte("p")))
+		assert.NoError(t, err)
+		_, err = conn.Write(maskedFrame(0x80|OpContinuation, []byte("にちは")))
+		assert.NoError(t, err)
+
+		op, payload := readServerFrame(t, br)
+		assert.Equal(t, byte(OpPong), op)
+		assert.Equal(t, "p", string(payload))
+		op, payload = readServerFrame(t, br)
+		assert.Equal(t, byte(OpText), op)
+		assert.Equal(t, "こんにちは", string(payload))
+	})
+
+	fail := map[string]struct {
+		frame    []byte
+		wantCode int
+	}{
+		"異常ケース：マスクされていない": {
+			frame:    []byte{0x80 | OpText, 0x01, 'a'},
+			wantCode: CloseProtocolError,
+		},
+		"異常ケース：RSVビットが立っている": {
+			frame:    maskedFrame(0xC0|OpText, []byte("a")),
+			wantCode: CloseProtocolError,
+		},
+		"異常ケース：不正なUTF-8": {
+			frame:    maskedFrame(0x80|OpText, []byte{0xff, 0xfe}),
+			wantCode: CloseInvalidPayloadData,
+		},
+		"異常ケース：メッセージなしの継続フレーム": {
+			frame:    maskedFrame(0x80|OpContinuation, []byte("a")),
+			wantCode: CloseProtocolError,
+		},
+		"異常ケース：分割された制御フレーム": {
+			frame:    maskedFrame(OpPing, []byte("a")),
+			wantCode: CloseProtocolError,
+		},
+		"異常ケース：不正なクローズコード": {
+			frame:    maskedFrame(0x80|OpClose, []byte{0x03, 0xED}),
+			wantCode: CloseProtocolError,
+		},
+		"異常ケース：メッセージが大きすぎる": {
+			frame: func() []byte {
+				// 126バイト以上は長さを2バイトで表す
+				payload := []byte(strings.Repeat("a", 2000))
+				frame := []byte{0x80 | OpText, 0x80 | 126, 0x07, 0xD0, 0, 0, 0, 0}
+				return append(frame, payload...)
+			}(),
+			wantCode: CloseMessageTooBig,
+		},
+	}
+	for tn, tc := range fail {
+		t.Run(tn, func(t *testing.T) {
+			conn, br, done := dialRaw(t)
+			defer done()
+
+			_, err := conn.Write(tc.frame)
+			assert.NoError(t, err)
+			op, payload := readServerFrame(t, br)
+			assert.Equal(t, byte(OpClose), op)
+			if assert.GreaterOrEqual(t, len(payload), 2) {
+				assert.Equal(t, tc.wantCode, int(payload[0])<<8|int(payload[1]))
+			}
+		})
+	}
+	t.Run("異常ケース：クローズ後のメッセージ送信", func(t *testing.T) {
+		c, done := dialEcho(t)
+		defer done()
+
+		assert.NoError(t, c.Close(CloseGoingAway, ""))
+		assert.True(t, errors.Is(c.WriteMessage(OpText, nil), ErrCloseSent))
+		assert.Error(t, c.WriteMessage(OpClose, nil))
+		assert.Error(t, c.WriteControl(OpText, nil))
+	})
+}
@@ -0,0 +1,179 @@
+package websocket
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Sec-WebSocket-Acceptの計算に使うGUID (RFC 6455 1.3)
+const acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// 対応しているプロトコルのバージョン
+const protocolVersion = "13"
+
+// ハンドシェイクに失敗した場合のエラー
+type HandshakeError struct {
+	Status  int
+	Message string
+}
+
+func (e *HandshakeError) Error() string {
+	return "websocket: " + e.Message
+}
+
+// HTTPリクエストをWebSocketの接続にアップグレードする
+// 失敗した場合はエラーレスポンスを書き込んだうえでエラーを返す
+func Upgrade(w http.ResponseWriter, r *http.Request) (*Conn, error) {
+	if err := checkUpgradeRequest(r); err != nil {
+		var herr *HandshakeError
+		if errors.As(err, &herr) {
+			if herr.Status == http.StatusUpgradeRequired {
+				w.Header().Set("Sec-WebSocket-Version", protocolVersion)
+			}
+			http.Error(w, herr.Message, herr.Status)
+		}
+		return nil, err
+	}
+
+	conn, brw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		http.Error(w, "websocket: hijacking is not supported", http.StatusInternalServerError)
+		return nil, err
+	}
+	// ハイジャック前にサーバーが設定したタイムアウトを解除する
+	if err = conn.SetDeadline(noDeadline); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	res := "HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + acceptKey(r.Header.Get("Sec-WebSocket-Key")) + "\r\n\r\n"
+	if _, err = brw.WriteString(res); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if err = brw.Flush(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return newConn(conn, brw.Reader, true), nil
+}
+
+func checkUpgradeRequest(r *http.Request) error {
+	if r.Method != http.MethodGet {
+		return &HandshakeError{Status: http.StatusMethodNotAllowed, Message: "method must be GET"}
+	}
+	if !headerContainsToken(r.Header, "Connection", "upgrade") {
+		return &HandshakeError{Status: http.StatusBadRequest, Message: "'upgrade' token not found in 'Connection' header"}
+	}
+	if !headerContainsToken(r.Header, "Upgrade", "websocket") {
+		return &HandshakeError{Status: http.StatusBadRequest, Message: "'websocket' token not found in 'Upgrade' header"}
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != protocolVersion {
+		return &HandshakeError{Status: http.StatusUpgradeRequired, Message: "unsupported version"}
+	}
+	key, err := base64.StdEncoding.DecodeString(r.Header.Get("Sec-WebSocket-Key"))
+	if err != nil || len(key) != 16 {
+		return &HandshakeError{Status: http.StatusBadRequest, Message: "invalid 'Sec-WebSocket-Key' header"}
+	}
+	return nil
+}
+
+// カンマ区切りのヘッダーにトークンが含まれるか
+func headerContainsToken(h http.Header, name, token string) bool {
+	for _, v := range h.Values(name) {
+		for _, t := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func acceptKey(key string) string {
+	sum := sha1.Sum([]byte(key + acceptGUID))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// WebSocketサーバーへ接続する（ws://のみ対応）
+func Dial(ctx context.Context, rawURL string, header http.Header) (*Conn, *http.Response, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, nil, err
+	}
+	switch u.Scheme {
+	case "ws":
+		u.Scheme = "http"
+	case "http":
+	default:
+		return nil, nil, fmt.Errorf("websocket: unsupported scheme %q", u.Scheme)
+	}
+	host := u.Host
+	if u.Port() == "" {
+		host = net.JoinHostPort(u.Hostname(), "80")
+	}
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", host)
+	if err != nil {
+		return nil, nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	nonce := make([]byte, 16)
+	if _, err = rand.Read(nonce); err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	key := base64.StdEncoding.EncodeToString(nonce)
+
+	req := &http.Request{
+		Method:     http.MethodGet,
+		URL:        u,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     http.Header{},
+		Host:       u.Host,
+	}
+	for k, vs := range header {
+		req.Header[k] = vs
+	}
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Sec-WebSocket-Key", key)
+	req.Header.Set("Sec-WebSocket-Version", protocolVersion)
+	if err = req.Write(conn); err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+
+	br := bufio.NewReader(conn)
+	res, err := http.ReadResponse(br, req)
+	if err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	if res.StatusCode != http.StatusSwitchingProtocols ||
+		!headerContainsToken(res.Header, "Upgrade", "websocket") ||
+		res.Header.Get("Sec-WebSocket-Accept") != acceptKey(key) {
+		conn.Close()
+		return nil, res, &HandshakeError{Status: res.StatusCode, Message: "bad handshake"}
+	}
+	_ = conn.SetDeadline(noDeadline)
+	return newConn(conn, br, false), res, nil
+}
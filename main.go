@@ -1,26 +1,346 @@
 package main
 
 import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io/fs"
 	"log"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
 
+	"github.com/dip-dev/go-tutorial/api"
 	"github.com/dip-dev/go-tutorial/internal/chapter1"
 	"github.com/dip-dev/go-tutorial/internal/chapter2"
 	"github.com/dip-dev/go-tutorial/internal/chapter3"
+	"github.com/dip-dev/go-tutorial/internal/helper/certs"
+	"github.com/dip-dev/go-tutorial/internal/helper/idempotency"
+	"github.com/dip-dev/go-tutorial/internal/helper/networking"
+	"github.com/dip-dev/go-tutorial/internal/helper/proxy"
+	"github.com/dip-dev/go-tutorial/internal/helper/versioning"
+)
+
+// 停止時に処理中のリクエストを待つ時間
+const shutdownTimeout = 10 * time.Second
+
+// 中継先のmock-api
+const mockAPIURL = "http://mock-api"
+
+// 中継するルートの設定ファイル（環境変数PROXY_CONFIGで変更できる）
+const defaultProxyConfig = "config/proxy.json"
+
+// APIのバージョン
+const (
+	apiV1 = "v1"
+	apiV2 = "v2"
 )
 
 func main() {
+	// mock-apiへの接続の設定（TLS・プロキシ・名前解決）
+	options, err := outboundOptions()
+	if err != nil {
+		log.Fatalf("failed to configure client: %+v", err)
+	}
+	networking.SetDefaultOptions(options...)
+	// 証明書のファイルやプロキシの設定が正しいかを起動時に確認する
+	if _, err := networking.NewClient(mockAPIURL); err != nil {
+		log.Fatalf("failed to configure client: %+v", err)
+	}
+
+	router, err := newRouter()
+	if err != nil {
+		log.Fatalf("failed to build router: %+v", err)
+	}
+
 	mux := http.NewServeMux()
+	mux.Handle("/", router)
+
+	// APIの仕様書
+	mux.HandleFunc("/openapi.json", api.ServeOpenAPI)
+
+	// 停止時にキャンセルされるコンテキストを全リクエストの親にする
+	// WebSocketやSSEなどの長時間の接続はこれを見て終了する
+	baseCtx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	srv := &http.Server{
+		Addr:    "0.0.0.0:8080",
+		Handler: mux,
+		BaseContext: func(net.Listener) context.Context {
+			return baseCtx
+		},
+	}
+	srv.RegisterOnShutdown(cancel)
+
+	// 証明書が指定された場合はHTTPSで待ち受ける
+	tlsConfig, err := serverTLSConfig()
+	if err != nil {
+		log.Fatalf("failed to configure TLS: %+v", err)
+	}
+	srv.TLSConfig = tlsConfig
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	errch := make(chan error, 1)
+	go func() {
+		if srv.TLSConfig != nil {
+			// 証明書はTLSConfigのGetCertificateで読み込むため、ファイルは指定しない
+			errch <- srv.ListenAndServeTLS("", "")
+			return
+		}
+		errch <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errch:
+		if !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("failed to launch service: %+v", err)
+		}
+	case <-ctx.Done():
+		shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancelShutdown()
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			log.Printf("failed to shutdown service: %+v", err)
+		}
+	}
+}
+
+// 環境変数からサーバーのTLSの設定を作成する
+// TLS_CERT_FILE・TLS_KEY_FILEが無い場合はnil（HTTPで待ち受ける）
+// TLS_CLIENT_CA_FILEを指定するとクライアント証明書を必須にする（mTLS）
+func serverTLSConfig() (*tls.Config, error) {
+	config := certs.ServerConfig{
+		CertFile:     os.Getenv("TLS_CERT_FILE"),
+		KeyFile:      os.Getenv("TLS_KEY_FILE"),
+		ClientCAFile: os.Getenv("TLS_CLIENT_CA_FILE"),
+	}
+	if config.CertFile == "" && config.KeyFile == "" {
+		return nil, nil
+	}
+	if v := os.Getenv("TLS_MIN_VERSION"); v != "" {
+		version, err := certs.ParseVersion(v)
+		if err != nil {
+			return nil, err
+		}
+		config.MinVersion = version
+	}
+	return config.TLSConfig()
+}
+
+// 環境変数からmock-apiへの接続のオプションを作成する
+// MOCK_API_URLをhttps://で指定した場合に使う
+func outboundOptions() ([]networking.Option, error) {
+	var options []networking.Option
+	if v := os.Getenv("MOCK_API_CA_FILE"); v != "" {
+		options = append(options, networking.WithRootCAFile(v))
+	}
+	certFile, keyFile := os.Getenv("MOCK_API_CLIENT_CERT_FILE"), os.Getenv("MOCK_API_CLIENT_KEY_FILE")
+	if certFile != "" || keyFile != "" {
+		options = append(options, networking.WithClientCertificateFile(certFile, keyFile))
+	}
+	if v := os.Getenv("MOCK_API_SERVER_NAME"); v != "" {
+		options = append(options, networking.WithServerName(v))
+	}
+	if v := os.Getenv("MOCK_API_MIN_TLS_VERSION"); v != "" {
+		version, err := certs.ParseVersion(v)
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, networking.WithMinTLSVersion(version))
+	}
+
+	// プロキシ（未指定の場合はHTTP_PROXYなどの環境変数に従う）
+	if v := os.Getenv("MOCK_API_PROXY"); v != "" {
+		options = append(options, networking.WithProxy(v, os.Getenv("MOCK_API_NO_PROXY")))
+	}
+	// 名前解決（MOCK_API_HOSTSは host=ip をカンマ区切りで指定する）
+	dialer, err := outboundDialer(os.Getenv("MOCK_API_HOSTS"), os.Getenv("MOCK_API_DNS_SERVER"))
+	if err != nil {
+		return nil, err
+	}
+	if dialer != nil {
+		options = append(options, networking.WithDialer(dialer))
+	}
+	return options, nil
+}
+
+// 名前解決の設定からDialerを作成する
+// 接続を共有するため、Dialerは起動時に1度だけ作成する
+func outboundDialer(hosts, dnsServer string) (*networking.Dialer, error) {
+	if hosts == "" && dnsServer == "" {
+		return nil, nil
+	}
+	dialer := &networking.Dialer{Hosts: map[string]string{}}
+	for _, entry := range strings.Split(hosts, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		host, ip, ok := strings.Cut(entry, "=")
+		if !ok || net.ParseIP(strings.TrimSpace(ip)) == nil {
+			return nil, fmt.Errorf("invalid MOCK_API_HOSTS entry: %q", entry)
+		}
+		dialer.Hosts[strings.ToLower(strings.TrimSpace(host))] = strings.TrimSpace(ip)
+	}
+	if dnsServer != "" {
+		// ポートを省略した場合は53番
+		if _, _, err := net.SplitHostPort(dnsServer); err != nil {
+			dnsServer = net.JoinHostPort(dnsServer, "53")
+		}
+		dialer.Resolver = networking.NewDNSResolver(dnsServer)
+	}
+	return dialer, nil
+}
+
+// バージョンごとのハンドラを登録する
+// 接頭辞（/v1, /v2）もAccept-Versionヘッダーも無いリクエストは既定のバージョンで処理する
+func newRouter() (*versioning.Router, error) {
+	// 移行を告知するまでは既存の利用者に合わせてv1を既定にする
+	defaultVersion := apiV1
+	if chapter3.DefaultEntryFormat() == chapter3.EntryFormatV2 {
+		defaultVersion = apiV2
+	}
+	v1, err := deprecatedVersion(apiV1, "API_V1_DEPRECATION", "API_V1_SUNSET")
+	if err != nil {
+		return nil, err
+	}
+	router, err := versioning.NewRouter(defaultVersion, v1, versioning.Version{Name: apiV2})
+	if err != nil {
+		return nil, err
+	}
+
+	// 登録のリトライで重複しないよう、Idempotency-Keyごとに結果を保存する
+	idem := idempotency.New(idempotency.NewMemoryStore())
 
 	// EchoAPI
-	mux.HandleFunc("/echo", chapter1.GetEcho)
+	router.HandleAll("/echo", http.HandlerFunc(chapter1.GetEcho))
+	router.HandleAll("/echo/ws", http.HandlerFunc(chapter1.EchoWebSocket))
+	router.HandleAll("/echo/sse", http.HandlerFunc(chapter1.EchoSSE))
 
 	// FIXME: ハンドラ追加時はこちらにコードを追加してください
-	mux.HandleFunc("/users", chapter2.Get)
-	mux.HandleFunc("/users", chapter2.Create)
-	mux.HandleFunc("/entries", chapter3.Get)
+	router.HandleAll("/users", byMethod(map[string]http.HandlerFunc{
+		http.MethodGet:  chapter2.Get,
+		http.MethodPost: idem.HandlerFunc(chapter2.Create),
+	}))
+	router.HandleAll("/users:batch", byMethod(map[string]http.HandlerFunc{
+		http.MethodPost: idem.HandlerFunc(chapter2.CreateBatch),
+	}))
+	// アップロードするファイルが大きいため、Idempotency-Keyには対応しない
+	router.HandleAll("/users:import", byMethod(map[string]http.HandlerFunc{
+		http.MethodPost: chapter2.Import,
+	}))
+	router.HandleAll("/users/", byMethod(map[string]http.HandlerFunc{
+		http.MethodGet:    chapter2.Detail,
+		http.MethodPut:    chapter2.Update,
+		http.MethodPatch:  chapter2.Patch,
+		http.MethodDelete: chapter2.Delete,
+	}))
+
+	// バージョンごとにレスポンスの形式が異なる
+	router.HandleFunc(apiV1, "/entries", byMethod(map[string]http.HandlerFunc{
+		http.MethodGet:  chapter3.GetWithFormat(chapter3.EntryFormatV1),
+		http.MethodPost: idem.HandlerFunc(chapter3.CreateWithFormat(chapter3.EntryFormatV1)),
+	}))
+	router.HandleFunc(apiV2, "/entries", byMethod(map[string]http.HandlerFunc{
+		http.MethodGet:  chapter3.GetWithFormat(chapter3.EntryFormatV2),
+		http.MethodPost: idem.HandlerFunc(chapter3.CreateWithFormat(chapter3.EntryFormatV2)),
+	}))
+
+	// 設定ファイルで定義したルートはmock-apiへそのまま中継する
+	if err := handleProxyRoutes(router); err != nil {
+		return nil, err
+	}
+
+	return router, nil
+}
+
+// 環境変数から非推奨化と提供終了の日時を読み込む
+// 日時は2006-01-02またはRFC 3339の形式で指定し、非推奨化の日時が無い場合は非推奨にしない
+func deprecatedVersion(name, deprecationEnv, sunsetEnv string) (versioning.Version, error) {
+	v := versioning.Version{Name: name}
+	var err error
+	if v.Deprecation, err = parseDateEnv(deprecationEnv); err != nil {
+		return v, err
+	}
+	if v.Sunset, err = parseDateEnv(sunsetEnv); err != nil {
+		return v, err
+	}
+	if !v.Sunset.IsZero() && !v.Deprecated() {
+		return v, fmt.Errorf("%s requires %s", sunsetEnv, deprecationEnv)
+	}
+	if v.Deprecated() && !v.Sunset.IsZero() && v.Sunset.Before(v.Deprecation) {
+		return v, fmt.Errorf("%s must not be before %s", sunsetEnv, deprecationEnv)
+	}
+	return v, nil
+}
+
+func parseDateEnv(name string) (time.Time, error) {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse("2006-01-02", raw); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid %s %q: %w", name, raw, err)
+	}
+	return t, nil
+}
+
+// 設定ファイルのルートごとに中継するハンドラを登録する
+// 既定の設定ファイルが無い場合は何もしない
+func handleProxyRoutes(router *versioning.Router) error {
+	path, specified := os.LookupEnv("PROXY_CONFIG")
+	if !specified {
+		path = defaultProxyConfig
+	}
+	config, err := proxy.LoadConfig(path)
+	if !specified && errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	c, err := networking.NewClient(mockAPIURL)
+	if err != nil {
+		return err
+	}
+	for _, route := range config.Routes {
+		h, err := proxy.NewHandler(c, route, nil)
+		if err != nil {
+			return err
+		}
+		router.HandleAll(route.Prefix, h)
+	}
+	return nil
+}
+
+// メソッドごとにハンドラを振り分ける
+// 同じパスを複数回登録するとServeMuxがpanicするため、1つのハンドラにまとめる
+func byMethod(handlers map[string]http.HandlerFunc) http.HandlerFunc {
+	allowed := make([]string, 0, len(handlers))
+	for method := range handlers {
+		allowed = append(allowed, method)
+	}
+	sort.Strings(allowed)
 
-	if err := http.ListenAndServe("0.0.0.0:8080", mux); err != nil {
-		log.Fatalf("failed to launch service: %+v", err)
+	return func(w http.ResponseWriter, r *http.Request) {
+		h, ok := handlers[r.Method]
+		if !ok {
+			w.Header().Set("Allow", strings.Join(allowed, ", "))
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		h(w, r)
 	}
 }